@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func init() {
+	var playerName string
+	var a, b Timestamp
+	var options mprisctl.ABOptions
+
+	var cmd = &cobra.Command{
+		Use:   "ab",
+		Short: "Repeat a section of the current track",
+		Long: `Repeat the section of the current track between --a and --b, seeking back to A
+whenever B is crossed, for practicing a passage. Times are like 1:02, 1:02:30,
+62.5 or 1m2s.
+
+With --count, the section is played that many times, the track then playing on.
+--rate sets the playback rate of the first time, and --rate-step changes it
+every time the section is played again, like 0.05 to speed up a little each
+time. The rate is restored once done.
+
+The repeat stops when the track changes, and is interrupted with Ctrl-C. It
+needs a player able to seek.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.Count < 0 {
+				return errors.New("--count must not be negative")
+			}
+			if options.Rate < 0 {
+				return errors.New("--rate must be positive")
+			}
+			options.A, options.B = time.Duration(a), time.Duration(b)
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return WithClient(func(client *mpris.Client) error {
+				return mprisctl.RepeatAB(ctx, client, playerName, options)
+			})
+		},
+	}
+
+	WithPlayer(cmd, &playerName)
+	cmd.Flags().Var(&a, "a", "start of the section")
+	cmd.Flags().Var(&b, "b", "end of the section")
+	cmd.Flags().IntVar(&options.Count, "count", 0, "number of times the section is played (default is until interrupted)")
+	cmd.Flags().Float64Var(&options.Rate, "rate", 0, "playback rate of the first time (default is the current rate)")
+	cmd.Flags().Float64Var(&options.RateStep, "rate-step", 0, "change of the rate every time the section is played again")
+	cmd.MarkFlagRequired("a")
+	cmd.MarkFlagRequired("b")
+
+	rootCmd.AddCommand(cmd)
+}
@@ -1,33 +1,70 @@
 package cmd
 
 import (
-	"mprisctl/internal"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
 func init() {
 
-	ActionCmd("play", "Play", mprisctl.NewMpris().Play)
-	ActionCmd("pause", "Pause", mprisctl.NewMpris().Pause)
-	ActionCmd("play-pause", "Toggle play/pause", mprisctl.NewMpris().PlayPause)
-	ActionCmd("stop", "Stop", mprisctl.NewMpris().Stop)
-	ActionCmd("next", "Next track", mprisctl.NewMpris().Next)
-	ActionCmd("previous", "Previous track", mprisctl.NewMpris().Previous)
+	ActionCmd("play", "Play", (*mpris.Client).Play)
+	ActionCmd("pause", "Pause", (*mpris.Client).Pause)
+	ActionCmd("play-pause", "Toggle play/pause", (*mpris.Client).PlayPause)
+	ActionCmd("stop", "Stop", (*mpris.Client).Stop)
+	ActionCmd("next", "Next track", (*mpris.Client).Next)
+	ActionCmd("previous", "Previous track", (*mpris.Client).Previous)
 }
 
-func ActionCmd(name string, short string, callback func(string)) {
+// actionFade is the fade flag of an action.
+type actionFade struct {
+	flag  string
+	usage string
+	kind  mprisctl.FadeKind
+}
+
+var actionFades = map[string]actionFade{
+	"play":  {"fade-in", "fade the volume in over the given duration, after playing", mprisctl.FadeIn},
+	"pause": {"fade", "fade the volume out over the given duration, before pausing", mprisctl.FadeOut},
+	"next":  {"crossfade-ish", "fade the volume out over the given duration, go to the next track, and fade it in", mprisctl.FadeThrough},
+}
+
+func ActionCmd(name string, short string, callback func(*mpris.Client, context.Context, string) error) {
 	var playerName string
+	var fadeDuration time.Duration
+	fade, fades := actionFades[name]
 
 	var cmd = &cobra.Command{
 		Use:   name,
 		Short: short,
 		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
-			callback(mprisctl.GetPlayerId(playerName))
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fades && fadeDuration > 0 {
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				return WithClient(func(client *mpris.Client) error {
+					return mprisctl.FadeAction(ctx, client, playerName, callback, fade.kind, fadeDuration)
+				})
+			}
+			return WithClient(func(client *mpris.Client) error {
+				// The fade in progress is cancelled, and the volume set to where it was going.
+				if err := mprisctl.CancelFade(cmd.Context(), client, playerName, true); err != nil {
+					return err
+				}
+				return callback(client, cmd.Context(), playerName)
+			})
 		},
 	}
 
-	WithRequiredPlayer(cmd, &playerName)
+	WithPlayer(cmd, &playerName)
+	if fades {
+		cmd.Flags().DurationVar(&fadeDuration, fade.flag, 0, fade.usage)
+	}
 	rootCmd.AddCommand(cmd)
 }
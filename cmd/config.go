@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/internal/config"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+var (
+	// settings is the effective configuration: the file, overridden by the environment and the flags.
+	settings = config.Default()
+	// settingsPath is the path of the configuration file read, empty when there is none.
+	settingsPath string
+
+	configPath     string
+	outputFormat   string
+	outputTemplate string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "configuration file (default is $MPRISCTL_CONFIG, or mprisctl/config.toml in $XDG_CONFIG_HOME)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "", `output format of list and watch: "lines", "json" or "template" (default "lines")`)
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template of the template format, or the name of a template of the configuration")
+	rootCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return loadSettings()
+	}
+
+	var showFormat string
+
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration",
+		Long: `Manage the configuration.
+
+The configuration is read from $MPRISCTL_CONFIG, or from mprisctl/config.toml in
+$XDG_CONFIG_HOME (~/.config by default); config.yaml, config.yml and
+config.json are read too when there is no config.toml. For example:
+
+  player = "music"                # the player of the commands given no -p
+  priority = ["spotify", "mpv"]   # else the first of these running
+  ignore = ["chromium"]           # players left out of lists and events
+  playerctld = "hide"
+  format = "template"             # output of list and watch
+  template = "short"
+  hook_concurrency = 4
+  hook_timeout = "30s"
+
+  [aliases]
+  music = "spotify"
+
+  [templates]
+  short = "{{.Event}} {{.Player}}: {{.Metadata.Title}} ({{duration .Position}})"
+
+  [volume]
+  step = 0.05
+  players = { vlc = 0.1 }
+
+  [exclusive]                     # see daemon --exclusive
+  enabled = true
+  resume = true
+  allow = ["firefox"]
+
+  [duck]                          # see daemon --duck
+  players = ["zoom"]
+  amount = 0.5
+  ramp = "500ms"
+
+  [[hooks]]
+  events = ["track"]
+  players = ["music"]
+  command = 'notify-send "$MPRIS_TITLE" "$MPRIS_ARTIST"'
+  debounce = "500ms"
+
+The environment variables MPRISCTL_PLAYER, MPRISCTL_PRIORITY, MPRISCTL_IGNORE,
+MPRISCTL_PLAYERCTLD, MPRISCTL_FORMAT, MPRISCTL_TEMPLATE and
+MPRISCTL_VOLUME_STEP override the file, and the flags override both.`,
+	}
+
+	var showCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if settingsPath == "" {
+				fmt.Println("# no configuration file, defaults")
+			} else {
+				fmt.Println(fmt.Sprintf("# %s", settingsPath))
+			}
+			return settings.Encode(os.Stdout, showFormat)
+		},
+	}
+	showCmd.Flags().StringVar(&showFormat, "as", "toml", `format of the configuration: "toml", "yaml" or "json"`)
+
+	configCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// loadSettings reads the configuration, overrides it with the environment and the flags,
+// and applies it.
+func loadSettings() error {
+	path := configPath
+	if path == "" {
+		var err error
+		if path, err = config.Path(); err != nil {
+			return err
+		}
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := loaded.ApplyEnvironment(os.LookupEnv); err != nil {
+		return err
+	}
+
+	flags := rootCmd.PersistentFlags()
+	if flags.Changed("playerctld") {
+		loaded.Playerctld = playerctld.String()
+		loaded.Override("playerctld", "--playerctld")
+	}
+	if flags.Changed("template") {
+		loaded.Template = outputTemplate
+		loaded.Format = config.FormatTemplate
+		loaded.Override("template", "--template")
+		loaded.Override("format", "--template")
+	}
+	if flags.Changed("format") {
+		loaded.Format = outputFormat
+		loaded.Override("format", "--format")
+	}
+	if err := loaded.Validate(); err != nil {
+		return err
+	}
+
+	mode, _ := mpris.ParsePlayerctldMode(loaded.Playerctld)
+	playerctld = PlayerctldMode(mode)
+	if err := mprisctl.SetOutput(loaded.Format, loaded.Template, loaded.Templates); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	settings, settingsPath = loaded, path
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/internal/hooks"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var exportProxy bool
+	var exclusive, resume bool
+	var exclusiveAllow []string
+	var duck []string
+	var duckAmount float64
+	var duckRamp time.Duration
+
+	var cmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Track the players in the background",
+		Long: `Track the players in the background, ordered by last activity.
+
+A player becomes the most recently active when it starts playing, or when it
+changes track or seeks while playing. The order is kept across restarts in
+$XDG_STATE_HOME/mprisctl/stack.json.
+
+Commands given no -p ask the daemon for the most recently active player, on the
+socket $XDG_RUNTIME_DIR/mprisctl.sock, and loop, shuffle and volume read the
+state of the player from it. The daemon is only asked about the buses it
+watches: when it is not running or watches other buses than the one selected,
+they pick the first playing player and query it on the bus instead.
+
+Other programs can use the socket too. It speaks JSON-RPC 2.0, one message per
+line, with the methods:
+  active     the most recently active player, or null
+  players    the players, the most recently active first; when the daemon
+             watches several buses, they are named with their bus, like vlc@system
+  buses      the buses watched, as {"label": "system", "address": "..."}
+  player     the state of {"player": "vlc"}
+  call       {"player": "vlc", "action": "volume", "value": 0.5}, where action is
+             play, pause, play-pause, stop, next, previous, raise, seek, position,
+             open-uri, loop, shuffle, volume or rate; durations are in microseconds
+  subscribe  {"players": ["vlc"], "types": ["playback-changed"]}, returning the
+             players and then notifying their events as "event" notifications
+The player defaults to the most recently active player.
+
+With --proxy, the most recently active player is also exported on the bus as
+org.mpris.MediaPlayer2.mprisctl, a player forwarding every call to it: media
+keys and desktop widgets can then target whatever is playing.
+
+With --exclusive, the players play one at a time: when one starts playing, the
+other playing players are paused. With --resume, they are resumed once it stops
+playing or leaves the bus. The players of --exclusive-allow may overlap with
+the others: they neither pause them nor are paused. The [exclusive] section of
+the configuration sets the same, for the flags not given.
+
+With --duck, the volume of the other playing players is lowered while the given
+player is playing, like a video call or a screen reader: by --duck-amount of it,
+gradually over --duck-ramp. It is restored once no such player is playing, unless
+it was changed meanwhile. The players whose volume cannot be changed are paused
+instead, and resumed.
+The [duck] section of the configuration sets the same.
+
+The hooks of the configuration are run on the events, as by watch.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var options mprisctl.DaemonOptions
+			if exportProxy {
+				proxyConn, err := ConnectBus()
+				if err != nil {
+					return err
+				}
+				defer proxyConn.Close()
+				options.ProxyConn = proxyConn
+			}
+			if configHooks := settings.HookList(); len(configHooks) > 0 {
+				options.Runner = hooks.NewRunner(configHooks, settings.HookOptions())
+			}
+
+			if cmd.Flags().Changed("exclusive") == false {
+				exclusive = settings.Exclusive.Enabled
+			}
+			if exclusive {
+				if cmd.Flags().Changed("resume") == false {
+					resume = settings.Exclusive.Resume
+				}
+				allow := settings.ExclusiveAllowList()
+				if cmd.Flags().Changed("exclusive-allow") {
+					allow = resolveAliases(exclusiveAllow)
+				}
+				options.Exclusive = &daemon.ExclusiveOptions{Resume: resume, Allow: allow}
+			}
+
+			ducking := daemon.DuckOptions{Players: settings.DuckList(), Amount: settings.Duck.Amount, Ramp: time.Duration(settings.Duck.Ramp)}
+			if cmd.Flags().Changed("duck") {
+				ducking.Players = resolveAliases(duck)
+			}
+			if cmd.Flags().Changed("duck-amount") {
+				if duckAmount <= 0 || duckAmount > 1 {
+					return errors.New("--duck-amount must be between 0 and 1")
+				}
+				ducking.Amount = duckAmount
+			}
+			if cmd.Flags().Changed("duck-ramp") {
+				if duckRamp < 0 {
+					return errors.New("--duck-ramp must not be negative")
+				}
+				ducking.Ramp = duckRamp
+			}
+			if len(ducking.Players) > 0 {
+				options.Ducking = &ducking
+			}
+
+			buses := selectedBuses()
+			for _, bus := range buses {
+				info := daemon.BusInfo{Address: bus.busAddress()}
+				if len(buses) > 1 {
+					info.Label = bus.label
+				}
+				options.Buses = append(options.Buses, info)
+			}
+
+			return WithClients(func(clients []*mpris.Client) error {
+				return mprisctl.RunDaemon(ctx, options, clients...)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&exclusive, "exclusive", false, "pause the other playing players when one starts playing")
+	cmd.Flags().BoolVar(&resume, "resume", false, "with --exclusive, resume the paused players once the player pausing them stops")
+	cmd.Flags().StringArrayVar(&exclusiveAllow, "exclusive-allow", nil, "with --exclusive, a player allowed to play along the others (repeatable)")
+	cmd.Flags().StringArrayVar(&duck, "duck", nil, "lower the volume of the other players while this player is playing (repeatable)")
+	cmd.Flags().Float64Var(&duckAmount, "duck-amount", daemon.DefaultDuckAmount, "part of their volume the ducked players lose, between 0 and 1")
+	cmd.Flags().DurationVar(&duckRamp, "duck-ramp", daemon.DefaultDuckRamp, "duration of the volume changes of ducking")
+	cmd.Flags().BoolVar(&exportProxy, "proxy", false, "export the most recently active player as org.mpris.MediaPlayer2.mprisctl")
+
+	rootCmd.AddCommand(cmd)
+}
+
+// resolveAliases returns names, their aliases resolved.
+func resolveAliases(names []string) []string {
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		resolved = append(resolved, settings.ResolveAlias(name))
+	}
+	return resolved
+}
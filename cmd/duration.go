@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration parses a duration given like 1:02, 1:02:30, 62.5 or 1m2s, the bare numbers being seconds.
+func parseDuration(v string) (time.Duration, error) {
+	if strings.Contains(v, ":") {
+		var total float64
+		parts := strings.Split(v, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid time %q", v)
+		}
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(part, 64)
+			// Only the seconds may have decimals.
+			if err != nil || value < 0 || (i < len(parts)-1 && value != float64(int(value))) {
+				return 0, fmt.Errorf("invalid time %q", v)
+			}
+			total = total*60 + value
+		}
+		return time.Duration(total * float64(time.Second)), nil
+	}
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	duration, err := time.ParseDuration(v)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid time %q, expecting like 1:02, 62.5 or 1m2s", v)
+	}
+	return duration, nil
+}
+
+// Timestamp is a position in a track, like 1:02, 1:02:30, 62.5 or 1m2s.
+type Timestamp time.Duration
+
+func (t *Timestamp) String() string {
+	if *t == 0 {
+		// Not shown as a default in the help text.
+		return ""
+	}
+	return time.Duration(*t).String()
+}
+
+func (t *Timestamp) Set(v string) error {
+	duration, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(duration)
+	return nil
+}
+
+func (t *Timestamp) Type() string {
+	return "time"
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+	}{
+		{"1:02", 62 * time.Second},
+		{"1:02:30", time.Hour + 2*time.Minute + 30*time.Second},
+		{"0:62.5", 62500 * time.Millisecond},
+		{"62.5", 62500 * time.Millisecond},
+		{"1m2s", 62 * time.Second},
+		{"30m", 30 * time.Minute},
+	}
+	for _, test := range tests {
+		duration, err := parseDuration(test.value)
+		if err != nil || duration != test.duration {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", test.value, duration, err, test.duration)
+		}
+	}
+
+	for _, value := range []string{"", "1:2:3:4", "1.5:00", "-1", "-1m", "1:-2", "soon"} {
+		if _, err := parseDuration(value); err == nil {
+			t.Errorf("parseDuration(%q) succeeded, want an error", value)
+		}
+	}
+}
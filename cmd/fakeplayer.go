@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/webflo-dev/mpris-ctl/internal/fakeplayer"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func init() {
+	var options fakeplayer.Options
+	var tracks []string
+	var disabled []string
+	var loopStatus LoopStatus = LoopStatus(mpris.LoopStatusNone)
+	var scriptPath string
+
+	var cmd = &cobra.Command{
+		Use:   "fake-player",
+		Short: "Export a scriptable fake player on the bus, for testing",
+		Long: `Export a fake player on the bus, whose position advances while playing.
+
+Commands are read from stdin, one per line, either as text or as JSON:
+  play, pause, play-pause, stop, next, previous, seek 10s, position 1m,
+  set Volume 0.5, set can-seek false, metadata title="A title" artist=A,B length=3m,
+  vanish, quit
+  {"action": "set", "property": "LoopStatus", "value": "Track"}
+
+A script, given with --script, is a JSON array of the same commands,
+or one command per line, each one waiting for its "after" delay.
+
+Every call made by D-Bus clients is printed, like CALL::Play.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			for _, track := range tracks {
+				parsed, err := fakeplayer.ParseTrack(track)
+				if err != nil {
+					return err
+				}
+				options.Tracks = append(options.Tracks, parsed)
+			}
+			if options.Capabilities, err = fakeplayer.ParseCapabilities(disabled); err != nil {
+				return err
+			}
+			if options.Identity == "" {
+				options.Identity = options.Name
+			}
+			options.LoopStatus = mpris.LoopStatus(loopStatus)
+			options.Output = cmd.OutOrStdout()
+
+			var script []fakeplayer.Command
+			if scriptPath != "" {
+				file, err := os.Open(scriptPath)
+				if err != nil {
+					return err
+				}
+				script, err = fakeplayer.ReadScript(file)
+				file.Close()
+				if err != nil {
+					return err
+				}
+			}
+
+			return runFakePlayer(cmd, options, script)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Name, "name", "", "player name, the bus name being org.mpris.MediaPlayer2.<name>")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVar(&options.Identity, "identity", "", "identity of the player (default is the name)")
+	cmd.Flags().StringVar(&options.DesktopEntry, "desktop-entry", "", "desktop entry of the player")
+	cmd.Flags().StringArrayVar(&tracks, "track", nil, `track, as title="A title" artist=A,B album=... length=3m url=... art_url=... (repeatable)`)
+	cmd.Flags().StringSliceVar(&disabled, "disable", nil, "capabilities to disable, among control, go-next, go-previous, pause, play and seek")
+	cmd.Flags().BoolVar(&options.Playing, "playing", false, "start playing")
+	cmd.Flags().Float64Var(&options.Volume, "volume", 1, "initial volume")
+	cmd.Flags().BoolVar(&options.FixedVolume, "fixed-volume", false, "reject the volume changes")
+	cmd.Flags().Float64Var(&options.Rate, "rate", 1, "initial playback rate")
+	cmd.Flags().Var(&loopStatus, "loop", "initial loop status")
+	cmd.RegisterFlagCompletionFunc("loop", loopStatusCompletion)
+	cmd.Flags().BoolVar(&options.Shuffle, "shuffle", false, "initial shuffle")
+	cmd.Flags().BoolVar(&options.TrackList, "track-list", false, "export the org.mpris.MediaPlayer2.TrackList interface")
+	cmd.Flags().StringVar(&scriptPath, "script", "", "file of commands to run")
+	cmd.Flags().StringSliceVar(&options.PlayerctldPlayers, "playerctld-players", nil, "act as playerctld fronting these players, the first one being active")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runFakePlayer(cmd *cobra.Command, options fakeplayer.Options, script []fakeplayer.Command) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := ConnectBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	player, err := fakeplayer.Start(conn, options)
+	if err != nil {
+		return err
+	}
+	defer player.Close()
+	fmt.Fprintf(cmd.OutOrStdout(), "READY::%s\n", mpris.BusName(options.Name))
+
+	go player.ReadCommands(cmd.InOrStdin(), cmd.ErrOrStderr())
+	go func() {
+		if err := player.RunScript(ctx, script); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "ERROR::script %s\n", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-player.Done():
+	}
+	return nil
+}
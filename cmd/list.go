@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var cmd = &cobra.Command{
+		Use:   "list",
+		Short: "List players",
+		Long: `List the players on the bus, one per line.
+
+Players are named by application, like firefox, followed by their instance
+when they have one, like instance=instance_1_84. "-p firefox" designates any instance.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return WithClients(func(clients []*mpris.Client) error {
+				return mprisctl.List(cmd.Context(), clients...)
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
@@ -1,11 +1,12 @@
 package cmd
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	mprisctl "mprisctl/internal"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
 func init() {
@@ -16,32 +17,42 @@ func init() {
 	var cmd = &cobra.Command{
 		Use:   "loop",
 		Short: "Get or set loop status",
-		Run: func(cmd *cobra.Command, args []string) {
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if cmd.Flags().Changed(setFlagName) {
-				SetLoopStatus(playerName, string(loopStatusValue))
+				return SetLoopStatus(cmd.Context(), playerName, mpris.LoopStatus(loopStatusValue))
 			} else {
-				printLoopStatus(playerName)
+				return printLoopStatus(cmd.Context(), playerName)
 			}
 		},
 	}
 
-	WithRequiredPlayer(cmd, &playerName)
+	WithPlayer(cmd, &playerName)
 	cmd.Flags().Var(&loopStatusValue, setFlagName, "set loop status")
 	cmd.RegisterFlagCompletionFunc(setFlagName, loopStatusCompletion)
 
 	rootCmd.AddCommand(cmd)
 }
 
-func printLoopStatus(playerName string) {
-	mpris := mprisctl.NewMpris()
-	if loopStatus, ok := mpris.LoopStatus(mprisctl.GetPlayerId(playerName)); ok {
-		fmt.Println(loopStatus)
+func printLoopStatus(ctx context.Context, playerName string) error {
+	if player, err := daemonPlayer(ctx, playerName); err == nil {
+		fmt.Println(player.LoopStatus)
+		return nil
 	}
+	return WithClient(func(client *mpris.Client) error {
+		loopStatus, err := client.LoopStatus(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		fmt.Println(loopStatus)
+		return nil
+	})
 }
 
-func SetLoopStatus(playerName string, status string) {
-	mpris := mprisctl.NewMpris()
-	mpris.SetLoopStatus(mprisctl.GetPlayerId(playerName), status)
+func SetLoopStatus(ctx context.Context, playerName string, status mpris.LoopStatus) error {
+	return WithClient(func(client *mpris.Client) error {
+		return client.SetLoopStatus(ctx, playerName, status)
+	})
 }
 
 type LoopStatus string
@@ -53,13 +64,11 @@ func (l *LoopStatus) String() string {
 
 // Set must have pointer receiver so it doesn't change the value of a copy
 func (e *LoopStatus) Set(v string) error {
-	switch v {
-	case string(mprisctl.LoopStatusNone), string(mprisctl.LoopStatusPlaylist), string(mprisctl.LoopStatusTrack):
+	if mpris.LoopStatus(v).IsValid() {
 		*e = LoopStatus(v)
 		return nil
-	default:
-		return errors.New(fmt.Sprintf(`must be one of "%s", "%s", or "%s"`, mprisctl.LoopStatusNone, mprisctl.LoopStatusPlaylist, mprisctl.LoopStatusTrack))
 	}
+	return fmt.Errorf("must be one of %s", quoteLoopStatuses())
 }
 
 // Type is only used in help text
@@ -67,10 +76,18 @@ func (e *LoopStatus) Type() string {
 	return "LoopStatus"
 }
 
+func quoteLoopStatuses() string {
+	quoted := make([]string, 0, len(mpris.LoopStatuses))
+	for _, status := range mpris.LoopStatuses {
+		quoted = append(quoted, fmt.Sprintf("%q", status))
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + ", or " + quoted[len(quoted)-1]
+}
+
 func loopStatusCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	return []string{
-		mprisctl.LoopStatusNone,
-		mprisctl.LoopStatusPlaylist,
-		mprisctl.LoopStatusTrack,
-	}, cobra.ShellCompDirectiveDefault
+	completions := make([]string, 0, len(mpris.LoopStatuses))
+	for _, status := range mpris.LoopStatuses {
+		completions = append(completions, string(status))
+	}
+	return completions, cobra.ShellCompDirectiveDefault
 }
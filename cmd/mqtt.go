@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/internal/mqttbridge"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var options mqttbridge.Options
+	var passwordFile string
+	var noDiscovery bool
+
+	var cmd = &cobra.Command{
+		Use:   "mqtt",
+		Short: "Bridge the players to an MQTT broker",
+		Long: `Bridge the players to an MQTT broker.
+
+The state of each player is published, retained, under
+<prefix>/<host>/<player>/: status, state (as Home Assistant names it), title,
+artist, album, art_url, length and position (in seconds), volume, rate, shuffle,
+loop, and info, the whole state as JSON. The position is published every 10s
+while playing, and on every other change.
+
+Publishing on <prefix>/<host>/<player>/set/<action> runs an action on the
+player, and on <prefix>/<host>/set/<action> on every player:
+  play, pause, play-pause, stop, next, previous, raise, open-uri,
+  seek and position (in seconds), loop, shuffle, volume and rate
+The payload is the value, like 0.5 for volume or Track for loop.
+
+<prefix>/<host>/available is online while the bridge is connected, and
+offline otherwise. The players are announced to Home Assistant by MQTT
+discovery, under the discovery prefix, with the entities of its MQTT integration:
+each player is a device with sensors for its state, title, artist, album,
+position and length, buttons for play-pause, next, previous and stop, and a
+number for its volume.
+
+For example, to pause every player of the host desktop:
+  mosquitto_pub -t mprisctl/desktop/set/pause -n`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if passwordFile != "" {
+				password, err := os.ReadFile(passwordFile)
+				if err != nil {
+					return err
+				}
+				options.Password = strings.TrimSpace(string(password))
+			}
+			if options.Host == "" {
+				host, err := os.Hostname()
+				if err != nil {
+					return err
+				}
+				options.Host, _, _ = strings.Cut(host, ".")
+			}
+			if options.ClientID == "" {
+				options.ClientID = "mprisctl-" + options.Host
+			}
+			if noDiscovery {
+				options.DiscoveryPrefix = ""
+			}
+			return WithClient(func(client *mpris.Client) error {
+				return mprisctl.BridgeMQTT(ctx, client, options)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Broker, "broker", "", "URL of the broker, like tcp://localhost:1883, ssl://... or ws://...")
+	cmd.Flags().StringVar(&options.Username, "username", "", "user name to connect with")
+	cmd.Flags().StringVar(&passwordFile, "password-file", "", "file holding the password to connect with")
+	cmd.Flags().StringVar(&options.ClientID, "client-id", "", "client ID (default mprisctl-<host>)")
+	cmd.Flags().StringVar(&options.Host, "host", "", "name of the machine in the topics (default the host name)")
+	cmd.Flags().StringVar(&options.TopicPrefix, "topic-prefix", "mprisctl", "first level of the topics")
+	cmd.Flags().StringVar(&options.DiscoveryPrefix, "discovery-prefix", "homeassistant", "prefix of the Home Assistant discovery topics")
+	cmd.Flags().BoolVar(&noDiscovery, "no-discovery", false, "do not announce the players to Home Assistant")
+	cmd.MarkFlagRequired("broker")
+
+	rootCmd.AddCommand(cmd)
+}
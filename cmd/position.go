@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	mprisctl "mprisctl/internal"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
 func init() {
@@ -16,29 +18,35 @@ func init() {
 		Use:   "position",
 		Short: "Get or set position",
 		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if cmd.Flags().Changed(setFlagName) {
-				setPosition(playerName, setValue)
+				return setPosition(cmd.Context(), playerName, setValue)
 			} else {
-				printPosition(playerName)
+				return printPosition(cmd.Context(), playerName)
 			}
 		},
 	}
 
-	WithRequiredPlayer(cmd, &playerName)
-	cmd.Flags().Int64Var(&setValue, setFlagName, 0, "set position")
+	WithPlayer(cmd, &playerName)
+	cmd.Flags().Int64Var(&setValue, setFlagName, 0, "set position in microseconds")
 
 	rootCmd.AddCommand(cmd)
 }
 
-func printPosition(player string) {
-	mpris := mprisctl.NewMpris()
-	if position, ok := mpris.Position(mprisctl.GetPlayerId(player)); ok {
-		fmt.Println(position)
-	}
+// printPosition asks the player, the daemon only updating the positions every second.
+func printPosition(ctx context.Context, playerName string) error {
+	return WithClient(func(client *mpris.Client) error {
+		position, err := client.Position(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		fmt.Println(position.Microseconds())
+		return nil
+	})
 }
 
-func setPosition(playerName string, value int64) {
-	mpris := mprisctl.NewMpris()
-	mpris.SetPosition(mprisctl.GetPlayerId(playerName), value)
+func setPosition(ctx context.Context, playerName string, value int64) error {
+	return WithClient(func(client *mpris.Client) error {
+		return client.SetPosition(ctx, playerName, time.Duration(value)*time.Microsecond)
+	})
 }
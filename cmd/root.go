@@ -1,24 +1,293 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/godbus/dbus/v5"
 	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "mprisctl",
-	Short: "A command line tool to control MPRIS enabled media players",
+	Use:          "mprisctl",
+	Short:        "A command line tool to control MPRIS enabled media players",
+	SilenceUsage: true,
+}
+
+var (
+	// busAddresses are the buses given with --bus-address, as [label=]address.
+	busAddresses []string
+	systemBus    bool
+	sessionBus   bool
+	playerctld   PlayerctldMode
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&busAddresses, "bus-address", nil, "address of a bus to connect to, optionally labelled as label=address (default is $DBUS_SESSION_BUS_ADDRESS)")
+	rootCmd.PersistentFlags().BoolVar(&systemBus, "system", false, "connect to the system bus")
+	rootCmd.PersistentFlags().BoolVar(&sessionBus, "session", false, "connect to the session bus, along with the buses selected by --system and --bus-address")
+	rootCmd.PersistentFlags().Var(&playerctld, "playerctld", `how to treat playerctld and the proxy of the mprisctl daemon: "hide" them from lists, "show" them, or "proxy" playerctld to the player it fronts`)
+	rootCmd.RegisterFlagCompletionFunc("playerctld", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"hide", "show", "proxy"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+type PlayerctldMode mpris.PlayerctldMode
+
+func (m *PlayerctldMode) String() string {
+	return mpris.PlayerctldMode(*m).String()
+}
+
+func (m *PlayerctldMode) Set(v string) error {
+	mode, err := mpris.ParsePlayerctldMode(v)
+	if err != nil {
+		return fmt.Errorf(`must be one of "hide", "show", or "proxy"`)
+	}
+	*m = PlayerctldMode(mode)
+	return nil
+}
+
+func (m *PlayerctldMode) Type() string {
+	return "PlayerctldMode"
 }
 
 func Execute() {
 	err := rootCmd.Execute()
+	if sharedClient != nil {
+		sharedClient.Close()
+	}
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
-func WithRequiredPlayer(cmd *cobra.Command, target *string) {
-	cmd.Flags().StringVarP(target, "player", "p", "", "name of the player")
-	cmd.MarkFlagRequired("player")
+// WithPlayer adds the -p flag to cmd, along with --launch to start the player when needed.
+// Without -p, cmd targets the configured player, else the first running player of the priority list,
+// else the most recently active player, as tracked by the daemon if it is running.
+// A player named like vlc@system is looked for on the bus with this label.
+func WithPlayer(cmd *cobra.Command, target *string) {
+	var launch bool
+	var launchTimeout time.Duration
+
+	cmd.Flags().StringVarP(target, "player", "p", "", "name of the player, followed by @<bus> for another bus (default is the most recently active player)")
+	cmd.Flags().BoolVar(&launch, "launch", false, "start the player if it is not running, with D-Bus activation or its desktop entry")
+	cmd.Flags().DurationVar(&launchTimeout, "launch-timeout", 10*time.Second, "how long to wait for a launched player")
+
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if *target == "" {
+			*target = settings.Player
+		}
+		*target = settings.ResolveAlias(*target)
+		if *target == "" {
+			if launch {
+				return errors.New("--launch needs the player to be named with -p")
+			}
+			selection, err := selectedBus()
+			if err != nil {
+				return err
+			}
+			playerName, err := mprisctl.ActivePlayer(cmd.Context(), selection.busAddress(), connectClient, settings.PriorityList())
+			if err != nil {
+				return err
+			}
+			*target = playerName
+		}
+		playerName, err := selectPlayerBus(*target)
+		if err != nil {
+			return err
+		}
+		*target = playerName
+		if launch == false {
+			return nil
+		}
+		return WithClient(func(client *mpris.Client) error {
+			return mprisctl.EnsurePlayer(cmd.Context(), client, *target, launchTimeout)
+		})
+	}
+}
+
+// playerBus, when set by WithPlayer, is the bus of the player, overriding the buses selected on the command line.
+var playerBus *busSelection
+
+// selectPlayerBus selects the bus of target when it is labelled like vlc@system, returning the player name.
+// The labels are the ones of the buses selected on the command line, along with "session" and "system".
+func selectPlayerBus(target string) (string, error) {
+	playerName, label, found := strings.Cut(target, "@")
+	if found == false {
+		return target, nil
+	}
+	buses := append(selectedBuses(), busSelection{label: "session"}, busSelection{label: "system", address: systemBusAddress})
+	for i := range buses {
+		if buses[i].label == label {
+			playerBus = &buses[i]
+			return playerName, nil
+		}
+	}
+	return "", fmt.Errorf("%s: no bus is labelled %s, give its address with --bus-address %s=ADDRESS", target, label, label)
+}
+
+// daemonPlayer returns the state of playerName on the selected bus as tracked by the daemon, for the commands
+// reading the state of a player to answer without querying it. The error wraps daemon.ErrNotRunning
+// when the daemon is not running, daemon.ErrBusNotWatched when it does not watch the selected bus,
+// and mpris.ErrPlayerNotFound when it does not track the player.
+func daemonPlayer(ctx context.Context, playerName string) (*daemon.PlayerInfo, error) {
+	selection, err := selectedBus()
+	if err != nil {
+		return nil, err
+	}
+	return mprisctl.DaemonPlayer(ctx, playerName, selection.busAddress())
+}
+
+// busSelection is a bus selected on the command line.
+type busSelection struct {
+	label string
+	// address is empty for the session bus, and "system" for the system bus.
+	address string
+}
+
+const systemBusAddress = "system"
+
+// selectedBuses returns the buses selected by --session, --system and --bus-address,
+// the session bus being the default.
+func selectedBuses() []busSelection {
+	buses := make([]busSelection, 0)
+	if sessionBus {
+		buses = append(buses, busSelection{label: "session"})
+	}
+	if systemBus {
+		buses = append(buses, busSelection{label: "system", address: systemBusAddress})
+	}
+	for i, address := range busAddresses {
+		label := fmt.Sprintf("bus%d", i+1)
+		// D-Bus addresses start with a transport followed by a colon, which labels cannot contain.
+		if prefix, rest, found := strings.Cut(address, "="); found && strings.Contains(prefix, ":") == false {
+			label, address = prefix, rest
+		}
+		buses = append(buses, busSelection{label: label, address: address})
+	}
+	if len(buses) == 0 {
+		buses = append(buses, busSelection{label: "session"})
+	}
+	return buses
+}
+
+func selectedBus() (busSelection, error) {
+	if playerBus != nil {
+		return *playerBus, nil
+	}
+	buses := selectedBuses()
+	if len(buses) > 1 {
+		return busSelection{}, errors.New("this command works on a single bus, select one with --session, --system or --bus-address")
+	}
+	return buses[0], nil
+}
+
+// busAddress returns the D-Bus address of the bus, resolving the session and system buses
+// the way the connection to them does, to tell whether the daemon watches the same bus.
+func (b busSelection) busAddress() string {
+	switch b.address {
+	case "":
+		if address := os.Getenv("DBUS_SESSION_BUS_ADDRESS"); address != "" {
+			return address
+		}
+		return "unix:path=" + filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "bus")
+	case systemBusAddress:
+		if address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"); address != "" {
+			return address
+		}
+		return "unix:path=/var/run/dbus/system_bus_socket"
+	default:
+		return b.address
+	}
+}
+
+func (b busSelection) connect() (mpris.Bus, error) {
+	if b.address == systemBusAddress {
+		return mpris.ConnectSystemBus()
+	}
+	return mpris.ConnectBus(b.address)
+}
+
+func newClient(bus mpris.Bus) *mpris.Client {
+	client := mpris.NewWithBus(bus)
+	client.SetPlayerctldMode(mpris.PlayerctldMode(playerctld))
+	client.SetIgnoredPlayers(settings.IgnoreList())
+	return client
+}
+
+// sharedClient is the client of WithClient, connected on first use and closed by Execute.
+var sharedClient *mpris.Client
+
+// connectClient returns the client of WithClient, connecting it to the selected bus on first use.
+func connectClient() (*mpris.Client, error) {
+	if sharedClient != nil {
+		return sharedClient, nil
+	}
+	selection, err := selectedBus()
+	if err != nil {
+		return nil, err
+	}
+	bus, err := selection.connect()
+	if err != nil {
+		return nil, err
+	}
+	sharedClient = newClient(bus)
+	return sharedClient, nil
+}
+
+// WithClient runs callback with a client connected to the bus, the same for every call of a command.
+func WithClient(callback func(client *mpris.Client) error) error {
+	client, err := connectClient()
+	if err != nil {
+		return err
+	}
+	return callback(client)
+}
+
+// WithClients runs callback with a client connected to each selected bus.
+// When there are several, the players of each client are labelled with their bus.
+func WithClients(callback func(clients []*mpris.Client) error) error {
+	buses := selectedBuses()
+	clients := make([]*mpris.Client, 0, len(buses))
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+	for _, selection := range buses {
+		bus, err := selection.connect()
+		if err != nil {
+			return fmt.Errorf("%s bus: %w", selection.label, err)
+		}
+		client := newClient(bus)
+		if len(buses) > 1 {
+			client.SetBusLabel(selection.label)
+		}
+		clients = append(clients, client)
+	}
+	return callback(clients)
+}
+
+// ConnectBus opens a connection to the bus selected by --session, --system or --bus-address.
+func ConnectBus() (*dbus.Conn, error) {
+	selection, err := selectedBus()
+	if err != nil {
+		return nil, err
+	}
+	switch selection.address {
+	case "":
+		return dbus.ConnectSessionBus()
+	case systemBusAddress:
+		return dbus.ConnectSystemBus()
+	default:
+		return dbus.Connect(selection.address)
+	}
 }
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var options mprisctl.ServeOptions
+	var tokenFile string
+
+	var cmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the players to other devices",
+		Long: `Serve the players to other devices.
+
+With --http, a REST API is served:
+  GET  /players                  the players
+  GET  /players/{name}           the state of a player
+  GET  /players/{name}/art       the art of the current track of a player
+  POST /players/{name}/{action}  play, pause, play-pause, stop, next, previous,
+                                 raise, seek and open-uri
+  PUT  /players/{name}/{field}   position, volume, loop, shuffle and rate
+  GET  /events                   the events, as Server-Sent Events
+  GET  /ws                       the events, on a WebSocket
+Values are given as {"value": ...}, durations being in microseconds.
+
+Streams of events start with a snapshot of the players, and are filtered with
+the query parameters player and type, like /events?player=vlc&type=track-changed.
+Clients which cannot keep up with the events are disconnected after an overflow
+message.
+
+Requests must carry the token given by --token or --token-file, as
+"Authorization: Bearer <token>", or as the query parameter access_token. A token is required to listen on an address
+other than the loopback one.
+
+Without token, so that the pages of other sites open in a browser cannot drive
+the players, requests must be for a loopback host like localhost, come from the
+remote or from an origin given by --cors-origin, and carry
+"Content-Type: application/json" when calling actions.
+
+The root serves a remote for browsers, listing the players with their art,
+track, progress and volume, with transport and seek controls: open
+http://<address>/#token=<token> on a phone to control the desktop, the token
+being remembered by the browser. --no-ui disables it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if tokenFile != "" {
+				token, err := os.ReadFile(tokenFile)
+				if err != nil {
+					return err
+				}
+				options.HTTP.Token = strings.TrimSpace(string(token))
+			}
+			return WithClient(func(client *mpris.Client) error {
+				return mprisctl.Serve(ctx, client, options)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&options.HTTPAddress, "http", "", "address to serve the HTTP API on, like 127.0.0.1:8765")
+	cmd.Flags().StringVar(&options.HTTP.Token, "token", "", "bearer token required by the HTTP API")
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "file holding the bearer token required by the HTTP API")
+	cmd.Flags().StringArrayVar(&options.HTTP.CORSOrigins, "cors-origin", nil, `origin allowed to call the HTTP API from a browser, "*" allowing any`)
+	cmd.Flags().BoolVar(&options.HTTP.DisableUI, "no-ui", false, "do not serve the remote at the root")
+	cmd.MarkFlagsMutuallyExclusive("token", "token-file")
+
+	rootCmd.AddCommand(cmd)
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	mprisctl "mprisctl/internal"
 
 	"github.com/spf13/cobra"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
 func init() {
@@ -16,29 +17,38 @@ func init() {
 		Use:   "shuffle",
 		Short: "Get or set shuffle",
 		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if cmd.Flags().Changed(setFlagName) {
-				setShuffle(playerName, setValue)
+				return setShuffle(cmd.Context(), playerName, setValue)
 			} else {
-				printShuffle(playerName)
+				return printShuffle(cmd.Context(), playerName)
 			}
 		},
 	}
 
-	WithRequiredPlayer(cmd, &playerName)
+	WithPlayer(cmd, &playerName)
 	cmd.Flags().BoolVar(&setValue, setFlagName, false, "set shuffle on or off")
 
 	rootCmd.AddCommand(cmd)
 }
 
-func printShuffle(playerName string) {
-	mpris := mprisctl.NewMpris()
-	if value, ok := mpris.Shuffle(mprisctl.GetPlayerId(playerName)); ok {
-		fmt.Println(value)
+func printShuffle(ctx context.Context, playerName string) error {
+	if player, err := daemonPlayer(ctx, playerName); err == nil {
+		fmt.Println(player.Shuffle)
+		return nil
 	}
+	return WithClient(func(client *mpris.Client) error {
+		value, err := client.Shuffle(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	})
 }
 
-func setShuffle(playerName string, value bool) {
-	mpris := mprisctl.NewMpris()
-	mpris.SetShuffle(mprisctl.GetPlayerId(playerName), value)
+func setShuffle(ctx context.Context, playerName string, value bool) error {
+	return WithClient(func(client *mpris.Client) error {
+		return client.SetShuffle(ctx, playerName, value)
+	})
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func init() {
+	var playerName string
+	var options mprisctl.SleepOptions
+
+	var cmd = &cobra.Command{
+		Use:   "sleep [DURATION]",
+		Short: "Pause after a time or at the end of some tracks",
+		Long: `Pause the player after a time, like 30m or 1:15:00, or with --after-track at
+the end of the current track, or with --after-track N at the end of the Nth
+track, the current one being the first.
+
+With --fade, the volume fades out before pausing: over the last minute, or over
+the given duration, like --fade=5m. The volume is restored once paused, so that the player does
+not start silent the next time. With --stop, the player is stopped instead.
+
+The timer runs until it fires, and is cancelled by an interrupt, restoring the
+volume. Run it in the background to go on with the terminal:
+  mprisctl sleep 30m --fade &`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			afterTrack := cmd.Flags().Changed("after-track")
+			if afterTrack && len(args) == 1 {
+				// --after-track N, the value not being attached to the flag.
+				tracks, err := strconv.Atoi(args[0])
+				if err != nil {
+					return errors.New("expecting either a duration or --after-track")
+				}
+				options.Tracks, args = tracks, nil
+			}
+			if (len(args) == 1) == afterTrack {
+				return errors.New("expecting either a duration or --after-track")
+			}
+			if afterTrack {
+				if options.Tracks <= 0 {
+					return errors.New("--after-track must be positive")
+				}
+			} else {
+				after, err := parseDuration(args[0])
+				if err != nil || after <= 0 {
+					return fmt.Errorf("invalid duration %q, expecting like 30m, 1h15m or 1:15:00", args[0])
+				}
+				options.After = after
+				options.Tracks = 0
+			}
+			if options.Fade < 0 {
+				return errors.New("--fade must not be negative")
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return WithClient(func(client *mpris.Client) error {
+				return mprisctl.Sleep(ctx, client, playerName, options)
+			})
+		},
+	}
+
+	WithPlayer(cmd, &playerName)
+	cmd.Flags().IntVar(&options.Tracks, "after-track", 1, "pause at the end of the current track, or of the Nth track")
+	cmd.Flags().Lookup("after-track").NoOptDefVal = "1"
+	cmd.Flags().DurationVar(&options.Fade, "fade", 0, "fade the volume out before pausing, over the given duration")
+	cmd.Flags().Lookup("fade").NoOptDefVal = "1m"
+	cmd.Flags().BoolVar(&options.Stop, "stop", false, "stop the player instead of pausing it")
+
+	rootCmd.AddCommand(cmd)
+}
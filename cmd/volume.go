@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func init() {
+	var playerName string
+	var setValue float64
+	var up, down bool
+	var step float64
+	var fadeTo float64
+	var over time.Duration
+	var setFlagName = "set"
+
+	var cmd = &cobra.Command{
+		Use:   "volume",
+		Short: "Get or set volume",
+		Long: `Get or set the volume, 1.0 being the nominal volume.
+
+--up and --down change it by a step, which is --step, else the step of the
+player in the configuration, else the step of the configuration (0.05 by
+default). The volume is then kept between 0 and 1.
+
+--fade-to changes it gradually, over --over, until the next command on the
+player cancels the fade.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case cmd.Flags().Changed(setFlagName):
+				return setVolume(cmd.Context(), playerName, setValue)
+			case cmd.Flags().Changed("fade-to"):
+				if over < 0 {
+					return errors.New("--over must not be negative")
+				}
+				return fadeVolume(cmd, playerName, fadeTo, over)
+			case up || down:
+				if cmd.Flags().Changed("step") == false {
+					step = settings.VolumeStep(playerName)
+				}
+				if down {
+					step = -step
+				}
+				return changeVolume(cmd.Context(), playerName, step)
+			default:
+				return printVolume(cmd.Context(), playerName)
+			}
+		},
+	}
+
+	WithPlayer(cmd, &playerName)
+	cmd.Flags().Float64Var(&setValue, setFlagName, 0, "set volume")
+	cmd.Flags().BoolVar(&up, "up", false, "raise the volume by a step")
+	cmd.Flags().BoolVar(&down, "down", false, "lower the volume by a step")
+	cmd.Flags().Float64Var(&step, "step", 0, "step of --up and --down (default from the configuration)")
+	cmd.Flags().Float64Var(&fadeTo, "fade-to", 0, "change the volume gradually to this value")
+	cmd.Flags().DurationVar(&over, "over", 2*time.Second, "duration of --fade-to")
+	cmd.MarkFlagsMutuallyExclusive(setFlagName, "up", "down", "fade-to")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func printVolume(ctx context.Context, playerName string) error {
+	if player, err := daemonPlayer(ctx, playerName); err == nil {
+		fmt.Println(player.Volume)
+		return nil
+	}
+	return WithClient(func(client *mpris.Client) error {
+		volume, err := client.Volume(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		fmt.Println(volume)
+		return nil
+	})
+}
+
+func setVolume(ctx context.Context, playerName string, value float64) error {
+	return WithClient(func(client *mpris.Client) error {
+		if err := mprisctl.CancelFade(ctx, client, playerName, false); err != nil {
+			return err
+		}
+		return client.SetVolume(ctx, playerName, value)
+	})
+}
+
+func changeVolume(ctx context.Context, playerName string, delta float64) error {
+	return WithClient(func(client *mpris.Client) error {
+		if err := mprisctl.CancelFade(ctx, client, playerName, false); err != nil {
+			return err
+		}
+		volume, err := client.Volume(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		// Rounded to thousandths, so repeated changes do not accumulate floating point errors.
+		// A volume already above 1 is not lowered by --up.
+		changed := math.Round((volume+delta)*1000) / 1000
+		return client.SetVolume(ctx, playerName, math.Max(0, math.Min(math.Max(1, volume), changed)))
+	})
+}
+
+func fadeVolume(cmd *cobra.Command, playerName string, value float64, over time.Duration) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return WithClient(func(client *mpris.Client) error {
+		return mprisctl.FadeVolume(ctx, client, playerName, value, over)
+	})
+}
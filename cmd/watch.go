@@ -1,20 +1,125 @@
 package cmd
 
 import (
-	mprisctl "mprisctl/internal"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	mprisctl "github.com/webflo-dev/mpris-ctl/internal"
+	"github.com/webflo-dev/mpris-ctl/internal/hooks"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	var recordPath string
+	var replayPath string
+	var hookValues []string
+	var hookDebounce time.Duration
+	var hookOptions hooks.Options
+
 	var watchCmd = &cobra.Command{
 		Use:   "watch",
 		Short: "Watch for changes",
-		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
-			mprisctl.Watch()
+		Long: `Watch for changes of every player.
+
+Several buses can be watched at once, selecting them with --session, --system
+and --bus-address: players are then named like vlc@system.
+
+With --record, everything read from the bus is also written to a file, as JSON lines.
+With --replay, such a file is played back without a bus, printing the same output.
+
+With --hook EVENT=COMMAND, COMMAND is run with sh -c on every EVENT, which is
+an event type like track-changed, or one of:
+  play, pause, stop   the playback changed to this status
+  connect, disconnect a player appeared or vanished
+  track               the track changed
+  *                   every event but the positions
+Several events can be given, separated by commas. The event is given to the
+command as environment variables, and as JSON on its standard input:
+  MPRIS_EVENT, MPRIS_PLAYER, MPRIS_PLAYER_NAME, MPRIS_BUS, MPRIS_IDENTITY,
+  MPRIS_DISPLAY_NAME, MPRIS_ICON, MPRIS_ICON_PATH, MPRIS_STATUS, MPRIS_LOOP, MPRIS_SHUFFLE, MPRIS_VOLUME, MPRIS_RATE,
+  MPRIS_POSITION, MPRIS_TRACK_ID, MPRIS_TITLE, MPRIS_ARTIST, MPRIS_ALBUM,
+  MPRIS_ALBUM_ARTIST, MPRIS_LENGTH, MPRIS_URL and MPRIS_ART_URL
+Durations are in microseconds. The output of the commands goes to stderr, and
+failures are printed as HOOK::failed lines.
+
+--hook-concurrency commands run at once, and up to 64 more wait for their turn.
+Beyond, an event replaces the one waiting for the same command and player, or
+is dropped with a HOOK::dropped line.
+
+With --hook-debounce, a command is only run once events stop coming for this
+long, for the last of them. The hooks of the configuration are run too.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			runner, err := newHookRunner(cmd, hookValues, hookDebounce, hookOptions)
+			if err != nil {
+				return err
+			}
+
+			if replayPath != "" {
+				file, err := os.Open(replayPath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				return mprisctl.Replay(ctx, runner, file)
+			}
+
+			if recordPath == "" {
+				return WithClients(func(clients []*mpris.Client) error {
+					return mprisctl.Watch(ctx, runner, clients...)
+				})
+			}
+			return WithClient(func(client *mpris.Client) error {
+				file, err := os.Create(recordPath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				return mprisctl.Record(ctx, runner, client, file)
+			})
 		},
 	}
 
+	watchCmd.Flags().StringVar(&recordPath, "record", "", "write a recording of the bus to this file")
+	watchCmd.Flags().StringVar(&replayPath, "replay", "", "print the events of a recording instead of watching the bus")
+	watchCmd.Flags().StringArrayVar(&hookValues, "hook", nil, "run a command on events, as EVENT[,EVENT...]=COMMAND (repeatable)")
+	watchCmd.Flags().DurationVar(&hookDebounce, "hook-debounce", 0, "wait for events to stop coming for this long before running a hook")
+	watchCmd.Flags().IntVar(&hookOptions.Concurrency, "hook-concurrency", hooks.DefaultConcurrency, "number of hooks run at once")
+	watchCmd.Flags().DurationVar(&hookOptions.Timeout, "hook-timeout", hooks.DefaultTimeout, "time after which a hook is killed")
+	watchCmd.MarkFlagsMutuallyExclusive("record", "replay")
+
 	rootCmd.AddCommand(watchCmd)
 }
+
+// newHookRunner returns the runner of the hooks of the configuration and of the hooks given as
+// EVENT=COMMAND with --hook, or nil when there is none. The flags override the options of the configuration.
+func newHookRunner(cmd *cobra.Command, values []string, debounce time.Duration, options hooks.Options) (*hooks.Runner, error) {
+	runHooks := settings.HookList()
+	for _, value := range values {
+		hook, err := hooks.ParseHook(value)
+		if err != nil {
+			return nil, err
+		}
+		hook.Debounce = debounce
+		runHooks = append(runHooks, hook)
+	}
+	if len(runHooks) == 0 {
+		return nil, nil
+	}
+
+	runnerOptions := settings.HookOptions()
+	if cmd.Flags().Changed("hook-concurrency") {
+		runnerOptions.Concurrency = options.Concurrency
+	}
+	if cmd.Flags().Changed("hook-timeout") {
+		runnerOptions.Timeout = options.Timeout
+	}
+	return hooks.NewRunner(runHooks, runnerOptions), nil
+}
@@ -0,0 +1,191 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// seekTimeout is how long the positions reported by a player are ignored after seeking,
+// unless it reports the seek.
+const seekTimeout = time.Second
+
+// ABOptions configures RepeatAB.
+type ABOptions struct {
+	// A and B are the start and the end of the section repeated.
+	A time.Duration
+	B time.Duration
+	// Count is the number of times the section is played, 0 repeating it until interrupted.
+	Count int
+	// Rate, when not 0, is the playback rate of the first time.
+	Rate float64
+	// RateStep is added to the rate every time the section is played again.
+	RateStep float64
+}
+
+// abRepeat is the state of RepeatAB.
+type abRepeat struct {
+	client  *mpris.Client
+	player  *mpris.PlayerState
+	options ABOptions
+	// rate is the current rate, and originalRate the one to restore.
+	rate         float64
+	originalRate float64
+	iteration    int
+}
+
+// RepeatAB plays the section of the current track between options.A and options.B over and over,
+// seeking back to A whenever B is crossed, until ctx is done, the track changes, or the section
+// was played options.Count times. The rate is then restored.
+func RepeatAB(ctx context.Context, client *mpris.Client, playerName string, options ABOptions) error {
+	player, err := client.Player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if player.CanSeek == false {
+		return fmt.Errorf("%s cannot seek, A-B repeat needs a player supporting it", player.Name)
+	}
+	if options.A >= options.B {
+		return errors.New("A must be before B")
+	}
+	if player.Metadata.Length > 0 && options.B > player.Metadata.Length {
+		return fmt.Errorf("B is after the end of the track, at %s", convertToDuration(player.Metadata.Length))
+	}
+	r := &abRepeat{client: client, player: player, options: options, rate: player.Rate, originalRate: player.Rate}
+	if r.rate <= 0 {
+		r.rate, r.originalRate = 1, 1
+	}
+	defer r.restoreRate()
+	if options.Rate != 0 {
+		if err := r.setRate(ctx, options.Rate); err != nil {
+			return err
+		}
+	}
+
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := client.Subscribe(eventsCtx, mpris.Filter{Players: []string{player.Name}})
+
+	fmt.Println(fmt.Sprintf("AB::started player=%s a=%s b=%s", player.Name, convertToDuration(options.A), convertToDuration(options.B)))
+	if err := r.jump(ctx); err != nil {
+		return err
+	}
+	// The positions of the events are stale from a jump to A until the player reports it,
+	// or for a while for the players not reporting it.
+	seekingUntil := time.Now().Add(seekTimeout)
+	var crossB <-chan time.Time
+	if player.PlaybackStatus == mpris.PlaybackPlaying {
+		crossB = time.After(r.untilB(options.A))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-crossB:
+			if r.done() {
+				return nil
+			}
+			if err := r.jump(ctx); err != nil {
+				return err
+			}
+			seekingUntil = time.Now().Add(seekTimeout)
+			crossB = time.After(r.untilB(options.A))
+		case event, ok := <-events:
+			if ok == false {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("lost connection to the bus")
+			}
+			switch event.Type {
+			case mpris.EventPlayerVanished:
+				return fmt.Errorf("%s left the bus", player.Name)
+			case mpris.EventTrackChanged:
+				if sameTrack(event.Player.Metadata, player.Metadata) == false {
+					fmt.Println(fmt.Sprintf("AB::stopped player=%s reason=track-changed", player.Name))
+					return nil
+				}
+			case mpris.EventSeeked:
+				seekingUntil = time.Time{}
+			}
+			if time.Now().Before(seekingUntil) {
+				continue
+			}
+			position := event.Player.Position
+			switch {
+			case event.Player.PlaybackStatus != mpris.PlaybackPlaying:
+				crossB = nil
+			case position >= options.B:
+				// Crossed B between two events, or seeked past it: back to A.
+				crossB = time.After(0)
+			default:
+				crossB = time.After(r.untilB(position))
+			}
+		}
+	}
+}
+
+// untilB returns the playing time from position to B.
+func (r *abRepeat) untilB(position time.Duration) time.Duration {
+	return time.Duration(float64(r.options.B-position) / r.rate)
+}
+
+// done reports whether the section was played options.Count times, or else changes the rate
+// for the next time.
+func (r *abRepeat) done() bool {
+	if r.options.Count > 0 && r.iteration >= r.options.Count {
+		fmt.Println(fmt.Sprintf("AB::done player=%s count=%d", r.player.Name, r.iteration))
+		return true
+	}
+	if r.options.RateStep != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+		if err := r.setRate(ctx, r.rate+r.options.RateStep); err != nil {
+			fmt.Println(fmt.Sprintf("AB::failed player=%s error=%s", r.player.Name, err))
+		}
+	}
+	return false
+}
+
+// jump seeks back to A.
+func (r *abRepeat) jump(ctx context.Context) error {
+	if err := r.client.SetPosition(ctx, r.player.Name, r.options.A); err != nil {
+		return err
+	}
+	r.iteration++
+	fmt.Println(fmt.Sprintf("AB::iteration player=%s count=%d rate=%v", r.player.Name, r.iteration, r.rate))
+	return nil
+}
+
+// setRate changes the rate, kept within the rates of the player.
+func (r *abRepeat) setRate(ctx context.Context, rate float64) error {
+	if r.player.MinimumRate > 0 {
+		rate = math.Max(rate, r.player.MinimumRate)
+	}
+	if r.player.MaximumRate > 0 {
+		rate = math.Min(rate, r.player.MaximumRate)
+	}
+	// Rounded to thousandths, so repeated steps do not accumulate floating point errors.
+	rate = math.Round(rate*1000) / 1000
+	if err := r.client.SetRate(ctx, r.player.Name, rate); err != nil {
+		return err
+	}
+	r.rate = rate
+	return nil
+}
+
+// restoreRate sets the rate back to what it was, reporting the failures.
+func (r *abRepeat) restoreRate() {
+	if r.rate == r.originalRate {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	if err := r.client.SetRate(ctx, r.player.Name, r.originalRate); err != nil {
+		fmt.Println(fmt.Sprintf("AB::failed player=%s error=%s", r.player.Name, err))
+	}
+}
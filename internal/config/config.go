@@ -0,0 +1,417 @@
+// Package config reads the configuration file of mprisctl: mprisctl/config.toml in $XDG_CONFIG_HOME,
+// or config.yaml, config.yml or config.json, and the MPRISCTL_* environment variables overriding it.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/internal/hooks"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultVolumeStep is the step of the volume changes when none is configured.
+const DefaultVolumeStep = 0.05
+
+// Output formats.
+const (
+	FormatLines    = "lines"
+	FormatJSON     = "json"
+	FormatTemplate = "template"
+)
+
+// Formats are the output formats.
+var Formats = []string{FormatLines, FormatJSON, FormatTemplate}
+
+// Config is the configuration of mprisctl.
+type Config struct {
+	// Player is the player targeted by the commands given no -p.
+	Player string `toml:"player,omitempty" yaml:"player,omitempty" json:"player,omitempty"`
+	// Priority are the players preferred, in order, when no player is given: the first running one is targeted.
+	Priority []string `toml:"priority,omitempty" yaml:"priority,omitempty" json:"priority,omitempty"`
+	// Ignore are the players left out of the lists and the events.
+	Ignore []string `toml:"ignore,omitempty" yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	// Aliases are names standing for players, like music = "spotify".
+	Aliases map[string]string `toml:"aliases,omitempty" yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// Playerctld is how playerctld and the proxy of the daemon are treated: hide, show or proxy.
+	Playerctld string `toml:"playerctld,omitempty" yaml:"playerctld,omitempty" json:"playerctld,omitempty"`
+
+	// Format is the output format of list, watch and config show: lines, json or template.
+	Format string `toml:"format,omitempty" yaml:"format,omitempty" json:"format,omitempty"`
+	// Template is the template of the template format: the name of one of Templates, or a Go template.
+	Template  string            `toml:"template,omitempty" yaml:"template,omitempty" json:"template,omitempty"`
+	Templates map[string]string `toml:"templates,omitempty" yaml:"templates,omitempty" json:"templates,omitempty"`
+
+	Volume    Volume    `toml:"volume" yaml:"volume" json:"volume"`
+	Exclusive Exclusive `toml:"exclusive" yaml:"exclusive" json:"exclusive"`
+	Duck      Duck      `toml:"duck" yaml:"duck" json:"duck"`
+
+	HookConcurrency int      `toml:"hook_concurrency,omitempty" yaml:"hook_concurrency,omitempty" json:"hook_concurrency,omitempty"`
+	HookTimeout     Duration `toml:"hook_timeout,omitempty" yaml:"hook_timeout,omitempty" json:"hook_timeout,omitempty"`
+	Hooks           []Hook   `toml:"hooks,omitempty" yaml:"hooks,omitempty" json:"hooks,omitempty"`
+
+	// path is the file read by Load, and sources where the settings overriding it come from,
+	// like MPRISCTL_FORMAT or --format, by key, for the errors of Validate to name them.
+	path    string
+	sources map[string]string
+}
+
+// Volume configures the volume changes.
+type Volume struct {
+	// Step is the step of volume up and volume down.
+	Step float64 `toml:"step" yaml:"step" json:"step"`
+	// Players are the steps of some players, replacing Step.
+	Players map[string]float64 `toml:"players,omitempty" yaml:"players,omitempty" json:"players,omitempty"`
+}
+
+// Exclusive configures the exclusive playback of the daemon, see daemon --exclusive.
+type Exclusive struct {
+	Enabled bool     `toml:"enabled" yaml:"enabled" json:"enabled"`
+	Resume  bool     `toml:"resume" yaml:"resume" json:"resume"`
+	Allow   []string `toml:"allow,omitempty" yaml:"allow,omitempty" json:"allow,omitempty"`
+}
+
+// Duck configures the ducking of the daemon, see daemon --duck.
+type Duck struct {
+	Players []string `toml:"players,omitempty" yaml:"players,omitempty" json:"players,omitempty"`
+	Amount  float64  `toml:"amount" yaml:"amount" json:"amount"`
+	Ramp    Duration `toml:"ramp" yaml:"ramp" json:"ramp"`
+}
+
+// Hook is a command run by watch and the daemon on some events, see hooks.Hook.
+type Hook struct {
+	Events   []string `toml:"events" yaml:"events" json:"events"`
+	Players  []string `toml:"players,omitempty" yaml:"players,omitempty" json:"players,omitempty"`
+	Command  string   `toml:"command" yaml:"command" json:"command"`
+	Debounce Duration `toml:"debounce,omitzero" yaml:"debounce,omitempty" json:"debounce,omitempty"`
+	Timeout  Duration `toml:"timeout,omitzero" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Duration is a time.Duration written like "1m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Default returns the configuration used without file.
+func Default() *Config {
+	return &Config{
+		Playerctld: mpris.PlayerctldHide.String(),
+		Format:     FormatLines,
+		Volume:     Volume{Step: DefaultVolumeStep},
+		Duck:       Duck{Amount: daemon.DefaultDuckAmount, Ramp: Duration(daemon.DefaultDuckRamp)},
+
+		HookConcurrency: hooks.DefaultConcurrency,
+		HookTimeout:     Duration(hooks.DefaultTimeout),
+	}
+}
+
+// Path returns the path of the configuration file: $MPRISCTL_CONFIG, or the first of
+// mprisctl/config.toml, config.yaml, config.yml and config.json found in $XDG_CONFIG_HOME,
+// which defaults to ~/.config. It returns an empty path when there is none.
+func Path() (string, error) {
+	if path := os.Getenv("MPRISCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	for _, name := range []string{"config.toml", "config.yaml", "config.yml", "config.json"} {
+		path := filepath.Join(configHome, "mprisctl", name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// Load reads the configuration file at path, over the defaults. An empty path gives the defaults.
+// The format is told by the extension of the file: .toml, .yaml, .yml or .json.
+func Load(path string) (*Config, error) {
+	config := Default()
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(path, data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config.path = path
+	return config, nil
+}
+
+// Override records that the setting key, like "format", was given by source, like "--format",
+// rather than by the file.
+func (c *Config) Override(key string, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+}
+
+// invalid returns the error of an invalid setting, naming where it comes from.
+func (c *Config) invalid(key string, message string) error {
+	if source, found := c.sources[key]; found {
+		return fmt.Errorf("%s: %s", source, message)
+	}
+	if c.path != "" {
+		return fmt.Errorf("%s: %s: %s", c.path, key, message)
+	}
+	return fmt.Errorf("%s: %s", key, message)
+}
+
+// decode decodes data into config, refusing the unknown keys, which are likely typos.
+func decode(path string, data []byte, config *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		metadata, err := toml.Decode(string(data), config)
+		if err != nil {
+			return err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown key %s", undecoded[0])
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && errors.Is(err, io.EOF) == false {
+			return err
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unknown format, expecting .toml, .yaml, .yml or .json")
+	}
+	return nil
+}
+
+// ApplyEnvironment overrides the configuration with the MPRISCTL_* environment variables:
+// MPRISCTL_PLAYER, MPRISCTL_PRIORITY and MPRISCTL_IGNORE (comma separated), MPRISCTL_PLAYERCTLD,
+// MPRISCTL_FORMAT, MPRISCTL_TEMPLATE and MPRISCTL_VOLUME_STEP.
+func (c *Config) ApplyEnvironment(lookup func(key string) (string, bool)) error {
+	if value, found := lookup("MPRISCTL_PLAYER"); found {
+		c.Player = value
+		c.Override("player", "MPRISCTL_PLAYER")
+	}
+	if value, found := lookup("MPRISCTL_PRIORITY"); found {
+		c.Priority = splitList(value)
+		c.Override("priority", "MPRISCTL_PRIORITY")
+	}
+	if value, found := lookup("MPRISCTL_IGNORE"); found {
+		c.Ignore = splitList(value)
+		c.Override("ignore", "MPRISCTL_IGNORE")
+	}
+	if value, found := lookup("MPRISCTL_PLAYERCTLD"); found {
+		c.Playerctld = value
+		c.Override("playerctld", "MPRISCTL_PLAYERCTLD")
+	}
+	if value, found := lookup("MPRISCTL_FORMAT"); found {
+		c.Format = value
+		c.Override("format", "MPRISCTL_FORMAT")
+	}
+	if value, found := lookup("MPRISCTL_TEMPLATE"); found {
+		c.Template = value
+		c.Override("template", "MPRISCTL_TEMPLATE")
+	}
+	if value, found := lookup("MPRISCTL_VOLUME_STEP"); found {
+		step, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("MPRISCTL_VOLUME_STEP: %w", err)
+		}
+		c.Volume.Step = step
+		c.Override("volume step", "MPRISCTL_VOLUME_STEP")
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Validate checks the values of the configuration. The errors name the file, the environment
+// variable or the flag giving the invalid value.
+func (c *Config) Validate() error {
+	if _, err := mpris.ParsePlayerctldMode(c.Playerctld); err != nil {
+		return c.invalid("playerctld", `must be one of "hide", "show", or "proxy"`)
+	}
+	validFormat := false
+	for _, format := range Formats {
+		validFormat = validFormat || c.Format == format
+	}
+	if validFormat == false {
+		return c.invalid("format", "must be one of "+strings.Join(Formats, ", "))
+	}
+	if c.Format == FormatTemplate && c.Template == "" {
+		return c.invalid("format", "the template format needs a template")
+	}
+	if c.Volume.Step <= 0 || c.Volume.Step > 1 {
+		return c.invalid("volume step", "must be between 0 and 1")
+	}
+	for name, step := range c.Volume.Players {
+		if step <= 0 || step > 1 {
+			return c.invalid("volume step of "+name, "must be between 0 and 1")
+		}
+	}
+	if c.Duck.Amount <= 0 || c.Duck.Amount > 1 {
+		return c.invalid("duck amount", "must be between 0 and 1")
+	}
+	if c.Duck.Ramp < 0 {
+		return c.invalid("duck ramp", "must not be negative")
+	}
+	if c.HookConcurrency <= 0 || c.HookTimeout <= 0 {
+		return c.invalid("hook_concurrency and hook_timeout", "must be positive")
+	}
+	for i, hook := range c.Hooks {
+		if err := hook.hook().Validate(); err != nil {
+			return c.invalid(fmt.Sprintf("hook %d", i+1), err.Error())
+		}
+	}
+	return nil
+}
+
+// ResolveAlias returns the player name an alias stands for, or name when it is not an alias.
+func (c *Config) ResolveAlias(name string) string {
+	if playerName, found := c.Aliases[name]; found {
+		return playerName
+	}
+	return name
+}
+
+// PriorityList returns the priority list, its aliases resolved.
+func (c *Config) PriorityList() []string {
+	return c.resolveAliases(c.Priority)
+}
+
+// ExclusiveAllowList returns the players allowed to overlap by the exclusive playback, their aliases resolved.
+func (c *Config) ExclusiveAllowList() []string {
+	return c.resolveAliases(c.Exclusive.Allow)
+}
+
+// DuckList returns the players ducking the others, their aliases resolved.
+func (c *Config) DuckList() []string {
+	return c.resolveAliases(c.Duck.Players)
+}
+
+// IgnoreList returns the players ignored, their aliases resolved.
+func (c *Config) IgnoreList() []string {
+	return c.resolveAliases(c.Ignore)
+}
+
+func (c *Config) resolveAliases(names []string) []string {
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		resolved = append(resolved, c.ResolveAlias(name))
+	}
+	return resolved
+}
+
+// VolumeStep returns the step of the volume changes of playerName: the step of the player named
+// exactly so, by its name or an alias, else of the first name matching it as by mpris.MatchPlayerName.
+func (c *Config) VolumeStep(playerName string) float64 {
+	if step, found := c.Volume.Players[playerName]; found {
+		return step
+	}
+	names := make([]string, 0, len(c.Volume.Players))
+	for name := range c.Volume.Players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.ResolveAlias(name) == playerName {
+			return c.Volume.Players[name]
+		}
+	}
+	for _, name := range names {
+		if mpris.MatchPlayerName(c.ResolveAlias(name), playerName) {
+			return c.Volume.Players[name]
+		}
+	}
+	return c.Volume.Step
+}
+
+// HookList returns the hooks of the configuration, their players resolved.
+func (c *Config) HookList() []hooks.Hook {
+	list := make([]hooks.Hook, 0, len(c.Hooks))
+	for _, hook := range c.Hooks {
+		resolved := hook.hook()
+		resolved.Players = c.resolveAliases(hook.Players)
+		list = append(list, resolved)
+	}
+	return list
+}
+
+// HookOptions returns the options of the runner of the hooks.
+func (c *Config) HookOptions() hooks.Options {
+	return hooks.Options{Concurrency: c.HookConcurrency, Timeout: time.Duration(c.HookTimeout)}
+}
+
+func (h Hook) hook() hooks.Hook {
+	return hooks.Hook{
+		Events:   h.Events,
+		Players:  h.Players,
+		Command:  h.Command,
+		Debounce: time.Duration(h.Debounce),
+		Timeout:  time.Duration(h.Timeout),
+	}
+}
+
+// Encode writes the configuration to w as format: toml, yaml or json.
+func (c *Config) Encode(w io.Writer, format string) error {
+	switch format {
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(c); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(c)
+	default:
+		return fmt.Errorf("unknown format %q, expecting toml, yaml or json", format)
+	}
+}
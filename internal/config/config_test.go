@@ -0,0 +1,283 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes content to a file named name in a temporary directory, returning its path.
+func writeConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.toml", `
+player = "music"
+priority = ["spotify", "vlc"]
+
+[aliases]
+music = "spotify"
+
+[volume]
+step = 0.1
+players = { music = 0.02 }
+
+[duck]
+ramp = "1s"
+
+[[hooks]]
+events = ["track"]
+command = "notify-send changed"
+debounce = "500ms"
+`},
+		{"config.yaml", `
+player: music
+priority: [spotify, vlc]
+aliases:
+  music: spotify
+volume:
+  step: 0.1
+  players:
+    music: 0.02
+duck:
+  ramp: 1s
+hooks:
+  - events: [track]
+    command: notify-send changed
+    debounce: 500ms
+`},
+		{"config.yml", `
+player: music
+priority: [spotify, vlc]
+aliases: {music: spotify}
+volume: {step: 0.1, players: {music: 0.02}}
+duck: {ramp: 1s}
+hooks: [{events: [track], command: notify-send changed, debounce: 500ms}]
+`},
+		{"config.json", `{
+	"player": "music",
+	"priority": ["spotify", "vlc"],
+	"aliases": {"music": "spotify"},
+	"volume": {"step": 0.1, "players": {"music": 0.02}},
+	"duck": {"ramp": "1s"},
+	"hooks": [{"events": ["track"], "command": "notify-send changed", "debounce": "500ms"}]
+}`},
+	}
+	for _, test := range tests {
+		config, err := Load(writeConfig(t, test.name, test.content))
+		if err != nil {
+			t.Errorf("Load(%q) failed: %v", test.name, err)
+			continue
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Load(%q).Validate() failed: %v", test.name, err)
+		}
+		if config.Player != "music" || config.ResolveAlias(config.Player) != "spotify" {
+			t.Errorf("Load(%q).Player = %q, want music", test.name, config.Player)
+		}
+		if reflect.DeepEqual(config.Priority, []string{"spotify", "vlc"}) == false {
+			t.Errorf("Load(%q).Priority = %v, want [spotify vlc]", test.name, config.Priority)
+		}
+		if config.Volume.Step != 0.1 || config.VolumeStep("spotify") != 0.02 {
+			t.Errorf("Load(%q) volume steps = %v, %v, want 0.1, 0.02", test.name, config.Volume.Step, config.VolumeStep("spotify"))
+		}
+		if time.Duration(config.Duck.Ramp) != time.Second {
+			t.Errorf("Load(%q).Duck.Ramp = %v, want 1s", test.name, time.Duration(config.Duck.Ramp))
+		}
+		// The settings not in the file keep their default.
+		if config.Format != FormatLines || config.Duck.Amount != Default().Duck.Amount {
+			t.Errorf("Load(%q) format and duck amount = %q, %v, want the defaults", test.name, config.Format, config.Duck.Amount)
+		}
+		if len(config.Hooks) != 1 || config.Hooks[0].Command != "notify-send changed" || time.Duration(config.Hooks[0].Debounce) != 500*time.Millisecond {
+			t.Errorf("Load(%q).Hooks = %+v, want the track hook", test.name, config.Hooks)
+		}
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	for _, name := range []string{"config.toml", "config.yaml"} {
+		config, err := Load(writeConfig(t, name, ""))
+		if err != nil {
+			t.Errorf("Load(%q) failed: %v", name, err)
+			continue
+		}
+		if reflect.DeepEqual(config.Volume, Default().Volume) == false || config.Format != Default().Format {
+			t.Errorf("Load(%q) = %+v, want the defaults", name, config)
+		}
+	}
+}
+
+func TestLoadRefusesUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+	}{
+		{"config.toml", "plaeyr = \"vlc\"\n", "plaeyr"},
+		{"config.toml", "[volume]\nstpe = 0.1\n", "stpe"},
+		{"config.yaml", "plaeyr: vlc\n", "plaeyr"},
+		{"config.yml", "volume:\n  stpe: 0.1\n", "stpe"},
+		{"config.json", `{"plaeyr": "vlc"}`, "plaeyr"},
+		{"config.json", `{"volume": {"stpe": 0.1}}`, "stpe"},
+	}
+	for _, test := range tests {
+		path := writeConfig(t, test.name, test.content)
+		_, err := Load(path)
+		if err == nil || strings.Contains(err.Error(), path) == false || strings.Contains(err.Error(), test.key) == false {
+			t.Errorf("Load(%q) with %q = %v, want an error naming the file and %s", test.name, test.content, err, test.key)
+		}
+	}
+
+	if _, err := Load(writeConfig(t, "config.ini", "player = vlc\n")); err == nil || strings.Contains(err.Error(), "unknown format") == false {
+		t.Errorf("Load(config.ini) = %v, want an unknown format error", err)
+	}
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	tests := []struct {
+		environment map[string]string
+		check       func(config *Config) bool
+	}{
+		{map[string]string{"MPRISCTL_PLAYER": "vlc"}, func(config *Config) bool { return config.Player == "vlc" }},
+		{map[string]string{"MPRISCTL_PRIORITY": "spotify, vlc,,"}, func(config *Config) bool {
+			return reflect.DeepEqual(config.Priority, []string{"spotify", "vlc"})
+		}},
+		{map[string]string{"MPRISCTL_IGNORE": "kdeconnect"}, func(config *Config) bool {
+			return reflect.DeepEqual(config.Ignore, []string{"kdeconnect"})
+		}},
+		{map[string]string{"MPRISCTL_IGNORE": ""}, func(config *Config) bool { return len(config.Ignore) == 0 }},
+		{map[string]string{"MPRISCTL_PLAYERCTLD": "show"}, func(config *Config) bool { return config.Playerctld == "show" }},
+		{map[string]string{"MPRISCTL_FORMAT": "template", "MPRISCTL_TEMPLATE": "{{.Title}}"}, func(config *Config) bool {
+			return config.Format == FormatTemplate && config.Template == "{{.Title}}"
+		}},
+		{map[string]string{"MPRISCTL_VOLUME_STEP": "0.2"}, func(config *Config) bool { return config.Volume.Step == 0.2 }},
+		// Unset variables leave the file alone.
+		{map[string]string{}, func(config *Config) bool {
+			return config.Player == "mpv" && reflect.DeepEqual(config.Ignore, []string{"chromium"}) && config.Volume.Step == 0.1
+		}},
+	}
+	path := writeConfig(t, "config.toml", "player = \"mpv\"\nignore = [\"chromium\"]\n[volume]\nstep = 0.1\n")
+	for _, test := range tests {
+		config, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookup := func(key string) (string, bool) {
+			value, found := test.environment[key]
+			return value, found
+		}
+		if err := config.ApplyEnvironment(lookup); err != nil {
+			t.Errorf("ApplyEnvironment(%v) failed: %v", test.environment, err)
+			continue
+		}
+		if test.check(config) == false {
+			t.Errorf("ApplyEnvironment(%v) = %+v, not overridden as expected", test.environment, config)
+		}
+	}
+
+	lookup := func(key string) (string, bool) { return "loud", key == "MPRISCTL_VOLUME_STEP" }
+	if err := Default().ApplyEnvironment(lookup); err == nil || strings.HasPrefix(err.Error(), "MPRISCTL_VOLUME_STEP: ") == false {
+		t.Errorf("ApplyEnvironment(MPRISCTL_VOLUME_STEP=loud) = %v, want an error naming the variable", err)
+	}
+}
+
+func TestValidateNamesTheSource(t *testing.T) {
+	path := writeConfig(t, "config.toml", "format = \"xml\"\n[volume]\nstep = 2.0\nplayers = { vlc = 0.1, mpv = 0.0 }\n")
+	tests := []struct {
+		// path is the file loaded, if any.
+		path        string
+		environment map[string]string
+		// flag overrides the format, like --format.
+		flag string
+		err  string
+	}{
+		{"", map[string]string{"MPRISCTL_PLAYERCTLD": "always"}, "", `MPRISCTL_PLAYERCTLD: must be one of "hide", "show", or "proxy"`},
+		{"", nil, "", ""},
+		{path, nil, "", path + ": format: must be one of lines, json, template"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "yaml"}, "", "MPRISCTL_FORMAT: must be one of lines, json, template"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "yaml"}, "html", "--format: must be one of lines, json, template"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "template"}, "", "MPRISCTL_FORMAT: the template format needs a template"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "json"}, "", path + ": volume step: must be between 0 and 1"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "json", "MPRISCTL_VOLUME_STEP": "-1"}, "", "MPRISCTL_VOLUME_STEP: must be between 0 and 1"},
+		{path, map[string]string{"MPRISCTL_FORMAT": "json", "MPRISCTL_VOLUME_STEP": "0.5"}, "", path + ": volume step of mpv: must be between 0 and 1"},
+	}
+	for _, test := range tests {
+		config, err := Load(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookup := func(key string) (string, bool) {
+			value, found := test.environment[key]
+			return value, found
+		}
+		if err := config.ApplyEnvironment(lookup); err != nil {
+			t.Fatal(err)
+		}
+		if test.flag != "" {
+			config.Format = test.flag
+			config.Override("format", "--format")
+		}
+		err = config.Validate()
+		if test.err == "" && err != nil {
+			t.Errorf("Validate() with %s %v = %v, want no error", test.path, test.environment, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("Validate() with %s %v = %v, want %q", test.path, test.environment, err, test.err)
+		}
+	}
+}
+
+func TestVolumeStep(t *testing.T) {
+	config := Default()
+	config.Aliases = map[string]string{"music": "spotify", "browser": "firefox"}
+	config.Volume.Players = map[string]float64{
+		"firefox":            0.1,
+		"firefox.instance_1": 0.2,
+		"music":              0.3,
+		"spotify":            0.4,
+		"browser":            0.5,
+		"chromium":           0.6,
+	}
+	tests := []struct {
+		playerName string
+		step       float64
+	}{
+		{"firefox.instance_1", 0.2},
+		{"firefox", 0.1},
+		{"firefox.instance_2", 0.5},
+		{"spotify", 0.4},
+		{"chromium.instance_3", 0.6},
+		{"vlc", DefaultVolumeStep},
+	}
+	for _, test := range tests {
+		if step := config.VolumeStep(test.playerName); step != test.step {
+			t.Errorf("VolumeStep(%q) = %v, want %v", test.playerName, step, test.step)
+		}
+	}
+
+	// An alias of a player matches it exactly, before the patterns matching it.
+	delete(config.Volume.Players, "spotify")
+	config.Volume.Players["spot"] = 0.7
+	config.Aliases["spot"] = "spotify.instance_9"
+	for _, test := range []struct {
+		playerName string
+		step       float64
+	}{{"spotify", 0.3}, {"spotify.instance_9", 0.7}} {
+		if step := config.VolumeStep(test.playerName); step != test.step {
+			t.Errorf("VolumeStep(%q) = %v, want %v", test.playerName, step, test.step)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/internal/hooks"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// daemonTimeout bounds the questions asked to the daemon, which answers from memory.
+const daemonTimeout = 500 * time.Millisecond
+
+// DaemonOptions configures RunDaemon, every field being optional.
+type DaemonOptions struct {
+	// ProxyConn is the connection on which the most recently active player is exported as the player mpris.ProxyName.
+	ProxyConn *dbus.Conn
+	// Runner runs its hooks on the events.
+	Runner *hooks.Runner
+	// Exclusive makes the players play one at a time.
+	Exclusive *daemon.ExclusiveOptions
+	// Ducking lowers the volume of the players while some others are playing.
+	Ducking *daemon.DuckOptions
+	// Buses are the buses of the clients, reported to the command line.
+	Buses []daemon.BusInfo
+}
+
+// RunDaemon tracks the players of every client until ctx is done,
+// answering on the socket of the daemon.
+func RunDaemon(ctx context.Context, options DaemonOptions, clients ...*mpris.Client) error {
+	statePath, err := daemon.StatePath()
+	if err != nil {
+		return err
+	}
+	tracker, err := daemon.New(statePath, clients)
+	if err != nil {
+		return err
+	}
+	if options.Exclusive != nil {
+		tracker.SetExclusive(*options.Exclusive)
+	}
+	if options.Ducking != nil {
+		tracker.SetDucking(*options.Ducking)
+	}
+	tracker.SetBuses(options.Buses)
+	server, err := daemon.Listen(daemon.SocketPath(), tracker.Methods())
+	if err != nil {
+		return err
+	}
+	if options.ProxyConn != nil {
+		if err := tracker.ExportProxy(options.ProxyConn); err != nil {
+			server.Close()
+			return fmt.Errorf("exporting the proxy: %w", err)
+		}
+	}
+
+	daemonCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(daemonCtx)
+		cancel()
+	}()
+	fmt.Println(fmt.Sprintf("DAEMON::listening socket=%s state=%s", server.Path(), statePath))
+
+	events := subscribe(daemonCtx, cancel, clients)
+	if options.Runner != nil {
+		defer options.Runner.Close()
+		events = handleEvents(events, options.Runner)
+	}
+	runErr := tracker.Run(events)
+	cancel()
+	if err := <-serveErr; err != nil {
+		return err
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("lost connection to the bus")
+}
+
+// handleEvents runs the hooks of runner on events, passing them on.
+func handleEvents(events <-chan mpris.Event, runner *hooks.Runner) <-chan mpris.Event {
+	handled := make(chan mpris.Event)
+	go func() {
+		defer close(handled)
+		for event := range events {
+			runner.Handle(event)
+			handled <- event
+		}
+	}()
+	return handled
+}
+
+// ActivePlayer returns the player to target on the bus at address when none is given: the first player
+// matching one of priority, else the most recently active player. The players are asked to the daemon,
+// and when it is not running, does not watch this bus or has not read it yet, to the bus with the client
+// returned by connect: the first playing player is then the most recently active, else the first player.
+func ActivePlayer(ctx context.Context, address string, connect func() (*mpris.Client, error), priority []string) (string, error) {
+	infos, err := DaemonPlayers(ctx, address)
+	if err != nil && errors.Is(err, daemon.ErrNotRunning) == false && errors.Is(err, daemon.ErrBusNotWatched) == false {
+		return "", fmt.Errorf("daemon: %w", err)
+	}
+	for _, pattern := range priority {
+		for _, info := range infos {
+			if mpris.MatchPlayerName(pattern, info.Name) {
+				return info.Name, nil
+			}
+		}
+	}
+	if len(infos) > 0 {
+		return infos[0].Name, nil
+	}
+
+	client, err := connect()
+	if err != nil {
+		return "", err
+	}
+	players, err := client.Players(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(players) == 0 {
+		return "", fmt.Errorf("%w: no player is running", mpris.ErrPlayerNotFound)
+	}
+	sort.SliceStable(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+	for _, pattern := range priority {
+		for _, player := range players {
+			if mpris.MatchPlayerName(pattern, player.Name) {
+				return player.Name, nil
+			}
+		}
+	}
+	for _, player := range players {
+		if player.PlaybackStatus == mpris.PlaybackPlaying {
+			return player.Name, nil
+		}
+	}
+	return players[0].Name, nil
+}
+
+// DaemonPlayers returns the players tracked by the daemon on the bus at address, the most recently active first.
+// The error wraps daemon.ErrNotRunning when the daemon is not running, and daemon.ErrBusNotWatched
+// when it does not watch this bus.
+func DaemonPlayers(ctx context.Context, address string) ([]daemon.PlayerInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, daemonTimeout)
+	defer cancel()
+	client, err := daemon.Dial(ctx, daemon.SocketPath())
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var buses []daemon.BusInfo
+	if err := client.Call(ctx, daemon.MethodBuses, nil, &buses); err != nil {
+		return nil, err
+	}
+	label, watched := "", false
+	for _, bus := range buses {
+		if bus.Address == address {
+			label, watched = bus.Label, true
+			break
+		}
+	}
+	if watched == false {
+		return nil, fmt.Errorf("%w: %s", daemon.ErrBusNotWatched, address)
+	}
+
+	var infos []daemon.PlayerInfo
+	if err := client.Call(ctx, daemon.MethodPlayers, nil, &infos); err != nil {
+		return nil, err
+	}
+	players := make([]daemon.PlayerInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Bus == label {
+			players = append(players, info)
+		}
+	}
+	return players, nil
+}
+
+// DaemonPlayer returns the state of playerName on the bus at address as tracked by the daemon: the most
+// recently active player matching it as by mpris.MatchPlayerName. The error wraps daemon.ErrNotRunning
+// when the daemon is not running, daemon.ErrBusNotWatched when it does not watch this bus, and
+// mpris.ErrPlayerNotFound when it does not track the player.
+func DaemonPlayer(ctx context.Context, playerName string, address string) (*daemon.PlayerInfo, error) {
+	infos, err := DaemonPlayers(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	for i, info := range infos {
+		if mpris.MatchPlayerName(playerName, info.Name) {
+			return &infos[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", mpris.ErrPlayerNotFound, playerName)
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// SubscriptionBuffer bounds the events waiting to be read by a subscription.
+const SubscriptionBuffer = 256
+
+// Broadcaster hands events to subscriptions, each with its own filter and buffer.
+// A subscription too slow to keep up overflows rather than stalling Broadcast.
+// A Broadcaster is safe for concurrent use.
+type Broadcaster struct {
+	lock          sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// Subscription receives the events selected by its filter.
+type Subscription struct {
+	broadcaster *Broadcaster
+	filter      Filter
+	events      chan mpris.Event
+	overflowed  bool
+}
+
+// NewBroadcaster returns a Broadcaster without subscriptions.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscriptions: make(map[*Subscription]bool)}
+}
+
+// Subscribe returns a subscription to the events selected by filter.
+func (b *Broadcaster) Subscribe(filter Filter) *Subscription {
+	subscription := &Subscription{
+		broadcaster: b,
+		filter:      filter,
+		events:      make(chan mpris.Event, SubscriptionBuffer),
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscriptions[subscription] = true
+	return subscription
+}
+
+// Broadcast hands event to the subscriptions selecting it, without waiting for them.
+func (b *Broadcaster) Broadcast(event mpris.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for subscription := range b.subscriptions {
+		if subscription.filter.Match(event) == false {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.overflowed = true
+			b.remove(subscription)
+		}
+	}
+}
+
+func (b *Broadcaster) remove(subscription *Subscription) {
+	if b.subscriptions[subscription] {
+		delete(b.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// Events returns the channel of the events. It is closed by Close, or when the subscription overflows.
+func (s *Subscription) Events() <-chan mpris.Event {
+	return s.events
+}
+
+// Overflowed reports whether the channel of the events was closed because they were not read fast enough.
+func (s *Subscription) Overflowed() bool {
+	s.broadcaster.lock.Lock()
+	defer s.broadcaster.lock.Unlock()
+	return s.overflowed
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.broadcaster.lock.Lock()
+	defer s.broadcaster.lock.Unlock()
+	s.broadcaster.remove(s)
+}
+
+// Filter selects the events of the players designated by Players, as by Stack.Find, and of the types Types.
+// Empty lists select everything.
+type Filter struct {
+	Players []string
+	Types   []mpris.EventType
+}
+
+// ParseFilter returns the filter selecting the events of players and of types, named as by mpris.EventType.String.
+func ParseFilter(players []string, types []string) (Filter, error) {
+	filter := Filter{Players: players}
+	for _, name := range types {
+		eventType, err := mpris.ParseEventType(name)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Types = append(filter.Types, eventType)
+	}
+	return filter, nil
+}
+
+// Match reports whether event is selected by the filter.
+func (f Filter) Match(event mpris.Event) bool {
+	return f.MatchPlayer(&event.Player) && (mpris.Filter{Types: f.Types}).Match(event)
+}
+
+// MatchPlayer reports whether player is one of the players selected by the filter.
+func (f Filter) MatchPlayer(player *mpris.PlayerState) bool {
+	if len(f.Players) == 0 {
+		return true
+	}
+	for _, name := range f.Players {
+		if designates(name, player) {
+			return true
+		}
+	}
+	return false
+}
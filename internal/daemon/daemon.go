@@ -0,0 +1,100 @@
+// Package daemon keeps track of the players in the background and answers
+// the questions of the command line and other programs over a unix socket.
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Daemon tracks the players from their events.
+type Daemon struct {
+	stack     *Stack
+	statePath string
+	// clients talk to the buses of the players, by bus label.
+	clients map[string]*mpris.Client
+	// proxy, when not nil, mirrors the most recently active player.
+	proxy *proxy
+	// exclusive, when not nil, pauses the other players when one starts playing.
+	exclusive *exclusive
+	// ducking, when not nil, lowers the volume of the other players while a priority player is playing.
+	ducking     *ducking
+	broadcaster *Broadcaster
+	// watched are the buses watched, as reported by MethodBuses.
+	watched []BusInfo
+}
+
+// New returns a Daemon persisting its player stack to statePath.
+// The calls to the players are made with clients, the client of a player being the one with its bus label.
+func New(statePath string, clients []*mpris.Client) (*Daemon, error) {
+	stack, err := LoadStack(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", statePath, err)
+	}
+	d := &Daemon{
+		stack:       stack,
+		statePath:   statePath,
+		clients:     make(map[string]*mpris.Client, len(clients)),
+		broadcaster: NewBroadcaster(),
+	}
+	for _, client := range clients {
+		d.clients[client.BusLabel()] = client
+	}
+	return d, nil
+}
+
+// SetBuses sets the buses reported as watched, for the command line to only ask about the players of these buses.
+func (d *Daemon) SetBuses(buses []BusInfo) {
+	d.watched = buses
+}
+
+// Run updates the player stack with events until the channel is closed,
+// or until the stack cannot be saved.
+func (d *Daemon) Run(events <-chan mpris.Event) error {
+	if d.proxy != nil {
+		defer d.proxy.close()
+	}
+	for event := range events {
+		d.stack.Update(event)
+		if d.proxy != nil {
+			d.proxy.update(d.stack, event)
+		}
+		if d.exclusive != nil {
+			d.exclusive.update(d.stack, event)
+		}
+		if d.ducking != nil {
+			d.ducking.update(d.stack, event)
+		}
+		d.broadcaster.Broadcast(event)
+		if err := d.stack.Save(d.statePath); err != nil {
+			return fmt.Errorf("saving player stack: %w", err)
+		}
+	}
+	return nil
+}
+
+// client returns the client of the bus of player.
+func (d *Daemon) client(player *mpris.PlayerState) (*mpris.Client, error) {
+	client, found := d.clients[player.Bus]
+	if found == false {
+		return nil, fmt.Errorf("no client for bus %q", player.Bus)
+	}
+	return client, nil
+}
+
+// background calls action on player without waiting, not to hold the events meanwhile.
+func (d *Daemon) background(player mpris.PlayerState, action func(*mpris.Client, context.Context, string) error) {
+	client, err := d.client(&player)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), proxyTimeout)
+		defer cancel()
+		if err := action(client, ctx, player.Name); err != nil {
+			fmt.Println(fmt.Sprintf("DAEMON::failed player=%s error=%s", stackKey(&player), err))
+		}
+	}()
+}
@@ -0,0 +1,210 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Defaults of DuckOptions.
+const (
+	DefaultDuckAmount = 0.5
+	DefaultDuckRamp   = 500 * time.Millisecond
+)
+
+// duckTolerance is how far from the ducked level the volume of a player can be, players rounding volumes,
+// before it counts as changed by the user.
+const duckTolerance = 0.01
+
+// DuckOptions configures the ducking of the daemon.
+type DuckOptions struct {
+	// Players are the players lowering the volume of the others while playing, matched as by mpris.MatchPlayerName.
+	Players []string
+	// Amount is the part of their volume the other players lose, between 0 and 1.
+	Amount float64
+	// Ramp is the duration of the volume changes.
+	Ramp time.Duration
+}
+
+// ducking lowers the volume of the players while a priority player is playing.
+type ducking struct {
+	daemon  *Daemon
+	options DuckOptions
+
+	lock sync.Mutex
+	// players are the players ducked, or being restored, by stack key.
+	players map[string]*duckedPlayer
+}
+
+type duckedPlayer struct {
+	player mpris.PlayerState
+	// volume is the volume to restore, and level the volume ducked to.
+	volume float64
+	level  float64
+	// lowered is set once the volume reached level.
+	lowered bool
+	// ducked is false once the player is being restored.
+	ducked bool
+	// paused is set when the player was paused, its volume not being changeable.
+	paused bool
+	// cancel stops the volume ramp in progress.
+	cancel context.CancelFunc
+}
+
+// SetDucking lowers the volume of the other players while one of options.Players is playing,
+// pausing those whose volume cannot be changed, and restores them once none is playing.
+func (d *Daemon) SetDucking(options DuckOptions) {
+	d.ducking = &ducking{daemon: d, options: options, players: make(map[string]*duckedPlayer)}
+}
+
+func (k *ducking) priority(player *mpris.PlayerState) bool {
+	return (mpris.Filter{Players: k.options.Players}).Match(mpris.Event{Player: *player})
+}
+
+// update ducks or restores players after event was applied to stack.
+func (k *ducking) update(stack *Stack, event mpris.Event) {
+	if event.Type == mpris.EventPlayerVanished {
+		k.forget(stackKey(&event.Player))
+	}
+
+	players := stack.Players()
+	interrupter := ""
+	for _, player := range players {
+		if player.PlaybackStatus == mpris.PlaybackPlaying && k.priority(&player) {
+			interrupter = stackKey(&player)
+			break
+		}
+	}
+	if interrupter == "" {
+		k.restore(stack)
+		return
+	}
+	for _, player := range players {
+		if player.PlaybackStatus == mpris.PlaybackPlaying && k.priority(&player) == false {
+			k.duck(player, interrupter)
+		}
+	}
+}
+
+// forget stops tracking the player with key, gone from the bus.
+func (k *ducking) forget(key string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if ducked, found := k.players[key]; found {
+		ducked.cancel()
+		delete(k.players, key)
+	}
+}
+
+// duck lowers the volume of player, or pauses it, unless it is already.
+func (k *ducking) duck(player mpris.PlayerState, interrupter string) {
+	client, err := k.daemon.client(&player)
+	if err != nil {
+		return
+	}
+	key := stackKey(&player)
+
+	k.lock.Lock()
+	ducked, found := k.players[key]
+	if found && ducked.ducked {
+		k.lock.Unlock()
+		return
+	}
+	from := player.Volume
+	if found {
+		// Being restored: its volume is somewhere between the two.
+		ducked.cancel()
+	} else {
+		ducked = &duckedPlayer{player: player, volume: player.Volume}
+		k.players[key] = ducked
+	}
+	ducked.ducked = true
+	ducked.lowered = false
+	ducked.level = ducked.volume * (1 - k.options.Amount)
+	ctx, cancel := context.WithCancel(context.Background())
+	ducked.cancel = cancel
+	k.lock.Unlock()
+
+	fmt.Println(fmt.Sprintf("DAEMON::ducking player=%s for=%s", key, interrupter))
+	go func() {
+		err := client.FadeVolume(ctx, player.Name, from, ducked.level, k.options.Ramp)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			k.lock.Lock()
+			defer k.lock.Unlock()
+			if ctx.Err() == nil {
+				ducked.lowered = true
+				fmt.Println(fmt.Sprintf("DAEMON::ducked player=%s volume=%v", key, ducked.level))
+			}
+			return
+		}
+		// The volume cannot be changed: pause the player instead.
+		k.lock.Lock()
+		ducked.paused = true
+		k.lock.Unlock()
+		fmt.Println(fmt.Sprintf("DAEMON::pausing player=%s for=%s", key, interrupter))
+		k.daemon.background(player, (*mpris.Client).Pause)
+	}()
+}
+
+// restore restores the volume of the players ducked, and resumes those paused if they still are.
+// The volume of the players whose volume was changed while ducked is left as is.
+func (k *ducking) restore(stack *Stack) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for key, ducked := range k.players {
+		if ducked.ducked == false {
+			continue
+		}
+		ducked.cancel()
+		ducked.ducked = false
+		current, found := stack.Find(key)
+		if found == false {
+			delete(k.players, key)
+			continue
+		}
+		fmt.Println(fmt.Sprintf("DAEMON::restoring player=%s", key))
+		if ducked.paused {
+			delete(k.players, key)
+			if current.PlaybackStatus == mpris.PlaybackPaused {
+				k.daemon.background(current, (*mpris.Client).Play)
+			}
+			continue
+		}
+
+		client, err := k.daemon.client(&current)
+		if err != nil {
+			delete(k.players, key)
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		ducked.cancel = cancel
+		go func(key string, ducked *duckedPlayer, playerName string, lowered bool) {
+			defer func() {
+				k.lock.Lock()
+				if k.players[key] == ducked && ducked.ducked == false {
+					delete(k.players, key)
+				}
+				k.lock.Unlock()
+			}()
+			// Asked to the player, the events of the last changes of the ramp possibly not read yet.
+			from, err := client.Volume(ctx, playerName)
+			if err == nil && lowered && math.Abs(from-ducked.level) > duckTolerance {
+				fmt.Println(fmt.Sprintf("DAEMON::keeping player=%s volume=%v", key, from))
+				return
+			}
+			if err == nil {
+				err = client.FadeVolume(ctx, playerName, from, ducked.volume, k.options.Ramp)
+			}
+			if err != nil && ctx.Err() == nil {
+				fmt.Println(fmt.Sprintf("DAEMON::failed player=%s error=%s", key, err))
+			}
+		}(key, ducked, current.Name, ducked.lowered)
+	}
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// ExclusiveOptions configures the exclusive playback of the daemon.
+type ExclusiveOptions struct {
+	// Resume resumes the players paused for a player once it stops playing or vanishes.
+	Resume bool
+	// Allow are the players which neither pause nor are paused by the others, matched as by mpris.MatchPlayerName.
+	Allow []string
+}
+
+// exclusive pauses the other players when one starts playing. It is only used by Daemon.Run.
+type exclusive struct {
+	daemon  *Daemon
+	options ExclusiveOptions
+
+	// paused are the players paused for each player, by stack key, to resume.
+	paused map[string][]mpris.PlayerState
+	// pausing are the players being paused, by stack key: they keep the players they paused themselves,
+	// to resume once they stop playing after being resumed.
+	pausing map[string]bool
+}
+
+// SetExclusive makes the players play one at a time: when one starts playing,
+// the daemon pauses the other playing ones.
+func (d *Daemon) SetExclusive(options ExclusiveOptions) {
+	d.exclusive = &exclusive{daemon: d, options: options, paused: make(map[string][]mpris.PlayerState), pausing: make(map[string]bool)}
+}
+
+func (e *exclusive) allowed(player *mpris.PlayerState) bool {
+	return len(e.options.Allow) > 0 && (mpris.Filter{Players: e.options.Allow}).Match(mpris.Event{Player: *player})
+}
+
+// update pauses or resumes players after event was applied to stack.
+func (e *exclusive) update(stack *Stack, event mpris.Event) {
+	player := event.Player
+	key := stackKey(&player)
+	switch {
+	case event.Type == mpris.EventPlaybackChanged && player.PlaybackStatus == mpris.PlaybackPlaying:
+		e.forget(key)
+		if e.allowed(&player) {
+			return
+		}
+		for _, other := range stack.Players() {
+			if other.PlaybackStatus != mpris.PlaybackPlaying || stackKey(&other) == key || e.allowed(&other) {
+				continue
+			}
+			e.pause(key, other)
+		}
+	case event.Type == mpris.EventPlaybackChanged || event.Type == mpris.EventPlayerVanished:
+		pausedByUs := e.pausing[key]
+		delete(e.pausing, key)
+		if pausedByUs == false || event.Type == mpris.EventPlayerVanished {
+			e.resume(stack, key)
+		}
+	}
+}
+
+// forget stops remembering player as paused for others: it plays again.
+func (e *exclusive) forget(key string) {
+	for interrupter, players := range e.paused {
+		kept := players[:0]
+		for _, player := range players {
+			if stackKey(&player) != key {
+				kept = append(kept, player)
+			}
+		}
+		e.paused[interrupter] = kept
+	}
+}
+
+// pause pauses player for the player with key interrupter, remembering it to resume it.
+func (e *exclusive) pause(interrupter string, player mpris.PlayerState) {
+	e.pausing[stackKey(&player)] = true
+	if e.options.Resume {
+		e.paused[interrupter] = append(e.paused[interrupter], player)
+	}
+	fmt.Println(fmt.Sprintf("DAEMON::pausing player=%s for=%s", stackKey(&player), interrupter))
+	e.daemon.background(player, (*mpris.Client).Pause)
+}
+
+// resume resumes the players paused for the player with key interrupter, if they are still paused.
+func (e *exclusive) resume(stack *Stack, interrupter string) {
+	players := e.paused[interrupter]
+	delete(e.paused, interrupter)
+
+	for _, player := range players {
+		current, found := stack.Find(stackKey(&player))
+		if found == false || current.PlaybackStatus != mpris.PlaybackPaused {
+			continue
+		}
+		fmt.Println(fmt.Sprintf("DAEMON::resuming player=%s after=%s", stackKey(&player), interrupter))
+		e.daemon.background(current, (*mpris.Client).Play)
+	}
+}
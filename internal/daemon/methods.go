@@ -0,0 +1,250 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Methods of the daemon. Players are designated as by Stack.Find,
+// the most recently active player being used when none is given.
+const (
+	// MethodActive returns the most recently active player, or null when there is no player.
+	MethodActive = "active"
+	// MethodPlayers returns the players, the most recently active first.
+	MethodPlayers = "players"
+	// MethodBuses returns the buses watched, see BusInfo.
+	MethodBuses = "buses"
+	// MethodPlayer returns the state of {"player": name}.
+	MethodPlayer = "player"
+	// MethodCall calls {"action": action, "value": value} on {"player": name}, see Actions.
+	MethodCall = "call"
+	// MethodSubscribe returns the players selected by {"players": [names], "types": [event types]},
+	// then sends their events as notifications of MethodEvent, until the connection is closed.
+	MethodSubscribe = "subscribe"
+
+	// MethodEvent is the method of the notifications sent to subscribers.
+	MethodEvent = "event"
+	// MethodOverflow is notified to subscribers too slow to read their events, before closing their connection.
+	MethodOverflow = "overflow"
+)
+
+// CodePlayerNotFound is the error code returned when the player given to a method is not on the bus.
+const CodePlayerNotFound = -32001
+
+// Methods returns the methods to serve.
+func (d *Daemon) Methods() map[string]Method {
+	return map[string]Method{
+		MethodActive:    d.active,
+		MethodPlayers:   d.players,
+		MethodBuses:     d.buses,
+		MethodPlayer:    d.player,
+		MethodCall:      d.call,
+		MethodSubscribe: d.subscribe,
+	}
+}
+
+type playerParams struct {
+	Player string `json:"player"`
+}
+
+type callParams struct {
+	Player string          `json:"player"`
+	Action string          `json:"action"`
+	Value  json.RawMessage `json:"value"`
+}
+
+type subscribeParams struct {
+	Players []string `json:"players"`
+	Types   []string `json:"types"`
+}
+
+// Action is an action of MethodCall, value being its JSON argument.
+type Action func(ctx context.Context, client *mpris.Client, playerName string, value json.RawMessage) error
+
+// Actions are the actions of MethodCall, by name. Durations are in microseconds.
+var Actions = map[string]Action{
+	"play":       withoutValue((*mpris.Client).Play),
+	"pause":      withoutValue((*mpris.Client).Pause),
+	"play-pause": withoutValue((*mpris.Client).PlayPause),
+	"stop":       withoutValue((*mpris.Client).Stop),
+	"next":       withoutValue((*mpris.Client).Next),
+	"previous":   withoutValue((*mpris.Client).Previous),
+	"raise":      withoutValue((*mpris.Client).Raise),
+	"seek":       withDuration((*mpris.Client).Seek),
+	"position":   withDuration((*mpris.Client).SetPosition),
+	"open-uri":   withValue((*mpris.Client).OpenUri),
+	"loop":       withValue((*mpris.Client).SetLoopStatus),
+	"shuffle":    withValue((*mpris.Client).SetShuffle),
+	"volume":     withValue((*mpris.Client).SetVolume),
+	"rate":       withValue((*mpris.Client).SetRate),
+}
+
+func withoutValue(f func(*mpris.Client, context.Context, string) error) Action {
+	return func(ctx context.Context, client *mpris.Client, playerName string, value json.RawMessage) error {
+		return f(client, ctx, playerName)
+	}
+}
+
+func withValue[T any](f func(*mpris.Client, context.Context, string, T) error) Action {
+	return func(ctx context.Context, client *mpris.Client, playerName string, value json.RawMessage) error {
+		var v T
+		if err := json.Unmarshal(value, &v); err != nil {
+			return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("value: %s", err)}
+		}
+		return f(client, ctx, playerName, v)
+	}
+}
+
+func withDuration(f func(*mpris.Client, context.Context, string, time.Duration) error) Action {
+	return withValue(func(client *mpris.Client, ctx context.Context, playerName string, microseconds int64) error {
+		return f(client, ctx, playerName, time.Duration(microseconds)*time.Microsecond)
+	})
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// find returns the player designated by name, the most recently active one if name is empty.
+func (d *Daemon) find(name string) (*mpris.PlayerState, error) {
+	var player mpris.PlayerState
+	var found bool
+	if name == "" {
+		player, found = d.stack.Active()
+	} else {
+		player, found = d.stack.Find(name)
+	}
+	if found == false {
+		if name == "" {
+			return nil, &Error{Code: CodePlayerNotFound, Message: "no player is running"}
+		}
+		return nil, &Error{Code: CodePlayerNotFound, Message: fmt.Sprintf("player not found: %s", name)}
+	}
+	return &player, nil
+}
+
+func (d *Daemon) active(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	player, found := d.stack.Active()
+	if found == false {
+		return nil, nil
+	}
+	return NewPlayerInfo(&player), nil
+}
+
+func (d *Daemon) players(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	players := d.stack.Players()
+	infos := make([]PlayerInfo, 0, len(players))
+	for i := range players {
+		infos = append(infos, NewPlayerInfo(&players[i]))
+	}
+	return infos, nil
+}
+
+func (d *Daemon) buses(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	buses := make([]BusInfo, 0, len(d.watched))
+	return append(buses, d.watched...), nil
+}
+
+func (d *Daemon) player(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p playerParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	player, err := d.find(p.Player)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayerInfo(player), nil
+}
+
+func (d *Daemon) call(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p callParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	action, found := Actions[p.Action]
+	if found == false {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown action %q, must be one of %s", p.Action, actionNames())}
+	}
+	player, err := d.find(p.Player)
+	if err != nil {
+		return nil, err
+	}
+	client, err := d.client(player)
+	if err != nil {
+		return nil, err
+	}
+	return nil, action(ctx, client, player.Name, p.Value)
+}
+
+func actionNames() string {
+	names := make([]string, 0, len(Actions))
+	for name := range Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (d *Daemon) subscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p subscribeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	conn := ConnFromContext(ctx)
+	if conn == nil {
+		return nil, &Error{Code: CodeInternalError, Message: "subscribing needs a connection"}
+	}
+	filter, err := ParseFilter(p.Players, p.Types)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	// The subscription starts before the snapshot is taken, and its events are sent after the response:
+	// no event is lost nor sent before the snapshot.
+	subscription := d.broadcaster.Subscribe(filter)
+	players := d.stack.Players()
+	snapshot := make([]PlayerInfo, 0, len(players))
+	for i := range players {
+		if filter.MatchPlayer(&players[i]) {
+			snapshot = append(snapshot, NewPlayerInfo(&players[i]))
+		}
+	}
+	conn.AfterResponse(func() {
+		go notify(ctx, conn, subscription)
+	})
+	return snapshot, nil
+}
+
+// notify sends the events of subscription on conn, until ctx is done or the subscription overflows.
+func notify(ctx context.Context, conn *Conn, subscription *Subscription) {
+	defer subscription.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events():
+			if ok == false {
+				conn.Notify(MethodOverflow, nil)
+				conn.Close()
+				return
+			}
+			if err := conn.Notify(MethodEvent, NewEventInfo(event)); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
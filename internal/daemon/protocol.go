@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The daemon speaks JSON-RPC 2.0 over a unix socket, one message per line.
+
+const jsonrpcVersion = "2.0"
+
+// Error codes defined by JSON-RPC.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// ErrNotRunning is returned by Dial when no daemon listens on the socket.
+var ErrNotRunning = errors.New("daemon not running")
+
+// ErrBusNotWatched is returned when the daemon does not watch the bus asked about.
+var ErrBusNotWatched = errors.New("daemon not watching the bus")
+
+// Request is a JSON-RPC request. Requests without ID are notifications and get no response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response, holding either Result or Error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// SocketPath returns the path of the socket of the daemon: mprisctl.sock in $XDG_RUNTIME_DIR,
+// or in the temporary directory when it is not set.
+func SocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "mprisctl.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("mprisctl-%d.sock", os.Getuid()))
+}
+
+// StatePath returns the path of the file keeping the player stack across restarts:
+// mprisctl/stack.json in $XDG_STATE_HOME, which defaults to ~/.local/state.
+func StatePath() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "mprisctl", "stack.json"), nil
+}
+
+// Client calls the methods of a daemon. Calls must not be concurrent.
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	encoder *json.Encoder
+	nextID  int
+}
+
+// Dial connects to the daemon listening on path.
+// It returns ErrNotRunning when there is none.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotRunning, err)
+	}
+	return &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		encoder: json.NewEncoder(conn),
+	}, nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Call calls method with params and stores its result into result, unless result is nil.
+// Errors returned by the method are *Error.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	c.nextID++
+	request := Request{JSONRPC: jsonrpcVersion, ID: json.RawMessage(fmt.Sprint(c.nextID)), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = data
+	}
+	if err := c.encoder.Encode(request); err != nil {
+		return err
+	}
+
+	var response Response
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var message struct {
+			Response
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &message); err != nil {
+			return err
+		}
+		// Notifications sent meanwhile to a subscribed connection are skipped.
+		if message.Method == "" {
+			response = message.Response
+			break
+		}
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,221 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/internal/mprisserver"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// proxyTimeout bounds the calls forwarded to the active player.
+const proxyTimeout = 5 * time.Second
+
+// ErrNoActivePlayer is returned by the proxy when there is no player to forward to.
+var ErrNoActivePlayer = errors.New("no active player")
+
+// proxy exports the most recently active player of a Stack as the player mpris.ProxyName,
+// forwarding the calls it receives and publishing the state of the player as its own.
+type proxy struct {
+	server *mprisserver.Server
+	daemon *Daemon
+
+	lock sync.Mutex
+	// active is the player mirrored, nil when there is none.
+	active *mpris.PlayerState
+}
+
+// ExportProxy exports the most recently active player as the player mpris.ProxyName on conn.
+// The proxy follows the events given to Run.
+func (d *Daemon) ExportProxy(conn *dbus.Conn) error {
+	p := &proxy{daemon: d}
+
+	server, err := mprisserver.Export(conn, mprisserver.Config{
+		Name:     mpris.ProxyName,
+		Identity: proxyIdentity(nil),
+		Player:   proxyPlayer{p},
+		Position: p.position,
+	}, idleState())
+	if err != nil {
+		return err
+	}
+	p.server = server
+	d.proxy = p
+	return nil
+}
+
+// idleState is the state published while there is no active player.
+func idleState() *mpris.PlayerState {
+	return &mpris.PlayerState{
+		PlaybackStatus: mpris.PlaybackStopped,
+		LoopStatus:     mpris.LoopStatusNone,
+		Rate:           1,
+		MinimumRate:    1,
+		MaximumRate:    1,
+	}
+}
+
+func proxyIdentity(player *mpris.PlayerState) mprisserver.Identity {
+	identity := mprisserver.Identity{Identity: "mprisctl", CanRaise: player != nil}
+	if player != nil {
+		identity.Identity = player.Identity
+		if identity.Identity == "" {
+			identity.Identity = player.Name
+		}
+		identity.DesktopEntry = player.DesktopEntry
+	}
+	return identity
+}
+
+// update publishes the state of the active player, after event was applied to the stack.
+func (p *proxy) update(stack *Stack, event mpris.Event) {
+	active, found := stack.Active()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	switch {
+	case found == false:
+		if p.active != nil {
+			p.active = nil
+			p.server.SetIdentity(proxyIdentity(nil))
+			p.server.Update(idleState())
+		}
+	case p.active == nil || stackKey(p.active) != stackKey(&active):
+		p.active = &active
+		p.server.SetIdentity(proxyIdentity(&active))
+		p.server.Update(&active)
+	case stackKey(&event.Player) == stackKey(&active):
+		p.active = &active
+		switch event.Type {
+		case mpris.EventSeeked:
+			p.server.Seeked(active.Position)
+		case mpris.EventPosition:
+			// The position is read from the player when asked.
+		default:
+			p.server.Update(&active)
+		}
+	}
+}
+
+// target returns the client and the name to forward the calls to.
+func (p *proxy) target() (*mpris.Client, string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.active == nil {
+		return nil, "", ErrNoActivePlayer
+	}
+	client, err := p.daemon.client(p.active)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, p.active.Name, nil
+}
+
+func (p *proxy) forward(call func(client *mpris.Client, ctx context.Context, playerName string) error) error {
+	client, playerName, err := p.target()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), proxyTimeout)
+	defer cancel()
+	return call(client, ctx, playerName)
+}
+
+func (p *proxy) position() time.Duration {
+	var position time.Duration
+	p.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		var err error
+		position, err = client.Position(ctx, playerName)
+		return err
+	})
+	return position
+}
+
+func (p *proxy) close() error {
+	return p.server.Close()
+}
+
+// proxyPlayer forwards the calls made on the proxy.
+type proxyPlayer struct {
+	proxy *proxy
+}
+
+func (p proxyPlayer) Raise() error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.Raise(ctx, playerName)
+	})
+}
+
+func (p proxyPlayer) Quit() error {
+	return mprisserver.ErrNotSupported
+}
+
+func (p proxyPlayer) Next() error {
+	return p.proxy.forward((*mpris.Client).Next)
+}
+
+func (p proxyPlayer) Previous() error {
+	return p.proxy.forward((*mpris.Client).Previous)
+}
+
+func (p proxyPlayer) Pause() error {
+	return p.proxy.forward((*mpris.Client).Pause)
+}
+
+func (p proxyPlayer) PlayPause() error {
+	return p.proxy.forward((*mpris.Client).PlayPause)
+}
+
+func (p proxyPlayer) Stop() error {
+	return p.proxy.forward((*mpris.Client).Stop)
+}
+
+func (p proxyPlayer) Play() error {
+	return p.proxy.forward((*mpris.Client).Play)
+}
+
+func (p proxyPlayer) Seek(offset time.Duration) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.Seek(ctx, playerName, offset)
+	})
+}
+
+// SetPosition ignores trackId, the track of the active player being the one of the proxy.
+func (p proxyPlayer) SetPosition(trackId string, position time.Duration) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.SetPosition(ctx, playerName, position)
+	})
+}
+
+func (p proxyPlayer) OpenUri(uri string) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.OpenUri(ctx, playerName, uri)
+	})
+}
+
+func (p proxyPlayer) SetLoopStatus(value mpris.LoopStatus) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.SetLoopStatus(ctx, playerName, value)
+	})
+}
+
+func (p proxyPlayer) SetShuffle(value bool) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.SetShuffle(ctx, playerName, value)
+	})
+}
+
+func (p proxyPlayer) SetVolume(value float64) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.SetVolume(ctx, playerName, value)
+	})
+}
+
+func (p proxyPlayer) SetRate(value float64) error {
+	return p.proxy.forward(func(client *mpris.Client, ctx context.Context, playerName string) error {
+		return client.SetRate(ctx, playerName, value)
+	})
+}
@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrRunning is returned by Listen when another daemon already listens on the socket.
+var ErrRunning = errors.New("daemon already running")
+
+// Method handles the calls of a method. Errors which are not *Error are reported as internal errors.
+type Method func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// Server serves methods on a unix socket.
+type Server struct {
+	listener net.Listener
+	path     string
+	methods  map[string]Method
+}
+
+// Listen listens on the unix socket at path, replacing a socket left by a daemon which is gone.
+func Listen(path string, methods map[string]Method) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if client, err := Dial(ctx, path); err == nil {
+			client.Close()
+			return nil, fmt.Errorf("%w: %s", ErrRunning, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{listener: listener, path: path, methods: methods}, nil
+}
+
+// Path returns the path of the socket.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Close closes the socket, for servers which are not serving.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Serve accepts connections until ctx is done, then closes the socket.
+func (s *Server) Serve(ctx context.Context) error {
+	var group sync.WaitGroup
+	defer group.Wait()
+
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// Conn is a connection to the server, given to methods through their context.
+type Conn struct {
+	cancel context.CancelFunc
+
+	lock    sync.Mutex
+	encoder *json.Encoder
+	// after are run once the response to the current request is written.
+	after []func()
+}
+
+type connKey struct{}
+
+// ConnFromContext returns the connection a method is called on.
+func ConnFromContext(ctx context.Context) *Conn {
+	conn, _ := ctx.Value(connKey{}).(*Conn)
+	return conn
+}
+
+// Notify sends a notification, a request without ID, to the client.
+func (c *Conn) Notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(Request{JSONRPC: jsonrpcVersion, Method: method, Params: data})
+}
+
+// AfterResponse runs f once the response to the current request is written,
+// for notifications which must follow it.
+func (c *Conn) AfterResponse(f func()) {
+	c.after = append(c.after, f)
+}
+
+// Close closes the connection.
+func (c *Conn) Close() {
+	c.cancel()
+}
+
+func (c *Conn) write(message interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.encoder.Encode(message)
+}
+
+func (s *Server) serveConn(ctx context.Context, netConn net.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		netConn.Close()
+	}()
+
+	conn := &Conn{cancel: cancel, encoder: json.NewEncoder(netConn)}
+	connCtx = context.WithValue(connCtx, connKey{}, conn)
+	scanner := bufio.NewScanner(netConn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		response := s.handle(connCtx, scanner.Bytes())
+		if response != nil {
+			if err := conn.write(response); err != nil {
+				return
+			}
+		}
+		for _, f := range conn.after {
+			f()
+		}
+		conn.after = nil
+	}
+}
+
+// handle returns the response to a request, nil for notifications.
+func (s *Server) handle(ctx context.Context, line []byte) *Response {
+	var request Request
+	if err := json.Unmarshal(line, &request); err != nil {
+		return errorResponse(json.RawMessage("null"), &Error{Code: CodeParseError, Message: err.Error()})
+	}
+	if request.JSONRPC != jsonrpcVersion || request.Method == "" {
+		return errorResponse(request.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	method, found := s.methods[request.Method]
+	if found == false {
+		if request.ID == nil {
+			return nil
+		}
+		return errorResponse(request.ID, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", request.Method)})
+	}
+	result, err := method(ctx, request.Params)
+	if request.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) == false {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return errorResponse(request.ID, rpcErr)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(request.ID, &Error{Code: CodeInternalError, Message: err.Error()})
+	}
+	return &Response{JSONRPC: jsonrpcVersion, ID: request.ID, Result: data}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: jsonrpcVersion, ID: id, Error: err}
+}
@@ -0,0 +1,216 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// maxPersisted bounds the players remembered across restarts.
+const maxPersisted = 32
+
+// Stack orders players by last activity, the most recently active first,
+// and caches the state of those on the bus.
+// Players leaving the bus keep their place, so the order survives them restarting,
+// unless they are below the players persisted by Save.
+// A Stack is safe for concurrent use.
+type Stack struct {
+	lock    sync.Mutex
+	entries []*stackEntry
+	// changed is set when the order changed since the last Save.
+	changed bool
+}
+
+type stackEntry struct {
+	// key is the player name, followed by its bus label if any.
+	key        string
+	lastActive time.Time
+	// state is nil while the player is not on the bus.
+	state *mpris.PlayerState
+}
+
+// persistedEntry is an entry of the file written by Save.
+type persistedEntry struct {
+	Player     string    `json:"player"`
+	LastActive time.Time `json:"last_active"`
+}
+
+func stackKey(player *mpris.PlayerState) string {
+	if player.Bus != "" {
+		return player.Name + "@" + player.Bus
+	}
+	return player.Name
+}
+
+// NewStack returns an empty Stack.
+func NewStack() *Stack {
+	return &Stack{entries: make([]*stackEntry, 0)}
+}
+
+// LoadStack returns the Stack saved to path, or an empty Stack when path does not exist.
+func LoadStack(path string) (*Stack, error) {
+	stack := NewStack()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stack, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	persisted := make([]persistedEntry, 0)
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, err
+	}
+	for _, entry := range persisted {
+		stack.entries = append(stack.entries, &stackEntry{key: entry.Player, lastActive: entry.LastActive})
+	}
+	return stack, nil
+}
+
+// Save writes the order of the players to path, when it changed since the last Save.
+func (s *Stack) Save(path string) error {
+	s.lock.Lock()
+	if s.changed == false {
+		s.lock.Unlock()
+		return nil
+	}
+	persisted := make([]persistedEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if len(persisted) == maxPersisted {
+			break
+		}
+		persisted = append(persisted, persistedEntry{Player: entry.key, LastActive: entry.lastActive})
+	}
+	s.changed = false
+	s.lock.Unlock()
+
+	data, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+// Update applies event to the stack. A player becomes the most recently active
+// when it starts playing, or when it changes track or seeks while playing.
+// A player seen for the first time goes on top, others keep their place when appearing.
+func (s *Stack) Update(event mpris.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	player := event.Player
+	index := s.find(stackKey(&player))
+	if event.Type == mpris.EventPlayerVanished {
+		if index >= 0 {
+			s.entries[index].state = nil
+			s.prune()
+		}
+		return
+	}
+
+	if index < 0 {
+		s.entries = append(s.entries, &stackEntry{key: stackKey(&player)})
+		s.touch(len(s.entries) - 1)
+		index = 0
+	}
+	s.entries[index].state = &player
+
+	switch event.Type {
+	case mpris.EventPlaybackChanged, mpris.EventTrackChanged, mpris.EventSeeked:
+		if player.PlaybackStatus == mpris.PlaybackPlaying {
+			s.touch(index)
+		}
+	}
+}
+
+// prune removes the players not on the bus below the maxPersisted first ones,
+// which Save would not write either, for the stack not to grow with every player ever seen.
+func (s *Stack) prune() {
+	if len(s.entries) <= maxPersisted {
+		return
+	}
+	entries := s.entries[:maxPersisted]
+	for _, entry := range s.entries[maxPersisted:] {
+		if entry.state != nil {
+			entries = append(entries, entry)
+		}
+	}
+	for index := len(entries); index < len(s.entries); index++ {
+		s.entries[index] = nil
+	}
+	s.entries = entries
+}
+
+// touch moves the entry at index on top.
+func (s *Stack) touch(index int) {
+	entry := s.entries[index]
+	entry.lastActive = time.Now()
+	copy(s.entries[1:index+1], s.entries[:index])
+	s.entries[0] = entry
+	s.changed = true
+}
+
+func (s *Stack) find(key string) int {
+	for index, entry := range s.entries {
+		if entry.key == key {
+			return index
+		}
+	}
+	return -1
+}
+
+// Active returns the most recently active player on the bus.
+func (s *Stack) Active() (mpris.PlayerState, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, entry := range s.entries {
+		if entry.state != nil {
+			return *entry.state, true
+		}
+	}
+	return mpris.PlayerState{}, false
+}
+
+// Find returns the most recently active player on the bus designated by name: a player name,
+// matched as by mpris.MatchPlayerName, followed by "@<bus label>" for the players of a labelled bus.
+func (s *Stack) Find(name string) (mpris.PlayerState, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, entry := range s.entries {
+		if entry.state != nil && designates(name, entry.state) {
+			return *entry.state, true
+		}
+	}
+	return mpris.PlayerState{}, false
+}
+
+// designates reports whether name designates player, see Find.
+func designates(name string, player *mpris.PlayerState) bool {
+	playerName, bus, _ := strings.Cut(name, "@")
+	return player.Bus == bus && mpris.MatchPlayerName(playerName, player.Name)
+}
+
+// Players returns the players on the bus, the most recently active first.
+func (s *Stack) Players() []mpris.PlayerState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	players := make([]mpris.PlayerState, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if entry.state != nil {
+			players = append(players, *entry.state)
+		}
+	}
+	return players
+}
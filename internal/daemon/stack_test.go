@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func TestStackPrunesVanishedPlayers(t *testing.T) {
+	stack := NewStack()
+	event := func(eventType mpris.EventType, name string) {
+		stack.Update(mpris.Event{Type: eventType, Player: mpris.PlayerState{Name: name, PlaybackStatus: mpris.PlaybackPlaying}})
+	}
+
+	// Like a browser, whose instance name changes on every launch.
+	for i := 0; i < 3*maxPersisted; i++ {
+		name := fmt.Sprintf("browser.instance%d", i)
+		event(mpris.EventPlaybackChanged, name)
+		event(mpris.EventPlayerVanished, name)
+	}
+	if len(stack.entries) != maxPersisted {
+		t.Errorf("len(entries) = %d, want %d", len(stack.entries), maxPersisted)
+	}
+
+	// The players on the bus are kept, however many, and so is a vanished player persisted by Save.
+	for i := 0; i < 2*maxPersisted; i++ {
+		event(mpris.EventPlayerAppeared, fmt.Sprintf("player%d", i))
+	}
+	event(mpris.EventPlaybackChanged, "browser")
+	event(mpris.EventPlayerVanished, "browser")
+	if len(stack.entries) != 2*maxPersisted+1 {
+		t.Errorf("len(entries) = %d, want %d", len(stack.entries), 2*maxPersisted+1)
+	}
+	if players := stack.Players(); len(players) != 2*maxPersisted {
+		t.Errorf("len(Players()) = %d, want %d", len(players), 2*maxPersisted)
+	}
+	if index := stack.find("browser"); index != 0 {
+		t.Errorf("find(%q) = %d, want 0", "browser", index)
+	}
+}
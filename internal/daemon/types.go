@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"github.com/webflo-dev/mpris-ctl/internal/desktop"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// PlayerInfo is the state of a player, as sent on the socket.
+// Durations are in microseconds, as in MPRIS.
+type PlayerInfo struct {
+	// Player is the name to give to the methods: the player name, followed by "@<bus>" when watching several buses.
+	Player       string `json:"player"`
+	Name         string `json:"name"`
+	Application  string `json:"application"`
+	Instance     string `json:"instance,omitempty"`
+	Bus          string `json:"bus,omitempty"`
+	BusName      string `json:"bus_name"`
+	Owner        string `json:"owner"`
+	Identity     string `json:"identity,omitempty"`
+	DesktopEntry string `json:"desktop_entry,omitempty"`
+	// DisplayName, Icon and IconPath present the player to humans, see desktop.IdentifyPlayer.
+	DisplayName string `json:"display_name"`
+	Icon        string `json:"icon,omitempty"`
+	IconPath    string `json:"icon_path,omitempty"`
+
+	PlaybackStatus string       `json:"playback_status"`
+	LoopStatus     string       `json:"loop_status"`
+	Shuffle        bool         `json:"shuffle"`
+	Volume         float64      `json:"volume"`
+	Position       int64        `json:"position"`
+	Rate           float64      `json:"rate"`
+	MinimumRate    float64      `json:"minimum_rate"`
+	MaximumRate    float64      `json:"maximum_rate"`
+	Metadata       MetadataInfo `json:"metadata"`
+
+	CanControl    bool `json:"can_control"`
+	CanGoNext     bool `json:"can_go_next"`
+	CanGoPrevious bool `json:"can_go_previous"`
+	CanPause      bool `json:"can_pause"`
+	CanPlay       bool `json:"can_play"`
+	CanSeek       bool `json:"can_seek"`
+}
+
+// BusInfo is a bus watched by the daemon, as sent on the socket.
+type BusInfo struct {
+	// Label is the bus of the players of this bus, see PlayerInfo.Player. It is empty when the daemon watches a single bus.
+	Label string `json:"label,omitempty"`
+	// Address is the D-Bus address of the bus, as told to the daemon.
+	Address string `json:"address"`
+}
+
+// MetadataInfo is the metadata of a track, as sent on the socket.
+type MetadataInfo struct {
+	TrackId     string   `json:"track_id,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Artist      []string `json:"artist,omitempty"`
+	Album       string   `json:"album,omitempty"`
+	AlbumArtist []string `json:"album_artist,omitempty"`
+	Length      int64    `json:"length,omitempty"`
+	Url         string   `json:"url,omitempty"`
+	ArtUrl      string   `json:"art_url,omitempty"`
+}
+
+// EventInfo is an event, as sent to subscribers.
+type EventInfo struct {
+	Type   string     `json:"type"`
+	Player PlayerInfo `json:"player"`
+}
+
+// NewPlayerInfo converts player to its representation on the socket.
+func NewPlayerInfo(player *mpris.PlayerState) PlayerInfo {
+	metadata := player.Metadata
+	identity := desktop.IdentifyPlayer(player)
+	return PlayerInfo{
+		Player:         stackKey(player),
+		Name:           player.Name,
+		Application:    player.Application,
+		Instance:       player.Instance,
+		Bus:            player.Bus,
+		BusName:        player.BusName,
+		Owner:          player.Owner,
+		Identity:       player.Identity,
+		DesktopEntry:   player.DesktopEntry,
+		DisplayName:    identity.DisplayName,
+		Icon:           identity.Icon,
+		IconPath:       identity.IconPath,
+		PlaybackStatus: string(player.PlaybackStatus),
+		LoopStatus:     string(player.LoopStatus),
+		Shuffle:        player.Shuffle,
+		Volume:         player.Volume,
+		Position:       player.Position.Microseconds(),
+		Rate:           player.Rate,
+		MinimumRate:    player.MinimumRate,
+		MaximumRate:    player.MaximumRate,
+		Metadata: MetadataInfo{
+			TrackId:     metadata.TrackId,
+			Title:       metadata.Title,
+			Artist:      metadata.Artist,
+			Album:       metadata.Album,
+			AlbumArtist: metadata.AlbumArtist,
+			Length:      metadata.Length.Microseconds(),
+			Url:         metadata.Url,
+			ArtUrl:      metadata.ArtUrl,
+		},
+		CanControl:    player.CanControl,
+		CanGoNext:     player.CanGoNext,
+		CanGoPrevious: player.CanGoPrevious,
+		CanPause:      player.CanPause,
+		CanPlay:       player.CanPlay,
+		CanSeek:       player.CanSeek,
+	}
+}
+
+// NewEventInfo converts event to its representation on the socket.
+func NewEventInfo(event mpris.Event) EventInfo {
+	return EventInfo{Type: event.Type.String(), Player: NewPlayerInfo(&event.Player)}
+}
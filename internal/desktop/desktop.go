@@ -0,0 +1,196 @@
+// Package desktop reads the desktop entries of applications, following the XDG
+// base directory, desktop entry and icon theme specifications.
+package desktop
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when no desktop entry has the requested id.
+var ErrNotFound = errors.New("desktop entry not found")
+
+// Entry is what is used of a desktop entry.
+type Entry struct {
+	// Path is the path of the .desktop file.
+	Path string
+	// Name is the name of the application, localized for the current locale.
+	Name string
+	// Icon is the icon of the application, as an icon name or an absolute path.
+	Icon string
+	// IconPath is the file of Icon, empty when it could not be found.
+	IconPath string
+	// Exec is the command line starting the application.
+	Exec string
+}
+
+// DataDirs returns $XDG_DATA_HOME followed by $XDG_DATA_DIRS, with their default values.
+func DataDirs() []string {
+	dirs := make([]string, 0)
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+	}
+	if dataHome != "" {
+		dirs = append(dirs, dataHome)
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Find reads the desktop entry whose id is id, like "vlc" or "org.gnome.Rhythmbox3",
+// in the applications directory of the data directories.
+func Find(id string) (*Entry, error) {
+	id = strings.TrimSuffix(id, ".desktop")
+	if id == "" || strings.Contains(id, "/") {
+		return nil, ErrNotFound
+	}
+	for _, dir := range DataDirs() {
+		for _, path := range candidatePaths(filepath.Join(dir, "applications"), id) {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			return Parse(path, Locales())
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindApplication reads the desktop entry of an application known by name, like "vlc":
+// either its id is name, or its id is a reverse DNS name ending with name, like "org.videolan.VLC",
+// ignoring case.
+func FindApplication(name string) (*Entry, error) {
+	if entry, err := Find(name); err == nil {
+		return entry, nil
+	}
+	suffix := "." + strings.ToLower(name) + ".desktop"
+	for _, dir := range DataDirs() {
+		files, err := os.ReadDir(filepath.Join(dir, "applications"))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if strings.HasSuffix(strings.ToLower(file.Name()), suffix) {
+				return Parse(filepath.Join(dir, "applications", file.Name()), Locales())
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// candidatePaths returns where the file of id can be: dashes of an id stand for
+// subdirectories, like "kde-konsole" for "kde/konsole.desktop".
+func candidatePaths(applications string, id string) []string {
+	paths := []string{filepath.Join(applications, id+".desktop")}
+	parts := strings.Split(id, "-")
+	for i := 1; i < len(parts); i++ {
+		dir := filepath.Join(parts[:i]...)
+		paths = append(paths, filepath.Join(applications, dir, strings.Join(parts[i:], "-")+".desktop"))
+	}
+	return paths
+}
+
+// Parse reads the [Desktop Entry] group of the file at path,
+// localizing Name with the first matching locale of locales.
+func Parse(path string, locales []string) (*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entry := &Entry{Path: path}
+	names := make(map[string]string)
+	inEntry := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if inEntry == false {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if found == false {
+			continue
+		}
+		key, value = strings.TrimSpace(key), unescape(strings.TrimSpace(value))
+		switch {
+		case key == "Name":
+			names[""] = value
+		case strings.HasPrefix(key, "Name[") && strings.HasSuffix(key, "]"):
+			names[key[len("Name["):len(key)-1]] = value
+		case key == "Icon":
+			entry.Icon = value
+		case key == "Exec":
+			entry.Exec = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entry.Name = names[""]
+	for _, locale := range locales {
+		if name, found := names[locale]; found {
+			entry.Name = name
+			break
+		}
+	}
+	entry.IconPath = FindIcon(entry.Icon)
+	return entry, nil
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\s`, " ", `\n`, "\n", `\t`, "\t", `\r`, "\r", `\\`, `\`).Replace(value)
+}
+
+// Locales returns the keys to look for in localized values, by order of preference,
+// for the locale of $LC_ALL, $LC_MESSAGES or $LANG: "fr_FR.UTF-8@euro" gives
+// fr_FR@euro, fr_FR, fr@euro and fr.
+func Locales() []string {
+	locale := ""
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(variable); locale != "" {
+			break
+		}
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+
+	locale, modifier, _ := strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	lang, country, _ := strings.Cut(locale, "_")
+
+	locales := make([]string, 0, 4)
+	if country != "" && modifier != "" {
+		locales = append(locales, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		locales = append(locales, lang+"_"+country)
+	}
+	if modifier != "" {
+		locales = append(locales, lang+"@"+modifier)
+	}
+	return append(locales, lang)
+}
@@ -0,0 +1,85 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Launch starts the application of e, detached from the calling process.
+func (e *Entry) Launch() error {
+	args, err := e.Command()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// Command returns the arguments of Exec, without the field codes standing for files or URLs.
+func (e *Entry) Command() ([]string, error) {
+	fields, err := splitExec(e.Exec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.Path, err)
+	}
+
+	args := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "%f", "%F", "%u", "%U", "%d", "%D", "%n", "%N", "%v", "%m":
+			continue
+		case "%i":
+			if e.Icon != "" {
+				args = append(args, "--icon", e.Icon)
+			}
+			continue
+		}
+		field = strings.NewReplacer("%c", e.Name, "%k", e.Path, "%%", "%").Replace(field)
+		args = append(args, field)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: no Exec", e.Path)
+	}
+	return args, nil
+}
+
+// splitExec splits an Exec value into arguments, as quoted by the desktop entry specification.
+func splitExec(value string) ([]string, error) {
+	args := make([]string, 0)
+	var arg strings.Builder
+	inArg, quoted, escaped := false, false, false
+	for _, r := range value {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case r == ' ' && quoted == false:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote in Exec")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,84 @@
+package desktop
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var iconExtensions = []string{".svg", ".png", ".xpm"}
+
+// FindIcon returns the file of the icon named name in the hicolor theme, which every
+// theme falls back to, or in the pixmaps directories. Scalable icons are preferred,
+// then the largest ones. An absolute name is returned as is if the file exists.
+func FindIcon(name string) string {
+	if name == "" {
+		return ""
+	}
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		return ""
+	}
+
+	dataDirs := DataDirs()
+	iconDirs := make([]string, 0, len(dataDirs)+1)
+	if home, err := os.UserHomeDir(); err == nil {
+		iconDirs = append(iconDirs, filepath.Join(home, ".icons"))
+	}
+	for _, dir := range dataDirs {
+		iconDirs = append(iconDirs, filepath.Join(dir, "icons"))
+	}
+
+	best, bestSize := "", -1
+	for _, dir := range iconDirs {
+		theme := filepath.Join(dir, "hicolor")
+		sizes, err := os.ReadDir(theme)
+		if err != nil {
+			continue
+		}
+		for _, size := range sizes {
+			path := findWithExtension(filepath.Join(theme, size.Name(), "apps", name))
+			if path == "" {
+				continue
+			}
+			if value := iconSize(size.Name()); value > bestSize {
+				best, bestSize = path, value
+			}
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	for _, dir := range dataDirs {
+		if path := findWithExtension(filepath.Join(dir, "pixmaps", name)); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// iconSize ranks the size directories of a theme, like "48x48", "48x48@2" or "scalable".
+func iconSize(dir string) int {
+	if dir == "scalable" {
+		return 1 << 16
+	}
+	size, _, _ := strings.Cut(dir, "x")
+	value, err := strconv.Atoi(size)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func findWithExtension(base string) string {
+	for _, extension := range iconExtensions {
+		if _, err := os.Stat(base + extension); err == nil {
+			return base + extension
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package desktop
+
+import (
+	"sync"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// PlayerIdentity is how a player is presented to humans.
+type PlayerIdentity struct {
+	// DisplayName is the localized name of the desktop entry,
+	// or else the Identity of the player, or else its application name.
+	DisplayName string
+	// Icon is the icon of the desktop entry, as an icon name or an absolute path.
+	Icon string
+	// IconPath is the file of Icon, empty when it could not be found.
+	IconPath string
+}
+
+var desktopEntries = struct {
+	lock    sync.Mutex
+	entries map[string]*Entry
+}{entries: make(map[string]*Entry)}
+
+// findDesktopEntry caches Find, including misses.
+func findDesktopEntry(id string) *Entry {
+	desktopEntries.lock.Lock()
+	defer desktopEntries.lock.Unlock()
+	entry, found := desktopEntries.entries[id]
+	if found == false {
+		entry, _ = Find(id)
+		desktopEntries.entries[id] = entry
+	}
+	return entry
+}
+
+// IdentifyPlayer resolves the desktop entry of player, falling back on its application name
+// for players not telling their DesktopEntry.
+func IdentifyPlayer(player *mpris.PlayerState) PlayerIdentity {
+	identity := PlayerIdentity{DisplayName: player.Identity}
+	if identity.DisplayName == "" {
+		identity.DisplayName = player.Application
+	}
+
+	desktopEntry := player.DesktopEntry
+	if desktopEntry == "" {
+		desktopEntry = player.Application
+	}
+	if entry := findDesktopEntry(desktopEntry); entry != nil {
+		if entry.Name != "" {
+			identity.DisplayName = entry.Name
+		}
+		identity.Icon = entry.Icon
+		identity.IconPath = entry.IconPath
+	}
+	return identity
+}
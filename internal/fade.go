@@ -0,0 +1,231 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// cancelTimeout bounds the wait for a cancelled fade to stop.
+const cancelTimeout = 2 * time.Second
+
+// FadeKind is how an action fades the volume.
+type FadeKind int
+
+const (
+	// FadeOut fades the volume out before the action, like pause.
+	FadeOut FadeKind = iota
+	// FadeIn fades the volume in after the action, like play.
+	FadeIn
+	// FadeThrough fades the volume out before the action and in after it, like next.
+	FadeThrough
+)
+
+// fade is a volume ramp of a player, recorded in a file for the next command to cancel it.
+type fade struct {
+	client *mpris.Client
+	player string
+	path   string
+	// volume is the volume the player is restored to, for the fades of actions.
+	volume float64
+	// cancelled is set when starting the fade cancelled the one of an action, volume being the volume to restore.
+	cancelled bool
+}
+
+// fadePath returns the path of the file recording the fade in progress on playerName:
+// mprisctl-fade-<player>.pid in $XDG_RUNTIME_DIR, holding the pid of the process fading,
+// followed for the fades of actions by the volume to restore.
+func fadePath(playerName string) string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, fmt.Sprintf("mprisctl-fade-%s.pid", playerName))
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("mprisctl-%d-fade-%s.pid", os.Getuid(), playerName))
+}
+
+// CancelFade stops the fade in progress on the player, made by another mprisctl. When restore is set and
+// the fade was one of an action, the volume is set back to what it was. It does nothing when no fade is in progress,
+// without querying the player.
+func CancelFade(ctx context.Context, client *mpris.Client, playerName string, restore bool) error {
+	if fading() == false {
+		return nil
+	}
+	player, err := client.Player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	volume, cancelled := cancelFade(player.Name)
+	if cancelled && restore {
+		return client.SetVolume(ctx, player.Name, volume)
+	}
+	return nil
+}
+
+// fading reports whether a fade is recorded for any player, the name of the player given
+// to a command not being the one of its file until resolved.
+func fading() bool {
+	paths, err := filepath.Glob(fadePath("*"))
+	return err == nil && len(paths) > 0
+}
+
+// cancelFade stops the fade in progress on playerName, returning the volume to restore, if any.
+func cancelFade(playerName string) (float64, bool) {
+	path := fadePath(playerName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	// Removed first: the process cancelled leaves the volume to us.
+	os.Remove(path)
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || isMprisctl(pid) == false {
+		return 0, false
+	}
+	syscall.Kill(pid, syscall.SIGTERM)
+	for deadline := time.Now().Add(cancelTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			break
+		}
+	}
+	if len(fields) < 2 {
+		return 0, false
+	}
+	volume, err := strconv.ParseFloat(fields[1], 64)
+	return volume, err == nil
+}
+
+// isMprisctl reports whether pid is another process running this binary, not a process reusing a stale pid.
+func isMprisctl(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	target, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	return err == nil && target == executable
+}
+
+// startFade cancels the fade in progress on the player, to start another one.
+func startFade(ctx context.Context, client *mpris.Client, playerName string) (*fade, error) {
+	player, err := client.Player(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	f := &fade{client: client, player: player.Name, path: fadePath(player.Name)}
+	f.volume, f.cancelled = cancelFade(player.Name)
+	return f, nil
+}
+
+// current returns the volume of the player, setting the volume to restore to it,
+// unless the fade of an action was cancelled.
+func (f *fade) current(ctx context.Context) (float64, error) {
+	volume, err := f.client.Volume(ctx, f.player)
+	if err != nil {
+		return 0, err
+	}
+	if f.cancelled == false {
+		f.volume = volume
+	}
+	return volume, nil
+}
+
+// record writes the file of the fade, the fade of an action restoring the volume.
+func (f *fade) record(action bool) error {
+	content := fmt.Sprintf("%d\n", os.Getpid())
+	if action {
+		content = fmt.Sprintf("%d %v\n", os.Getpid(), f.volume)
+	}
+	return os.WriteFile(f.path, []byte(content), 0o600)
+}
+
+// finish removes the file of the fade. When the fade was interrupted by the user rather than
+// cancelled by another command, the volume is restored when restore is set.
+func (f *fade) finish(interrupted bool, restore bool) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return
+	}
+	if fields := strings.Fields(string(data)); len(fields) == 0 || fields[0] != strconv.Itoa(os.Getpid()) {
+		// Cancelled by another command, now in charge of the volume.
+		return
+	}
+	os.Remove(f.path)
+	if interrupted && restore {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+		f.client.SetVolume(ctx, f.player, f.volume)
+	}
+}
+
+// FadeVolume changes the volume of the player to volume, gradually over duration.
+// It is cancelled by the next command on the player, or when ctx is done.
+func FadeVolume(ctx context.Context, client *mpris.Client, playerName string, volume float64, duration time.Duration) error {
+	f, err := startFade(ctx, client, playerName)
+	if err != nil {
+		return err
+	}
+	from, err := f.current(ctx)
+	if err != nil {
+		return err
+	}
+	if err := f.record(false); err != nil {
+		return err
+	}
+	err = client.FadeVolume(ctx, f.player, from, volume, duration)
+	f.finish(ctx.Err() != nil, false)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// FadeAction runs action on the player, fading the volume out before it or in after it, over duration.
+// The volume is back to what it was once done. The fade is cancelled by the next command on the player,
+// or when ctx is done, the volume being restored then. The action is run without fade on the players
+// without volume.
+func FadeAction(ctx context.Context, client *mpris.Client, playerName string, action func(*mpris.Client, context.Context, string) error, kind FadeKind, duration time.Duration) error {
+	f, err := startFade(ctx, client, playerName)
+	if err != nil {
+		return err
+	}
+	from, err := f.current(ctx)
+	if err != nil {
+		return action(client, ctx, f.player)
+	}
+	if err := f.record(true); err != nil {
+		return err
+	}
+	volume := f.volume
+
+	if kind == FadeOut || kind == FadeThrough {
+		err = client.FadeVolume(ctx, f.player, from, 0, duration)
+	} else {
+		err = client.SetVolume(ctx, f.player, 0)
+	}
+	if err == nil {
+		err = action(client, ctx, f.player)
+	}
+	if err == nil && kind == FadeOut {
+		err = client.SetVolume(ctx, f.player, volume)
+	} else if err == nil {
+		err = client.FadeVolume(ctx, f.player, 0, volume, duration)
+	}
+	f.finish(err != nil, true)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,363 @@
+package fakeplayer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Duration reads durations from JSON as strings like "1m30s", or as a number of seconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+		return nil
+	case string:
+		duration, err := time.ParseDuration(value)
+		*d = Duration(duration)
+		return err
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Command changes the state of the player, as a user of the application would.
+//
+// Actions are play, pause, play-pause, stop, next, previous, seek (Value is an offset),
+// position (Value is a position), set (Property is a property of the Player interface,
+// or a capability like "can-seek"), metadata (Track replaces the current track),
+// tracks (Tracks replaces the track list), vanish (leaves the bus) and quit.
+type Command struct {
+	// After delays the command, counting from the previous one, in scripts.
+	After    Duration    `json:"after,omitempty"`
+	Action   string      `json:"action"`
+	Property string      `json:"property,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Track    *Track      `json:"track,omitempty"`
+	Tracks   []Track     `json:"tracks,omitempty"`
+}
+
+// ParseCommand reads a command either as JSON, or as text:
+//
+//	play
+//	seek 10s
+//	set Volume 0.5
+//	set can-seek false
+//	metadata title="Some title" artist=A,B length=3m
+func ParseCommand(line string) (Command, error) {
+	line = strings.TrimSpace(line)
+	var command Command
+	if strings.HasPrefix(line, "{") {
+		err := json.Unmarshal([]byte(line), &command)
+		return command, err
+	}
+
+	fields := splitFields(line)
+	if len(fields) == 0 {
+		return command, fmt.Errorf("empty command")
+	}
+	command.Action = fields[0]
+	switch command.Action {
+	case "seek", "position":
+		if len(fields) != 2 {
+			return command, fmt.Errorf("usage: %s <duration>", command.Action)
+		}
+		command.Value = fields[1]
+	case "set":
+		if len(fields) != 3 {
+			return command, fmt.Errorf("usage: set <property> <value>")
+		}
+		command.Property = fields[1]
+		command.Value = parseValue(fields[2])
+	case "metadata":
+		track, err := parseTrackFields(fields[1:])
+		if err != nil {
+			return command, err
+		}
+		command.Track = &track
+	}
+	return command, nil
+}
+
+// ParseTrack reads a track from space separated key=value fields, like
+// title="Some title" artist=A,B length=3m. The keys are title, artist, album, length, url and art_url.
+func ParseTrack(line string) (Track, error) {
+	return parseTrackFields(splitFields(line))
+}
+
+func parseTrackFields(fields []string) (Track, error) {
+	var track Track
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if found == false {
+			return track, fmt.Errorf("invalid track field %q, expecting key=value", field)
+		}
+		switch key {
+		case "title":
+			track.Title = value
+		case "artist":
+			track.Artist = strings.Split(value, ",")
+		case "album":
+			track.Album = value
+		case "length":
+			length, err := time.ParseDuration(value)
+			if err != nil {
+				return track, err
+			}
+			track.Length = Duration(length)
+		case "url":
+			track.Url = value
+		case "art_url":
+			track.ArtUrl = value
+		default:
+			return track, fmt.Errorf("unknown track field %q", key)
+		}
+	}
+	return track, nil
+}
+
+// splitFields splits line on spaces, keeping double quoted parts together.
+func splitFields(line string) []string {
+	fields := make([]string, 0)
+	var field strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && quoted == false:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+func parseValue(value string) interface{} {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// ReadScript reads commands from a JSON array, or from one command per line.
+func ReadScript(reader io.Reader) ([]Command, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var commands []Command
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		err := json.Unmarshal(trimmed, &commands)
+		return commands, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		command, err := ParseCommand(line)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+	return commands, scanner.Err()
+}
+
+// RunScript runs commands in order, waiting for their After delay, until ctx is done.
+func (p *FakePlayer) RunScript(ctx context.Context, commands []Command) error {
+	for _, command := range commands {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.done:
+			return nil
+		case <-time.After(time.Duration(command.After)):
+		}
+		if err := p.Run(command); err != nil {
+			return fmt.Errorf("%s: %w", command.Action, err)
+		}
+	}
+	return nil
+}
+
+// ReadCommands runs the commands read from reader, one per line, until the end of reader.
+// Invalid commands are reported to errors and skipped.
+func (p *FakePlayer) ReadCommands(reader io.Reader, errors io.Writer) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		command, err := ParseCommand(line)
+		if err == nil {
+			err = p.Run(command)
+		}
+		if err != nil {
+			fmt.Fprintf(errors, "ERROR::%s %s\n", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Run executes command.
+func (p *FakePlayer) Run(command Command) error {
+	switch command.Action {
+	case "play":
+		p.setPlaybackStatus(mpris.PlaybackPlaying)
+	case "pause":
+		p.setPlaybackStatus(mpris.PlaybackPaused)
+	case "play-pause":
+		p.lock.Lock()
+		playing := p.state.PlaybackStatus == mpris.PlaybackPlaying
+		p.lock.Unlock()
+		if playing {
+			p.setPlaybackStatus(mpris.PlaybackPaused)
+		} else {
+			p.setPlaybackStatus(mpris.PlaybackPlaying)
+		}
+	case "stop":
+		p.setPlaybackStatus(mpris.PlaybackStopped)
+	case "next":
+		p.skip(1, false)
+	case "previous":
+		p.skip(-1, false)
+	case "seek":
+		offset, err := commandDuration(command.Value)
+		if err != nil {
+			return err
+		}
+		p.seekTo(p.position() + offset)
+	case "position":
+		position, err := commandDuration(command.Value)
+		if err != nil {
+			return err
+		}
+		p.seekTo(position)
+	case "set":
+		if err := p.set(command.Property, command.Value); err != nil {
+			return err
+		}
+		p.publish()
+	case "metadata":
+		if command.Track == nil {
+			return fmt.Errorf("metadata needs a track")
+		}
+		p.lock.Lock()
+		if len(p.tracks) == 0 {
+			p.setTracks([]Track{*command.Track})
+		} else {
+			p.tracks[p.current] = command.Track.metadata(p.tracks[p.current].TrackId)
+			p.state.Metadata = p.tracks[p.current]
+		}
+		p.syncClock()
+		p.scheduleEnd()
+		p.lock.Unlock()
+		p.publish()
+	case "tracks":
+		p.lock.Lock()
+		p.setTracks(command.Tracks)
+		p.checkPoint = time.Now()
+		p.scheduleEnd()
+		currentTrack := p.state.Metadata.TrackId
+		p.lock.Unlock()
+		p.publish()
+		return p.server.TrackListReplaced(currentTrack)
+	case "vanish", "quit":
+		return p.Close()
+	default:
+		return fmt.Errorf("unknown action %q", command.Action)
+	}
+	return nil
+}
+
+func (p *FakePlayer) set(property string, value interface{}) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if field, isCapability := parseCapability(property); isCapability {
+		enabled, ok := value.(bool)
+		if ok == false {
+			return fmt.Errorf("%s expects a boolean", property)
+		}
+		*field(&p.state.Capabilities) = enabled
+		return nil
+	}
+
+	switch property {
+	case mpris.FieldPlaybackStatus:
+		p.syncClock()
+		p.state.PlaybackStatus = mpris.PlaybackStatus(fmt.Sprint(value))
+		p.scheduleEnd()
+	case mpris.FieldLoopStatus:
+		status := mpris.LoopStatus(fmt.Sprint(value))
+		if status.IsValid() == false {
+			return fmt.Errorf("invalid loop status %q", value)
+		}
+		p.state.LoopStatus = status
+	case mpris.FieldShuffle:
+		shuffle, ok := value.(bool)
+		if ok == false {
+			return fmt.Errorf("%s expects a boolean", property)
+		}
+		p.state.Shuffle = shuffle
+	case mpris.FieldVolume, mpris.FieldRate:
+		number, ok := value.(float64)
+		if ok == false {
+			return fmt.Errorf("%s expects a number", property)
+		}
+		if property == mpris.FieldVolume {
+			p.state.Volume = number
+		} else {
+			p.syncClock()
+			p.state.Rate = number
+			p.scheduleEnd()
+		}
+	default:
+		return fmt.Errorf("unknown property %q", property)
+	}
+	return nil
+}
+
+func commandDuration(value interface{}) (time.Duration, error) {
+	switch value := value.(type) {
+	case float64:
+		return time.Duration(value * float64(time.Second)), nil
+	case string:
+		return time.ParseDuration(value)
+	default:
+		return 0, fmt.Errorf("invalid duration %v", value)
+	}
+}
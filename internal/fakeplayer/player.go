@@ -0,0 +1,517 @@
+// Package fakeplayer implements a scriptable MPRIS player with a simulated playback clock.
+package fakeplayer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/internal/mprisserver"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Track describes a track of the fake player.
+type Track struct {
+	Title  string   `json:"title,omitempty"`
+	Artist []string `json:"artist,omitempty"`
+	Album  string   `json:"album,omitempty"`
+	Length Duration `json:"length,omitempty"`
+	Url    string   `json:"url,omitempty"`
+	ArtUrl string   `json:"art_url,omitempty"`
+}
+
+func (t Track) metadata(trackId string) mpris.Metadata {
+	return mpris.Metadata{
+		TrackId: trackId,
+		Title:   t.Title,
+		Artist:  t.Artist,
+		Album:   t.Album,
+		Length:  time.Duration(t.Length),
+		Url:     t.Url,
+		ArtUrl:  t.ArtUrl,
+	}
+}
+
+// Options configures a fake player.
+type Options struct {
+	Name         string
+	Identity     string
+	DesktopEntry string
+	Tracks       []Track
+	Capabilities mpris.Capabilities
+	Playing      bool
+	Volume       float64
+	// FixedVolume rejects the volume changes, like players without volume control.
+	FixedVolume bool
+	Rate        float64
+	LoopStatus  mpris.LoopStatus
+	Shuffle     bool
+	// TrackList exports org.mpris.MediaPlayer2.TrackList.
+	TrackList bool
+	// PlayerctldPlayers, when set, exports the interface of playerctld listing these players,
+	// the first one being the player playerctld fronts.
+	PlayerctldPlayers []string
+	// Output receives a line for every call made by D-Bus clients.
+	Output io.Writer
+}
+
+// FakePlayer is a player exported on the bus, whose position advances with time while playing.
+type FakePlayer struct {
+	lock       sync.Mutex
+	options    Options
+	server     *mprisserver.Server
+	state      mpris.PlayerState
+	tracks     []mpris.Metadata
+	current    int
+	checkPoint time.Time
+	endTimer   *time.Timer
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// Start exports a fake player on conn.
+func Start(conn *dbus.Conn, options Options) (*FakePlayer, error) {
+	if options.Output == nil {
+		options.Output = io.Discard
+	}
+	if len(options.Tracks) == 0 {
+		options.Tracks = []Track{{Title: "Track 1", Artist: []string{"Artist"}, Album: "Album", Length: Duration(3 * time.Minute)}}
+	}
+	if options.Rate == 0 {
+		options.Rate = 1
+	}
+	if options.LoopStatus == "" {
+		options.LoopStatus = mpris.LoopStatusNone
+	}
+
+	player := &FakePlayer{
+		options:    options,
+		checkPoint: time.Now(),
+		done:       make(chan struct{}),
+	}
+	player.state = mpris.PlayerState{
+		Name:           options.Name,
+		BusName:        mpris.BusName(options.Name),
+		PlaybackStatus: mpris.PlaybackStopped,
+		LoopStatus:     options.LoopStatus,
+		Shuffle:        options.Shuffle,
+		Volume:         options.Volume,
+		Rate:           options.Rate,
+		MinimumRate:    0.25,
+		MaximumRate:    4,
+		Capabilities:   options.Capabilities,
+	}
+	player.setTracks(options.Tracks)
+
+	config := mprisserver.Config{
+		Name: options.Name,
+		Identity: mprisserver.Identity{
+			Identity:     options.Identity,
+			DesktopEntry: options.DesktopEntry,
+			CanQuit:      true,
+		},
+		Player:   player,
+		Position: player.position,
+	}
+	if options.TrackList {
+		config.TrackList = player
+	}
+	if len(options.PlayerctldPlayers) > 0 {
+		busNames := make([]string, 0, len(options.PlayerctldPlayers))
+		for _, name := range options.PlayerctldPlayers {
+			busNames = append(busNames, mpris.BusName(name))
+		}
+		config.ExtraProperties = map[string]map[string]interface{}{
+			mpris.PlayerctldInterface: {"PlayerNames": busNames},
+		}
+	}
+	server, err := mprisserver.Export(conn, config, &player.state)
+	if err != nil {
+		return nil, err
+	}
+	player.server = server
+
+	if options.Playing {
+		player.Play()
+	}
+	return player, nil
+}
+
+// Done is closed once the player quits.
+func (p *FakePlayer) Done() <-chan struct{} {
+	return p.done
+}
+
+// Close takes the player off the bus.
+func (p *FakePlayer) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		p.lock.Lock()
+		p.stopEndTimer()
+		p.lock.Unlock()
+		err = p.server.Close()
+		close(p.done)
+	})
+	return err
+}
+
+func (p *FakePlayer) log(format string, args ...interface{}) {
+	fmt.Fprintf(p.options.Output, format+"\n", args...)
+}
+
+func (p *FakePlayer) setTracks(tracks []Track) {
+	p.tracks = make([]mpris.Metadata, 0, len(tracks))
+	for i, track := range tracks {
+		p.tracks = append(p.tracks, track.metadata(fmt.Sprintf("/org/mprisctl/fakeplayer/track/%d", i+1)))
+	}
+	p.current = 0
+	p.state.Position = 0
+	p.state.Metadata = mpris.Metadata{}
+	if len(p.tracks) > 0 {
+		p.state.Metadata = p.tracks[0]
+	}
+}
+
+// position returns the position of the simulated clock.
+func (p *FakePlayer) position() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.positionLocked()
+}
+
+func (p *FakePlayer) positionLocked() time.Duration {
+	position := p.state.Position
+	if p.state.PlaybackStatus == mpris.PlaybackPlaying {
+		position += time.Duration(float64(time.Since(p.checkPoint)) * p.state.Rate)
+	}
+	if length := p.state.Metadata.Length; length > 0 && position > length {
+		position = length
+	}
+	return position
+}
+
+// syncClock stores the position of the simulated clock, before the clock changes.
+func (p *FakePlayer) syncClock() {
+	p.state.Position = p.positionLocked()
+	p.checkPoint = time.Now()
+}
+
+func (p *FakePlayer) stopEndTimer() {
+	if p.endTimer != nil {
+		p.endTimer.Stop()
+		p.endTimer = nil
+	}
+}
+
+// scheduleEnd arms a timer firing when the current track ends.
+func (p *FakePlayer) scheduleEnd() {
+	p.stopEndTimer()
+	length := p.state.Metadata.Length
+	if p.state.PlaybackStatus != mpris.PlaybackPlaying || length <= 0 {
+		return
+	}
+	remaining := time.Duration(float64(length-p.state.Position) / p.state.Rate)
+	p.endTimer = time.AfterFunc(remaining, p.onTrackEnd)
+}
+
+func (p *FakePlayer) onTrackEnd() {
+	p.lock.Lock()
+	p.syncClock()
+	if p.state.LoopStatus == mpris.LoopStatusTrack {
+		p.state.Position = 0
+		p.scheduleEnd()
+		p.lock.Unlock()
+		p.server.Seeked(0)
+		return
+	}
+	p.lock.Unlock()
+	p.skip(1, true)
+}
+
+// publish sends the current state to the bus. It must be called without holding the lock.
+func (p *FakePlayer) publish() {
+	p.lock.Lock()
+	state := p.state
+	state.Position = p.positionLocked()
+	p.lock.Unlock()
+	p.server.Update(&state)
+}
+
+func (p *FakePlayer) setPlaybackStatus(status mpris.PlaybackStatus) {
+	p.lock.Lock()
+	p.syncClock()
+	p.state.PlaybackStatus = status
+	if status == mpris.PlaybackStopped {
+		p.state.Position = 0
+	}
+	p.scheduleEnd()
+	p.lock.Unlock()
+	p.publish()
+}
+
+// skip moves offset tracks forward or backward, stopping at the end of the list unless looping.
+func (p *FakePlayer) skip(offset int, automatic bool) {
+	p.lock.Lock()
+	if len(p.tracks) == 0 {
+		p.lock.Unlock()
+		return
+	}
+	index := p.current + offset
+	if index < 0 || index >= len(p.tracks) {
+		if p.state.LoopStatus != mpris.LoopStatusPlaylist {
+			p.lock.Unlock()
+			if automatic || index >= len(p.tracks) {
+				p.setPlaybackStatus(mpris.PlaybackStopped)
+			}
+			return
+		}
+		index = (index + len(p.tracks)) % len(p.tracks)
+	}
+	p.goTo(index)
+	p.lock.Unlock()
+	p.publish()
+}
+
+func (p *FakePlayer) goTo(index int) {
+	p.current = index
+	p.state.Metadata = p.tracks[index]
+	p.state.Position = 0
+	p.checkPoint = time.Now()
+	p.scheduleEnd()
+}
+
+func (p *FakePlayer) seekTo(position time.Duration) {
+	p.lock.Lock()
+	if position < 0 {
+		position = 0
+	}
+	if length := p.state.Metadata.Length; length > 0 && position > length {
+		p.lock.Unlock()
+		p.skip(1, true)
+		return
+	}
+	p.state.Position = position
+	p.checkPoint = time.Now()
+	p.scheduleEnd()
+	p.lock.Unlock()
+	p.server.Seeked(position)
+}
+
+func (p *FakePlayer) can(capability bool) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.state.CanControl && capability
+}
+
+func (p *FakePlayer) capabilities() mpris.Capabilities {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.state.Capabilities
+}
+
+func (p *FakePlayer) Raise() error {
+	p.log("CALL::Raise")
+	return mprisserver.ErrNotSupported
+}
+
+func (p *FakePlayer) Quit() error {
+	p.log("CALL::Quit")
+	go p.Close()
+	return nil
+}
+
+func (p *FakePlayer) Next() error {
+	p.log("CALL::Next")
+	if p.can(p.capabilities().CanGoNext) {
+		p.skip(1, false)
+	}
+	return nil
+}
+
+func (p *FakePlayer) Previous() error {
+	p.log("CALL::Previous")
+	if p.can(p.capabilities().CanGoPrevious) {
+		p.skip(-1, false)
+	}
+	return nil
+}
+
+func (p *FakePlayer) Pause() error {
+	p.log("CALL::Pause")
+	if p.can(p.capabilities().CanPause) {
+		p.setPlaybackStatus(mpris.PlaybackPaused)
+	}
+	return nil
+}
+
+func (p *FakePlayer) PlayPause() error {
+	p.log("CALL::PlayPause")
+	p.lock.Lock()
+	playing := p.state.PlaybackStatus == mpris.PlaybackPlaying
+	p.lock.Unlock()
+	if playing && p.can(p.capabilities().CanPause) {
+		p.setPlaybackStatus(mpris.PlaybackPaused)
+	} else if playing == false && p.can(p.capabilities().CanPlay) {
+		p.setPlaybackStatus(mpris.PlaybackPlaying)
+	}
+	return nil
+}
+
+func (p *FakePlayer) Stop() error {
+	p.log("CALL::Stop")
+	if p.can(true) {
+		p.setPlaybackStatus(mpris.PlaybackStopped)
+	}
+	return nil
+}
+
+func (p *FakePlayer) Play() error {
+	p.log("CALL::Play")
+	if p.can(p.capabilities().CanPlay) {
+		p.setPlaybackStatus(mpris.PlaybackPlaying)
+	}
+	return nil
+}
+
+func (p *FakePlayer) Seek(offset time.Duration) error {
+	p.log("CALL::Seek offset=%d", offset.Microseconds())
+	if p.can(p.capabilities().CanSeek) {
+		p.seekTo(p.position() + offset)
+	}
+	return nil
+}
+
+func (p *FakePlayer) SetPosition(trackId string, position time.Duration) error {
+	p.log("CALL::SetPosition track_id=%s position=%d", trackId, position.Microseconds())
+	p.lock.Lock()
+	currentTrack := p.state.Metadata.TrackId == trackId
+	length := p.state.Metadata.Length
+	p.lock.Unlock()
+	// Unlike Seek, positions past the end of the track are ignored.
+	if currentTrack && position >= 0 && (length == 0 || position <= length) && p.can(p.capabilities().CanSeek) {
+		p.seekTo(position)
+	}
+	return nil
+}
+
+func (p *FakePlayer) OpenUri(uri string) error {
+	p.log("CALL::OpenUri uri=%s", uri)
+	return mprisserver.ErrNotSupported
+}
+
+func (p *FakePlayer) SetLoopStatus(value mpris.LoopStatus) error {
+	p.log("CALL::Set LoopStatus=%s", value)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state.LoopStatus = value
+	return nil
+}
+
+func (p *FakePlayer) SetShuffle(value bool) error {
+	p.log("CALL::Set Shuffle=%t", value)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state.Shuffle = value
+	return nil
+}
+
+func (p *FakePlayer) SetVolume(value float64) error {
+	p.log("CALL::Set Volume=%v", value)
+	if p.options.FixedVolume {
+		return mprisserver.ErrNotSupported
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state.Volume = value
+	return nil
+}
+
+func (p *FakePlayer) SetRate(value float64) error {
+	p.log("CALL::Set Rate=%v", value)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if value < p.state.MinimumRate || value > p.state.MaximumRate {
+		return fmt.Errorf("rate must be between %v and %v", p.state.MinimumRate, p.state.MaximumRate)
+	}
+	p.syncClock()
+	p.state.Rate = value
+	p.scheduleEnd()
+	return nil
+}
+
+// Tracks implements mprisserver.TrackList.
+func (p *FakePlayer) Tracks() []mpris.Metadata {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]mpris.Metadata(nil), p.tracks...)
+}
+
+func (p *FakePlayer) CanEditTracks() bool {
+	return false
+}
+
+func (p *FakePlayer) AddTrack(uri string, afterTrack string, setAsCurrent bool) error {
+	p.log("CALL::AddTrack uri=%s after_track=%s set_as_current=%t", uri, afterTrack, setAsCurrent)
+	return mprisserver.ErrNotSupported
+}
+
+func (p *FakePlayer) RemoveTrack(trackId string) error {
+	p.log("CALL::RemoveTrack track_id=%s", trackId)
+	return mprisserver.ErrNotSupported
+}
+
+func (p *FakePlayer) GoTo(trackId string) error {
+	p.log("CALL::GoTo track_id=%s", trackId)
+	p.lock.Lock()
+	for i, track := range p.tracks {
+		if track.TrackId == trackId {
+			p.goTo(i)
+			p.lock.Unlock()
+			p.publish()
+			return nil
+		}
+	}
+	p.lock.Unlock()
+	return fmt.Errorf("unknown track %s", trackId)
+}
+
+// parseCapability accepts names like "go-next", "can-go-next" or "CanGoNext".
+func parseCapability(name string) (func(c *mpris.Capabilities) *bool, bool) {
+	fields := map[string]func(c *mpris.Capabilities) *bool{
+		"control":    func(c *mpris.Capabilities) *bool { return &c.CanControl },
+		"gonext":     func(c *mpris.Capabilities) *bool { return &c.CanGoNext },
+		"goprevious": func(c *mpris.Capabilities) *bool { return &c.CanGoPrevious },
+		"pause":      func(c *mpris.Capabilities) *bool { return &c.CanPause },
+		"play":       func(c *mpris.Capabilities) *bool { return &c.CanPlay },
+		"seek":       func(c *mpris.Capabilities) *bool { return &c.CanSeek },
+	}
+	normalized := strings.ToLower(strings.ReplaceAll(name, "-", ""))
+	field, ok := fields[strings.TrimPrefix(normalized, "can")]
+	return field, ok
+}
+
+// Capabilities lists the names accepted by ParseCapabilities.
+var Capabilities = []string{"control", "go-next", "go-previous", "pause", "play", "seek"}
+
+// ParseCapabilities returns every capability enabled, except the ones named in disabled.
+func ParseCapabilities(disabled []string) (mpris.Capabilities, error) {
+	capabilities := mpris.Capabilities{
+		CanControl:    true,
+		CanGoNext:     true,
+		CanGoPrevious: true,
+		CanPause:      true,
+		CanPlay:       true,
+		CanSeek:       true,
+	}
+	for _, name := range disabled {
+		field, ok := parseCapability(name)
+		if ok == false {
+			return capabilities, fmt.Errorf("unknown capability %q, must be one of %s", name, strings.Join(Capabilities, ", "))
+		}
+		*field(&capabilities) = false
+	}
+	return capabilities, nil
+}
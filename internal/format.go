@@ -0,0 +1,89 @@
+package mprisctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/internal/config"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// output is how list and watch print the players: config.FormatLines, the default,
+// config.FormatJSON or config.FormatTemplate.
+var output = struct {
+	format   string
+	template *template.Template
+}{format: config.FormatLines}
+
+// templateFuncs are the functions available in the templates, besides the ones of text/template.
+var templateFuncs = template.FuncMap{
+	"join": func(separator string, values []string) string { return strings.Join(values, separator) },
+	// duration formats microseconds, like the fields position and length.
+	"duration": func(microseconds int64) string {
+		return convertToDuration(time.Duration(microseconds) * time.Microsecond)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// templateData is given to the templates: the event type, empty in list, and the player,
+// with the fields of the socket of the daemon, like .Metadata.Title and .PlaybackStatus.
+type templateData struct {
+	Event string
+	daemon.PlayerInfo
+}
+
+// SetOutput sets the output format of list and watch. The template of config.FormatTemplate
+// is either the name of one of templates, or the text of a Go template.
+func SetOutput(format string, text string, templates map[string]string) error {
+	output.format = format
+	output.template = nil
+	if format != config.FormatTemplate {
+		return nil
+	}
+	name := "template"
+	if named, found := templates[text]; found {
+		name, text = text, named
+	}
+	parsed, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+	output.template = parsed
+	return nil
+}
+
+// printFormatted prints player, with the event type unless it is 0, when the format is not config.FormatLines.
+// It returns false when the lines format is used, for the caller to print them.
+func printFormatted(eventType mpris.EventType, player *mpris.PlayerState) bool {
+	info := daemon.NewPlayerInfo(player)
+	switch output.format {
+	case config.FormatJSON:
+		var data []byte
+		if eventType == 0 {
+			data, _ = json.Marshal(info)
+		} else {
+			data, _ = json.Marshal(daemon.EventInfo{Type: eventType.String(), Player: info})
+		}
+		fmt.Println(string(data))
+	case config.FormatTemplate:
+		data := templateData{PlayerInfo: info}
+		if eventType != 0 {
+			data.Event = eventType.String()
+		}
+		var text strings.Builder
+		if err := output.template.Execute(&text, data); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return true
+		}
+		fmt.Println(strings.TrimSuffix(text.String(), "\n"))
+	default:
+		return false
+	}
+	return true
+}
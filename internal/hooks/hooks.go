@@ -0,0 +1,342 @@
+// Package hooks runs shell commands on the events of the players.
+//
+// The commands are run with sh -c. The event is given to them as environment variables,
+// MPRIS_EVENT, MPRIS_PLAYER, MPRIS_TITLE, MPRIS_STATUS..., and as JSON on their standard input,
+// as the daemon sends it to its subscribers.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Default options of a Runner.
+const (
+	DefaultConcurrency = 4
+	DefaultTimeout     = 30 * time.Second
+)
+
+// QueueSize bounds the runs waiting for their turn. When the queue is full, a new run replaces the last
+// one of the same hook and player waiting, if any, and is dropped otherwise.
+const QueueSize = 64
+
+// matchers are the events hooks can be run on, besides the types of the events:
+// shorthands of the playback changes and of the connections of the players.
+var matchers = map[string]func(event mpris.Event) bool{
+	"play":       playbackMatcher(mpris.PlaybackPlaying),
+	"pause":      playbackMatcher(mpris.PlaybackPaused),
+	"stop":       playbackMatcher(mpris.PlaybackStopped),
+	"connect":    typeMatcher(mpris.EventPlayerAppeared),
+	"disconnect": typeMatcher(mpris.EventPlayerVanished),
+	"track":      typeMatcher(mpris.EventTrackChanged),
+	"*":          func(event mpris.Event) bool { return event.Type != mpris.EventPosition },
+}
+
+func typeMatcher(eventType mpris.EventType) func(event mpris.Event) bool {
+	return func(event mpris.Event) bool { return event.Type == eventType }
+}
+
+func playbackMatcher(status mpris.PlaybackStatus) func(event mpris.Event) bool {
+	return func(event mpris.Event) bool {
+		return event.Type == mpris.EventPlaybackChanged && event.Player.PlaybackStatus == status
+	}
+}
+
+// Events returns the names of the events hooks can be run on.
+func Events() []string {
+	names := make([]string, 0, len(matchers))
+	for name := range matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hook is a command run on some events.
+type Hook struct {
+	// Events are the events running the command: types of events, like track-changed,
+	// or play, pause, stop, connect, disconnect and track, "*" being every event but the positions.
+	Events []string
+	// Players, when not empty, restricts the hook to these players.
+	Players []string
+	Command string
+	// Debounce, when not 0, delays the command until no other event came for this long,
+	// running it once for the last event. It is counted for each player.
+	Debounce time.Duration
+	// Timeout, when not 0, replaces the timeout of the Runner.
+	Timeout time.Duration
+}
+
+// ParseHook parses a hook given as EVENT[,EVENT...]=COMMAND.
+func ParseHook(value string) (Hook, error) {
+	events, command, found := strings.Cut(value, "=")
+	if found == false || strings.TrimSpace(command) == "" {
+		return Hook{}, fmt.Errorf("invalid hook %q: expecting EVENT=COMMAND", value)
+	}
+	hook := Hook{Events: strings.Split(events, ","), Command: command}
+	return hook, hook.Validate()
+}
+
+// Validate checks the events of the hook.
+func (h Hook) Validate() error {
+	if len(h.Events) == 0 || strings.TrimSpace(h.Command) == "" {
+		return fmt.Errorf("invalid hook: expecting events and a command")
+	}
+	for _, name := range h.Events {
+		if _, found := matchers[name]; found {
+			continue
+		}
+		if _, err := mpris.ParseEventType(name); err != nil {
+			return fmt.Errorf("invalid hook event %q: expecting one of %s, or an event type", name, strings.Join(Events(), ", "))
+		}
+	}
+	return nil
+}
+
+func (h Hook) matches(event mpris.Event) bool {
+	if len(h.Players) > 0 && (mpris.Filter{Players: h.Players}).Match(event) == false {
+		return false
+	}
+	for _, name := range h.Events {
+		if matcher, found := matchers[name]; found {
+			if matcher(event) {
+				return true
+			}
+		} else if eventType, _ := mpris.ParseEventType(name); eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures a Runner.
+type Options struct {
+	// Concurrency is the number of commands run at once, the other ones waiting for their turn in a queue
+	// of QueueSize runs.
+	Concurrency int
+	// Timeout is the time after which a command is killed.
+	Timeout time.Duration
+}
+
+// Runner runs hooks on events, with a fixed number of workers taking the runs from a bounded queue.
+type Runner struct {
+	hooks   []Hook
+	timeout time.Duration
+	workers sync.WaitGroup
+
+	lock sync.Mutex
+	// queued signals the workers that queue has runs, or that the runner is closed.
+	queued *sync.Cond
+	queue  []queuedRun
+	// pending are the debounced runs, by hook and player.
+	pending map[pendingKey]*pendingRun
+	closed  bool
+}
+
+// queuedRun is a run of a hook waiting for a worker.
+type queuedRun struct {
+	hook  Hook
+	index int
+	event mpris.Event
+}
+
+type pendingKey struct {
+	hook   int
+	player string
+}
+
+type pendingRun struct {
+	timer *time.Timer
+	event mpris.Event
+}
+
+// NewRunner returns a runner of hooks, running until closed.
+func NewRunner(hooks []Hook, options Options) *Runner {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	r := &Runner{
+		hooks:   hooks,
+		timeout: options.Timeout,
+		queue:   make([]queuedRun, 0, QueueSize),
+		pending: make(map[pendingKey]*pendingRun),
+	}
+	r.queued = sync.NewCond(&r.lock)
+	r.workers.Add(options.Concurrency)
+	for i := 0; i < options.Concurrency; i++ {
+		go r.work()
+	}
+	return r
+}
+
+// Handle runs the hooks matching event, after their debounce delay.
+func (r *Runner) Handle(event mpris.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return
+	}
+	for i, hook := range r.hooks {
+		if hook.matches(event) == false {
+			continue
+		}
+		if hook.Debounce <= 0 {
+			r.enqueue(hook, i, event)
+			continue
+		}
+
+		hook := hook
+		key := pendingKey{hook: i, player: event.Player.Owner}
+		if run, found := r.pending[key]; found {
+			run.event = event
+			run.timer.Reset(hook.Debounce)
+			continue
+		}
+		run := &pendingRun{event: event}
+		run.timer = time.AfterFunc(hook.Debounce, func() {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			if r.pending[key] != run || r.closed {
+				return
+			}
+			delete(r.pending, key)
+			r.enqueue(hook, key.hook, run.event)
+		})
+		r.pending[key] = run
+	}
+}
+
+// Close drops the debounced runs, and waits for the commands running or waiting for their turn.
+func (r *Runner) Close() {
+	r.lock.Lock()
+	r.closed = true
+	for key, run := range r.pending {
+		run.timer.Stop()
+		delete(r.pending, key)
+	}
+	r.queued.Broadcast()
+	r.lock.Unlock()
+	r.workers.Wait()
+}
+
+// enqueue queues a run of hook, the hook at index in r.hooks. r.lock must be held.
+func (r *Runner) enqueue(hook Hook, index int, event mpris.Event) {
+	if len(r.queue) >= QueueSize {
+		for i := len(r.queue) - 1; i >= 0; i-- {
+			if r.queue[i].index == index && r.queue[i].event.Player.Owner == event.Player.Owner {
+				r.queue[i].event = event
+				return
+			}
+		}
+		fmt.Println(fmt.Sprintf("HOOK::dropped event=%s player=%s command=%s",
+			event.Type, daemon.NewPlayerInfo(&event.Player).Player, strconv.Quote(hook.Command)))
+		return
+	}
+	r.queue = append(r.queue, queuedRun{hook: hook, index: index, event: event})
+	r.queued.Signal()
+}
+
+// work runs the queued hooks, until the runner is closed and the queue empty.
+func (r *Runner) work() {
+	defer r.workers.Done()
+	for {
+		r.lock.Lock()
+		for len(r.queue) == 0 && r.closed == false {
+			r.queued.Wait()
+		}
+		if len(r.queue) == 0 {
+			r.lock.Unlock()
+			return
+		}
+		next := r.queue[0]
+		r.queue = append(r.queue[:0], r.queue[1:]...)
+		r.lock.Unlock()
+
+		timeout := r.timeout
+		if next.hook.Timeout > 0 {
+			timeout = next.hook.Timeout
+		}
+		if err := run(next.hook.Command, next.event, timeout); err != nil {
+			fmt.Println(fmt.Sprintf("HOOK::failed event=%s player=%s command=%s error=%s",
+				next.event.Type, daemon.NewPlayerInfo(&next.event.Player).Player, strconv.Quote(next.hook.Command), err))
+		}
+	}
+}
+
+// run runs command for event, killing it along with its children after timeout.
+func run(command string, event mpris.Event, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	input, err := json.Marshal(daemon.NewEventInfo(event))
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), Environment(event)...)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	// The output of the hooks is kept apart from the events printed.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// Environment returns the variables describing event to a command, as KEY=value.
+func Environment(event mpris.Event) []string {
+	info := daemon.NewPlayerInfo(&event.Player)
+	metadata := info.Metadata
+	variables := []struct{ name, value string }{
+		{"EVENT", event.Type.String()},
+		{"PLAYER", info.Player},
+		{"PLAYER_NAME", info.Name},
+		{"BUS", info.Bus},
+		{"IDENTITY", info.Identity},
+		{"DISPLAY_NAME", info.DisplayName},
+		{"ICON", info.Icon},
+		{"ICON_PATH", info.IconPath},
+		{"STATUS", info.PlaybackStatus},
+		{"LOOP", info.LoopStatus},
+		{"SHUFFLE", strconv.FormatBool(info.Shuffle)},
+		{"VOLUME", strconv.FormatFloat(info.Volume, 'f', -1, 64)},
+		{"RATE", strconv.FormatFloat(info.Rate, 'f', -1, 64)},
+		{"POSITION", strconv.FormatInt(info.Position, 10)},
+		{"TRACK_ID", metadata.TrackId},
+		{"TITLE", metadata.Title},
+		{"ARTIST", strings.Join(metadata.Artist, ", ")},
+		{"ALBUM", metadata.Album},
+		{"ALBUM_ARTIST", strings.Join(metadata.AlbumArtist, ", ")},
+		{"LENGTH", strconv.FormatInt(metadata.Length, 10)},
+		{"URL", metadata.Url},
+		{"ART_URL", metadata.ArtUrl},
+	}
+
+	environment := make([]string, 0, len(variables))
+	for _, variable := range variables {
+		environment = append(environment, "MPRIS_"+variable.name+"="+variable.value)
+	}
+	return environment
+}
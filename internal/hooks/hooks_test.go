@@ -0,0 +1,254 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+func trackEvent(owner string, title string) mpris.Event {
+	player := mpris.PlayerState{Name: "vlc", Owner: owner}
+	player.Metadata.Title = title
+	return mpris.Event{Type: mpris.EventTrackChanged, Player: player}
+}
+
+func TestRunnerQueueIsBounded(t *testing.T) {
+	dir := t.TempDir()
+	gate := filepath.Join(dir, "gate")
+	output := filepath.Join(dir, "output")
+	t.Setenv("GATE", gate)
+	t.Setenv("OUTPUT", output)
+	// The commands wait for the gate, for the events to pile up.
+	command := `while [ ! -e "$GATE" ]; do sleep 0.01; done; echo "$MPRIS_TITLE" >> "$OUTPUT"`
+	runner := NewRunner([]Hook{{Events: []string{"track"}, Command: command}}, Options{Concurrency: 1})
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 1000; i++ {
+		runner.Handle(trackEvent(":1.1", strconv.Itoa(i)))
+	}
+	if started := runtime.NumGoroutine() - goroutines; started > 0 {
+		t.Errorf("got %d goroutines started by the events, want none", started)
+	}
+	if err := os.WriteFile(gate, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runner.Close()
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := strings.Fields(string(data))
+	if len(titles) > QueueSize+1 {
+		t.Errorf("got %d runs, want at most %d", len(titles), QueueSize+1)
+	}
+	// The last event replaced the last run waiting.
+	if last := titles[len(titles)-1]; last != "999" {
+		t.Errorf("got %s run last, want the last event", last)
+	}
+}
+
+// readLines waits for the file at path to have count lines, returning them.
+func readLines(t *testing.T, path string, count int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		lines := strings.Fields(string(data))
+		if len(lines) >= count || time.Now().After(deadline) {
+			return lines
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunnerDebouncesEachPlayer(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output")
+	t.Setenv("OUTPUT", output)
+	hook := Hook{Events: []string{"track"}, Command: `echo "$MPRIS_TITLE" >> "$OUTPUT"`, Debounce: 200 * time.Millisecond}
+	runner := NewRunner([]Hook{hook}, Options{})
+	defer runner.Close()
+
+	for _, event := range []mpris.Event{
+		trackEvent(":1.1", "a1"),
+		trackEvent(":1.2", "b1"),
+		trackEvent(":1.1", "a2"),
+		trackEvent(":1.1", "a3"),
+		trackEvent(":1.2", "b2"),
+	} {
+		runner.Handle(event)
+	}
+	readLines(t, output, 2)
+	// Long enough for the runs of the earlier events to show, if any.
+	time.Sleep(300 * time.Millisecond)
+	titles := readLines(t, output, 2)
+	sort.Strings(titles)
+	if reflect.DeepEqual(titles, []string{"a3", "b2"}) == false {
+		t.Errorf("got runs for %v, want one run for the last event of each player, [a3 b2]", titles)
+	}
+}
+
+func TestRunnerTimeoutKillsProcessGroup(t *testing.T) {
+	late := filepath.Join(t.TempDir(), "late")
+	t.Setenv("LATE", late)
+	// The command waits for a child, which the timeout must kill too.
+	hook := Hook{Events: []string{"track"}, Command: `(sleep 1; touch "$LATE") & wait`, Timeout: 100 * time.Millisecond}
+	runner := NewRunner([]Hook{hook}, Options{Timeout: time.Minute})
+
+	start := time.Now()
+	runner.Handle(trackEvent(":1.1", "title"))
+	runner.Close()
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("the hook ran for %s, want it killed after 100ms", elapsed)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(late); err == nil {
+		t.Error("the child of the hook ran to completion, want it killed with the hook")
+	}
+
+	if err := run("sleep 1", trackEvent(":1.1", "title"), 50*time.Millisecond); err == nil || err.Error() != "timed out after 50ms" {
+		t.Errorf("run(sleep 1) = %v, want timed out after 50ms", err)
+	}
+}
+
+func testEvent() mpris.Event {
+	player := mpris.PlayerState{
+		Name:           "vlc.instance_7",
+		Owner:          ":1.42",
+		Bus:            "system",
+		Identity:       "VLC media player",
+		PlaybackStatus: mpris.PlaybackPlaying,
+		LoopStatus:     mpris.LoopStatusPlaylist,
+		Shuffle:        true,
+		Volume:         0.75,
+		Rate:           1.5,
+		Position:       90 * time.Second,
+	}
+	player.Metadata = mpris.Metadata{
+		TrackId:     "/org/mpris/track/1",
+		Title:       "Title",
+		Artist:      []string{"First", "Second"},
+		Album:       "Album",
+		AlbumArtist: []string{"Band"},
+		Length:      3 * time.Minute,
+		Url:         "file:///music/title.ogg",
+		ArtUrl:      "file:///music/cover.png",
+	}
+	return mpris.Event{Type: mpris.EventTrackChanged, Player: player}
+}
+
+func TestEnvironment(t *testing.T) {
+	environment := make(map[string]string)
+	for _, variable := range Environment(testEvent()) {
+		name, value, _ := strings.Cut(variable, "=")
+		environment[name] = value
+	}
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"MPRIS_EVENT", "track-changed"},
+		{"MPRIS_PLAYER", "vlc.instance_7@system"},
+		{"MPRIS_PLAYER_NAME", "vlc.instance_7"},
+		{"MPRIS_BUS", "system"},
+		{"MPRIS_IDENTITY", "VLC media player"},
+		{"MPRIS_STATUS", "Playing"},
+		{"MPRIS_LOOP", "Playlist"},
+		{"MPRIS_SHUFFLE", "true"},
+		{"MPRIS_VOLUME", "0.75"},
+		{"MPRIS_RATE", "1.5"},
+		{"MPRIS_POSITION", "90000000"},
+		{"MPRIS_TRACK_ID", "/org/mpris/track/1"},
+		{"MPRIS_TITLE", "Title"},
+		{"MPRIS_ARTIST", "First, Second"},
+		{"MPRIS_ALBUM", "Album"},
+		{"MPRIS_ALBUM_ARTIST", "Band"},
+		{"MPRIS_LENGTH", "180000000"},
+		{"MPRIS_URL", "file:///music/title.ogg"},
+		{"MPRIS_ART_URL", "file:///music/cover.png"},
+	}
+	for _, test := range tests {
+		if value, found := environment[test.name]; found == false || value != test.value {
+			t.Errorf("Environment()[%s] = %q, want %q", test.name, value, test.value)
+		}
+	}
+	for _, name := range []string{"MPRIS_DISPLAY_NAME", "MPRIS_ICON", "MPRIS_ICON_PATH"} {
+		if _, found := environment[name]; found == false {
+			t.Errorf("Environment() has no %s", name)
+		}
+	}
+}
+
+func TestRunWritesTheEventOnStdin(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output")
+	t.Setenv("OUTPUT", output)
+	if err := run(`cat > "$OUTPUT"`, testEvent(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\n") != 1 || strings.HasSuffix(string(data), "\n") == false {
+		t.Errorf("got %q on stdin, want a single line of JSON", data)
+	}
+	var info daemon.EventInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(info, daemon.NewEventInfo(testEvent())) == false {
+		t.Errorf("got %+v on stdin, want %+v", info, daemon.NewEventInfo(testEvent()))
+	}
+}
+
+func TestHookMatches(t *testing.T) {
+	playback := func(status mpris.PlaybackStatus) mpris.Event {
+		return mpris.Event{Type: mpris.EventPlaybackChanged, Player: mpris.PlayerState{Name: "vlc", PlaybackStatus: status}}
+	}
+	event := func(eventType mpris.EventType) mpris.Event {
+		return mpris.Event{Type: eventType, Player: mpris.PlayerState{Name: "vlc"}}
+	}
+	tests := []struct {
+		events  []string
+		players []string
+		event   mpris.Event
+		matches bool
+	}{
+		{[]string{"play"}, nil, playback(mpris.PlaybackPlaying), true},
+		{[]string{"play"}, nil, playback(mpris.PlaybackPaused), false},
+		{[]string{"pause"}, nil, playback(mpris.PlaybackPaused), true},
+		{[]string{"pause"}, nil, playback(mpris.PlaybackStopped), false},
+		{[]string{"stop"}, nil, playback(mpris.PlaybackStopped), true},
+		{[]string{"connect"}, nil, event(mpris.EventPlayerAppeared), true},
+		{[]string{"connect"}, nil, event(mpris.EventPlayerVanished), false},
+		{[]string{"disconnect"}, nil, event(mpris.EventPlayerVanished), true},
+		{[]string{"track"}, nil, event(mpris.EventTrackChanged), true},
+		{[]string{"track"}, nil, playback(mpris.PlaybackPlaying), false},
+		{[]string{"pause", "track"}, nil, event(mpris.EventTrackChanged), true},
+		{[]string{"volume-changed"}, nil, event(mpris.EventVolumeChanged), true},
+		{[]string{"volume-changed"}, nil, event(mpris.EventSeeked), false},
+		{[]string{"*"}, nil, event(mpris.EventSeeked), true},
+		{[]string{"*"}, nil, playback(mpris.PlaybackPaused), true},
+		{[]string{"*"}, nil, event(mpris.EventPosition), false},
+		{[]string{"position"}, nil, event(mpris.EventPosition), true},
+		{[]string{"*"}, []string{"vlc"}, event(mpris.EventTrackChanged), true},
+		{[]string{"*"}, []string{"spotify"}, event(mpris.EventTrackChanged), false},
+	}
+	for _, test := range tests {
+		hook := Hook{Events: test.events, Players: test.players, Command: "true"}
+		if matches := hook.matches(test.event); matches != test.matches {
+			t.Errorf("Hook{Events: %v, Players: %v}.matches(%s %s) = %v, want %v",
+				test.events, test.players, test.event.Type, test.event.Player.PlaybackStatus, matches, test.matches)
+		}
+	}
+}
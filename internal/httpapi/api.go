@@ -0,0 +1,316 @@
+// Package httpapi serves the players of a bus over HTTP.
+//
+//	GET  /players                  the players
+//	GET  /players/{name}           the state of a player
+//	GET  /players/{name}/art       the art of the current track of a player
+//	POST /players/{name}/{action}  play, pause, play-pause, stop, next, previous, raise,
+//	                               seek and open-uri, the latter two taking {"value": ...}
+//	PUT  /players/{name}/{field}   position, volume, loop, shuffle and rate, taking {"value": ...}
+//	GET  /events                   the events, as Server-Sent Events
+//	GET  /ws                       the events, on a WebSocket
+//
+// Players and events are represented as on the socket of the daemon,
+// durations being in microseconds. Streams of events start with a snapshot of the players,
+// and can be filtered with the query parameters player and type, like ?player=vlc&type=track-changed.
+// Clients which cannot set headers, like EventSource, can give the token as the query parameter access_token.
+//
+// Without token, the API is only reachable from the machine, but the pages of any site open in a browser of
+// the machine could call it: requests must then be for a loopback host, like localhost:8765, not for a name
+// of another site resolving to the loopback address, come from the same origin or an allowed one, and carry
+// "Content-Type: application/json" when calling actions, which pages of other sites cannot send without CORS.
+//
+// The root serves a remote, a single page using the API from a browser, like the one of a phone.
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// maxBodySize bounds the request bodies, which hold a single value.
+const maxBodySize = 64 * 1024
+
+// putFields are the actions which set a property, called with PUT. The other ones are called with POST.
+var putFields = map[string]bool{
+	"position": true,
+	"volume":   true,
+	"loop":     true,
+	"shuffle":  true,
+	"rate":     true,
+}
+
+// Options configures the API.
+type Options struct {
+	// Token, when not empty, must be given by every request as a bearer token.
+	Token string
+	// CORSOrigins are the origins allowed to call the API from a browser, "*" allowing any.
+	CORSOrigins []string
+	// DisableUI disables the remote served at the root.
+	DisableUI bool
+}
+
+// API is the http.Handler of the API.
+// Its streams of events need Run to be running.
+type API struct {
+	client      *mpris.Client
+	options     Options
+	broadcaster *daemon.Broadcaster
+
+	lock sync.Mutex
+	// players are the players on the bus, by owner, for the snapshots.
+	players map[string]mpris.PlayerState
+}
+
+// New returns the API driving the players of client.
+func New(client *mpris.Client, options Options) *API {
+	return &API{
+		client:      client,
+		options:     options,
+		broadcaster: daemon.NewBroadcaster(),
+		players:     make(map[string]mpris.PlayerState),
+	}
+}
+
+// valueBody is the body of the requests taking a value.
+type valueBody struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// errorBody is the body of the error responses.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.cors(w, r) {
+		return
+	}
+	if status, err := a.guard(r); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	if a.serveWeb(w, r) {
+		return
+	}
+	if a.authorized(r) == false {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mprisctl"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(segments) == 1 && segments[0] == "events":
+		a.allow(w, r, a.streamEvents, http.MethodGet)
+	case len(segments) == 1 && segments[0] == "ws":
+		a.allow(w, r, a.streamWebSocket, http.MethodGet)
+	case len(segments) == 1 && segments[0] == "players":
+		a.allow(w, r, a.listPlayers, http.MethodGet)
+	case len(segments) == 2 && segments[0] == "players":
+		a.allow(w, r, func(w http.ResponseWriter, r *http.Request) { a.getPlayer(w, r, segments[1]) }, http.MethodGet)
+	case len(segments) == 3 && segments[0] == "players" && segments[2] == "art":
+		a.allow(w, r, func(w http.ResponseWriter, r *http.Request) { a.getArt(w, r, segments[1]) }, http.MethodGet)
+	case len(segments) == 3 && segments[0] == "players":
+		method := http.MethodPost
+		if putFields[segments[2]] {
+			method = http.MethodPut
+		}
+		a.allow(w, r, func(w http.ResponseWriter, r *http.Request) { a.callAction(w, r, segments[1], segments[2]) }, method)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint: %s", r.URL.Path))
+	}
+}
+
+// cors sets the CORS headers of the allowed origins, and answers preflight requests.
+// It returns true when the request was answered.
+func (a *API) cors(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	allowed := ""
+	for _, allowedOrigin := range a.options.CORSOrigins {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			allowed = allowedOrigin
+			break
+		}
+	}
+	if origin == "" || allowed == "" {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	if allowed != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowedOrigin accepts the requests of the same host, and of the CORS origins.
+func (a *API) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if originURL, err := url.Parse(origin); err == nil && strings.EqualFold(originURL.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range a.options.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// guard rejects the requests which may come from the pages of other sites, when the API has no token.
+// It returns the status of the response along with the error.
+func (a *API) guard(r *http.Request) (int, error) {
+	if a.options.Token != "" {
+		return 0, nil
+	}
+	if isLoopbackHost(r.Host) == false {
+		return http.StatusForbidden, fmt.Errorf("host %q not allowed without token", r.Host)
+	}
+	if a.allowedOrigin(r) == false {
+		return http.StatusForbidden, fmt.Errorf("origin %q not allowed", r.Header.Get("Origin"))
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("expecting Content-Type: application/json")
+		}
+	}
+	return 0, nil
+}
+
+// isLoopbackHost reports whether host, the Host header of a request, designates the loopback interface.
+func isLoopbackHost(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (a *API) authorized(r *http.Request) bool {
+	if a.options.Token == "" {
+		return true
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if found == false {
+		token, found = r.URL.Query().Get("access_token"), r.URL.Query().Has("access_token")
+	}
+	return found && subtle.ConstantTimeCompare([]byte(token), []byte(a.options.Token)) == 1
+}
+
+// allow calls handler when the request has one of methods, HEAD being allowed along with GET.
+func (a *API) allow(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc, methods ...string) {
+	for _, method := range methods {
+		if r.Method == method || (method == http.MethodGet && r.Method == http.MethodHead) {
+			handler(w, r)
+			return
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+}
+
+func (a *API) listPlayers(w http.ResponseWriter, r *http.Request) {
+	players, err := a.client.Players(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+	infos := make([]daemon.PlayerInfo, 0, len(players))
+	for _, player := range players {
+		infos = append(infos, daemon.NewPlayerInfo(player))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (a *API) getPlayer(w http.ResponseWriter, r *http.Request, playerName string) {
+	player, err := a.client.Player(r.Context(), playerName)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, daemon.NewPlayerInfo(player))
+}
+
+func (a *API) callAction(w http.ResponseWriter, r *http.Request, playerName string, actionName string) {
+	action, found := daemon.Actions[actionName]
+	if found == false {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such action: %s", actionName))
+		return
+	}
+
+	var body valueBody
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("body: %w", err))
+			return
+		}
+	}
+	if r.Method == http.MethodPut && body.Value == nil {
+		writeError(w, http.StatusBadRequest, errors.New(`body: missing "value"`))
+		return
+	}
+
+	if err := action(r.Context(), a.client, playerName, body.Value); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// statusOf returns the status of the response to a request which failed with err.
+func statusOf(err error) int {
+	var rpcErr *daemon.Error
+	switch {
+	case errors.Is(err, mpris.ErrPlayerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, mpris.ErrInvalidValue):
+		return http.StatusBadRequest
+	case errors.As(err, &rpcErr) && rpcErr.Code == daemon.CodeInvalidParams:
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,157 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris/mpristest"
+)
+
+func newTestAPI(options Options) (*API, *mpristest.Player) {
+	bus := mpristest.NewBus()
+	player := bus.AddPlayer("vlc")
+	return New(mpris.NewWithBus(bus), options), player
+}
+
+type testRequest struct {
+	method, path, body string
+	host               string
+	headers            map[string]string
+}
+
+func (r testRequest) serve(api *API) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+	request.Host = "127.0.0.1:8765"
+	if r.host != "" {
+		request.Host = r.host
+	}
+	for key, value := range r.headers {
+		request.Header.Set(key, value)
+	}
+	response := httptest.NewRecorder()
+	api.ServeHTTP(response, request)
+	return response
+}
+
+var jsonHeaders = map[string]string{"Content-Type": "application/json"}
+
+func TestServeHTTPWithoutToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		request testRequest
+		status  int
+	}{
+		{"read", testRequest{method: "GET", path: "/players/vlc"}, http.StatusOK},
+		{"read from localhost", testRequest{method: "GET", path: "/players", host: "localhost:8765"}, http.StatusOK},
+		{"read from IPv6 loopback", testRequest{method: "GET", path: "/players", host: "[::1]:8765"}, http.StatusOK},
+		{"action", testRequest{method: "POST", path: "/players/vlc/play", headers: jsonHeaders}, http.StatusNoContent},
+		{"action with charset", testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}}, http.StatusNoContent},
+		{"field", testRequest{method: "PUT", path: "/players/vlc/volume", body: `{"value":0.5}`, headers: jsonHeaders}, http.StatusNoContent},
+		{"action without content type", testRequest{method: "POST", path: "/players/vlc/play"}, http.StatusUnsupportedMediaType},
+		{"action as a form", testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Content-Type": "text/plain"}}, http.StatusUnsupportedMediaType},
+		{"field without content type", testRequest{method: "PUT", path: "/players/vlc/volume", body: `{"value":0}`}, http.StatusUnsupportedMediaType},
+		{"rebound host", testRequest{method: "GET", path: "/players", host: "attacker.example:8765"}, http.StatusForbidden},
+		{"rebound host calling an action", testRequest{method: "POST", path: "/players/vlc/play", host: "attacker.example", headers: jsonHeaders}, http.StatusForbidden},
+		{"remote page", testRequest{method: "GET", path: "/", host: "attacker.example"}, http.StatusForbidden},
+		{"same origin", testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Content-Type": "application/json", "Origin": "http://127.0.0.1:8765"}}, http.StatusNoContent},
+		{"other origin", testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Content-Type": "application/json", "Origin": "https://attacker.example"}}, http.StatusForbidden},
+		{"other origin reading", testRequest{method: "GET", path: "/players", headers: map[string]string{"Origin": "https://attacker.example"}}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, _ := newTestAPI(Options{})
+			if response := test.request.serve(api); response.Code != test.status {
+				t.Errorf("got %d %q, want %d", response.Code, response.Body, test.status)
+			}
+		})
+	}
+}
+
+func TestServeHTTPWithoutTokenDoesNotCallRejectedActions(t *testing.T) {
+	api, player := newTestAPI(Options{})
+	testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Content-Type": "text/plain"}}.serve(api)
+	testRequest{method: "POST", path: "/players/vlc/play", host: "attacker.example", headers: jsonHeaders}.serve(api)
+	if calls := player.Calls(); len(calls) != 0 {
+		t.Fatalf("got calls %v, want none", calls)
+	}
+
+	testRequest{method: "POST", path: "/players/vlc/play", headers: jsonHeaders}.serve(api)
+	if calls := player.Calls(); len(calls) != 1 || calls[0].Method != "Play" {
+		t.Fatalf("got calls %v, want Play", calls)
+	}
+}
+
+func TestServeHTTPCORSOrigins(t *testing.T) {
+	api, _ := newTestAPI(Options{CORSOrigins: []string{"https://dash.example"}})
+
+	preflight := testRequest{method: "OPTIONS", path: "/players/vlc/play", headers: map[string]string{
+		"Origin":                        "https://dash.example",
+		"Access-Control-Request-Method": "POST",
+	}}.serve(api)
+	if preflight.Code != http.StatusNoContent || preflight.Header().Get("Access-Control-Allow-Origin") != "https://dash.example" {
+		t.Fatalf("got preflight %d %v", preflight.Code, preflight.Header())
+	}
+
+	headers := map[string]string{"Content-Type": "application/json", "Origin": "https://dash.example"}
+	if response := (testRequest{method: "POST", path: "/players/vlc/play", headers: headers}).serve(api); response.Code != http.StatusNoContent {
+		t.Errorf("allowed origin: got %d %q", response.Code, response.Body)
+	}
+	headers["Origin"] = "https://other.example"
+	if response := (testRequest{method: "POST", path: "/players/vlc/play", headers: headers}).serve(api); response.Code != http.StatusForbidden {
+		t.Errorf("other origin: got %d %q, want 403", response.Code, response.Body)
+	}
+}
+
+func TestServeHTTPWithToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		request testRequest
+		status  int
+	}{
+		{"missing token", testRequest{method: "GET", path: "/players"}, http.StatusUnauthorized},
+		{"wrong token", testRequest{method: "GET", path: "/players", headers: map[string]string{"Authorization": "Bearer wrong"}}, http.StatusUnauthorized},
+		{"header", testRequest{method: "GET", path: "/players", headers: map[string]string{"Authorization": "Bearer secret"}}, http.StatusOK},
+		{"query", testRequest{method: "GET", path: "/players?access_token=secret"}, http.StatusOK},
+		// The token is enough: the API can be reached from other hosts, and scripts need not set a content type.
+		{"other host", testRequest{method: "GET", path: "/players", host: "desktop.lan:8765", headers: map[string]string{"Authorization": "Bearer secret"}}, http.StatusOK},
+		{"action without content type", testRequest{method: "POST", path: "/players/vlc/play", headers: map[string]string{"Authorization": "Bearer secret"}}, http.StatusNoContent},
+		{"remote page without token", testRequest{method: "GET", path: "/"}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, _ := newTestAPI(Options{Token: "secret"})
+			if response := test.request.serve(api); response.Code != test.status {
+				t.Errorf("got %d %q, want %d", response.Code, response.Body, test.status)
+			}
+		})
+	}
+}
+
+func TestServeHTTPErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  testRequest
+		status   int
+		contains string
+	}{
+		{"unknown player", testRequest{method: "GET", path: "/players/missing"}, http.StatusNotFound, "player not found"},
+		{"unknown action", testRequest{method: "POST", path: "/players/vlc/dance", headers: jsonHeaders}, http.StatusNotFound, "no such action"},
+		{"wrong method", testRequest{method: "GET", path: "/players/vlc/play"}, http.StatusMethodNotAllowed, "not allowed"},
+		{"missing value", testRequest{method: "PUT", path: "/players/vlc/volume", headers: jsonHeaders}, http.StatusBadRequest, "missing"},
+		{"invalid body", testRequest{method: "PUT", path: "/players/vlc/volume", body: "{", headers: jsonHeaders}, http.StatusBadRequest, "body"},
+		{"invalid value", testRequest{method: "PUT", path: "/players/vlc/loop", body: `{"value":"Bogus"}`, headers: jsonHeaders}, http.StatusBadRequest, "invalid value"},
+		{"unknown endpoint", testRequest{method: "GET", path: "/nothing/here"}, http.StatusNotFound, "no such endpoint"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, _ := newTestAPI(Options{})
+			response := test.request.serve(api)
+			if response.Code != test.status || strings.Contains(response.Body.String(), test.contains) == false {
+				t.Errorf("got %d %q, want %d and %q", response.Code, response.Body, test.status, test.contains)
+			}
+		})
+	}
+}
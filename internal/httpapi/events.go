@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+const (
+	// writeTimeout bounds the writes to a stream: a client not reading is disconnected.
+	writeTimeout = 10 * time.Second
+	// keepAliveInterval is the interval of the messages keeping idle streams open through proxies.
+	keepAliveInterval = 15 * time.Second
+)
+
+// Types of the messages of a stream, along with the event types.
+const (
+	messageSnapshot = "snapshot"
+	messageOverflow = "overflow"
+)
+
+// snapshotMessage is the first message of a stream: the state of the selected players.
+type snapshotMessage struct {
+	Type    string              `json:"type"`
+	Players []daemon.PlayerInfo `json:"players"`
+}
+
+// overflowMessage is the last message of a stream whose client could not keep up with the events.
+type overflowMessage struct {
+	Type string `json:"type"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// Run follows the events of the players, for the streams, until ctx is done.
+func (a *API) Run(ctx context.Context) error {
+	for event := range a.client.Subscribe(ctx, mpris.Filter{}) {
+		a.lock.Lock()
+		if event.Type == mpris.EventPlayerVanished {
+			delete(a.players, event.Player.Owner)
+		} else {
+			a.players[event.Player.Owner] = event.Player
+		}
+		a.lock.Unlock()
+		a.broadcaster.Broadcast(event)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("lost connection to the bus")
+}
+
+// subscribe returns a subscription to the events selected by the query of r, along with the snapshot of the players.
+// The subscription starts before the snapshot is taken, so no event is lost.
+func (a *API) subscribe(r *http.Request) (*daemon.Subscription, snapshotMessage, error) {
+	query := r.URL.Query()
+	filter, err := daemon.ParseFilter(queryList(query, "player"), queryList(query, "type"))
+	if err != nil {
+		return nil, snapshotMessage{}, err
+	}
+	subscription := a.broadcaster.Subscribe(filter)
+
+	snapshot := snapshotMessage{Type: messageSnapshot, Players: make([]daemon.PlayerInfo, 0)}
+	a.lock.Lock()
+	for _, player := range a.players {
+		if filter.MatchPlayer(&player) {
+			snapshot.Players = append(snapshot.Players, daemon.NewPlayerInfo(&player))
+		}
+	}
+	a.lock.Unlock()
+	sort.Slice(snapshot.Players, func(i, j int) bool { return snapshot.Players[i].Name < snapshot.Players[j].Name })
+	return subscription, snapshot, nil
+}
+
+// queryList returns the values of key, given as repeated parameters or separated by commas.
+func queryList(query url.Values, key string) []string {
+	values := make([]string, 0)
+	for _, value := range query[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// streamEvents streams the events as Server-Sent Events, the event field being the type of the message.
+func (a *API) streamEvents(w http.ResponseWriter, r *http.Request) {
+	subscription, snapshot, err := a.subscribe(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer subscription.Close()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(messageType string, message interface{}) error {
+		controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", messageType, data); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	if err := send(messageSnapshot, snapshot); err != nil {
+		return
+	}
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case event, ok := <-subscription.Events():
+			if ok == false {
+				send(messageOverflow, overflowMessage{Type: messageOverflow})
+				return
+			}
+			if err := send(event.Type.String(), daemon.NewEventInfo(event)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamWebSocket streams the events as JSON text messages on a WebSocket.
+// Messages received from the client are ignored.
+func (a *API) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	subscription, snapshot, err := a.subscribe(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer subscription.Close()
+
+	wsUpgrader := upgrader
+	wsUpgrader.CheckOrigin = a.allowedOrigin
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Reading handles the control messages, and tells when the client is gone.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(message interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(message)
+	}
+
+	if err := send(snapshot); err != nil {
+		return
+	}
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-subscription.Events():
+			if ok == false {
+				send(overflowMessage{Type: messageOverflow})
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, messageOverflow), time.Now().Add(writeTimeout))
+				return
+			}
+			if err := send(daemon.NewEventInfo(event)); err != nil {
+				return
+			}
+		}
+	}
+}
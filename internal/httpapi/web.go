@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// web is the remote, a single page driving the API from a browser.
+//
+//go:embed web
+var web embed.FS
+
+// webFiles serves the files of the remote, at the root.
+var webFiles = func() http.Handler {
+	files, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}()
+
+// serveWeb serves the remote when the request is for one of its files, and returns true.
+// The files hold no state of the players, and are served without token:
+// the page asks for it.
+func (a *API) serveWeb(w http.ResponseWriter, r *http.Request) bool {
+	if a.options.DisableUI || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" {
+		name = "index.html"
+	}
+	if info, err := fs.Stat(web, "web/"+name); err != nil || info.IsDir() {
+		return false
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	webFiles.ServeHTTP(w, r)
+	return true
+}
+
+// maxArtSize bounds the size of the art served from a file.
+const maxArtSize = 32 * 1024 * 1024
+
+// getArt serves the art of the current track of a player: local files are served,
+// other URLs are redirected to.
+func (a *API) getArt(w http.ResponseWriter, r *http.Request, playerName string) {
+	player, err := a.client.Player(r.Context(), playerName)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if player.Metadata.ArtUrl == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s: no art", player.Name))
+		return
+	}
+	artUrl, err := url.Parse(player.Metadata.ArtUrl)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("art: %w", err))
+		return
+	}
+	switch artUrl.Scheme {
+	case "http", "https":
+		http.Redirect(w, r, artUrl.String(), http.StatusFound)
+	case "file":
+		a.serveArtFile(w, r, artUrl.Path)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("art: unsupported URL %s", player.Metadata.ArtUrl))
+	}
+}
+
+// serveArtFile serves the image at path, refusing anything else.
+func (a *API) serveArtFile(w http.ResponseWriter, r *http.Request, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("art: %w", err))
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("art: %w", err))
+		return
+	}
+	if info.Mode().IsRegular() == false || info.Size() > maxArtSize {
+		writeError(w, http.StatusNotFound, errors.New("art: not a regular file of a supported size"))
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && errors.Is(err, io.ErrUnexpectedEOF) == false && errors.Is(err, io.EOF) == false {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("art: %w", err))
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	if strings.HasPrefix(contentType, "image/") == false {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("art: not an image: %s", contentType))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, "", info.ModTime().Truncate(time.Second), file)
+}
@@ -0,0 +1,296 @@
+"use strict";
+
+// The remote drives the HTTP API of mprisctl, and follows its stream of events.
+// The token is kept in the local storage, and can be given in the URL as #token=...
+
+const eventTypes = [
+  "snapshot", "overflow",
+  "player-appeared", "player-vanished", "track-changed", "playback-changed",
+  "loop-status-changed", "shuffle-changed", "seeked", "volume-changed",
+  "capabilities-changed", "position",
+];
+const loopStatuses = ["None", "Playlist", "Track"];
+
+const $ = (id) => document.getElementById(id);
+
+const state = {
+  token: localStorage.getItem("mprisctl-token") || "",
+  players: new Map(),
+  selected: null,
+  // receivedAt is when the position of each player was last received, to advance it while playing.
+  receivedAt: new Map(),
+  source: null,
+  seeking: false,
+  changingVolume: false,
+};
+
+function authHeaders() {
+  return state.token ? { Authorization: "Bearer " + state.token } : {};
+}
+
+function withToken(url) {
+  if (!state.token) {
+    return url;
+  }
+  return url + (url.includes("?") ? "&" : "?") + "access_token=" + encodeURIComponent(state.token);
+}
+
+async function request(method, path, value) {
+  const options = { method, headers: authHeaders() };
+  // Always sent, the API requiring it of actions when it has no token.
+  options.headers["Content-Type"] = "application/json";
+  if (value !== undefined) {
+    options.body = JSON.stringify({ value });
+  }
+  const response = await fetch(path, options);
+  if (response.status === 401) {
+    showLogin("Invalid token");
+    throw new Error("unauthorized");
+  }
+  if (!response.ok) {
+    const body = await response.json().catch(() => ({}));
+    throw new Error(body.error || response.statusText);
+  }
+  return response.status === 204 ? null : response.json();
+}
+
+function call(method, action, value) {
+  if (!state.selected) {
+    return;
+  }
+  const path = "players/" + encodeURIComponent(state.selected) + "/" + action;
+  request(method, path, value).then(() => setStatus(""), (error) => setStatus(error.message));
+}
+
+function setStatus(message) {
+  $("status").textContent = message;
+}
+
+function formatDuration(microseconds) {
+  const seconds = Math.max(0, Math.floor(microseconds / 1e6));
+  const minutes = Math.floor(seconds / 60);
+  const hours = Math.floor(minutes / 60);
+  const pad = (n) => String(n).padStart(2, "0");
+  if (hours > 0) {
+    return hours + ":" + pad(minutes % 60) + ":" + pad(seconds % 60);
+  }
+  return minutes + ":" + pad(seconds % 60);
+}
+
+// position returns the position of player now, advancing the last one received while playing.
+function position(player) {
+  let value = player.position;
+  if (player.playback_status === "Playing") {
+    value += (Date.now() - (state.receivedAt.get(player.player) || Date.now())) * 1000 * (player.rate || 1);
+  }
+  const length = player.metadata.length || 0;
+  return length > 0 ? Math.min(value, length) : value;
+}
+
+function artSource(player) {
+  const artUrl = player.metadata.art_url || "";
+  if (artUrl.startsWith("http://") || artUrl.startsWith("https://") || artUrl.startsWith("data:")) {
+    return artUrl;
+  }
+  if (artUrl) {
+    return withToken("players/" + encodeURIComponent(player.player) + "/art?v=" + encodeURIComponent(artUrl));
+  }
+  return "";
+}
+
+function update(player) {
+  state.players.set(player.player, player);
+  state.receivedAt.set(player.player, Date.now());
+}
+
+function chooseSelected() {
+  if (state.selected && state.players.has(state.selected)) {
+    return;
+  }
+  const players = [...state.players.values()];
+  const playing = players.find((player) => player.playback_status === "Playing");
+  state.selected = (playing || players[0] || {}).player || null;
+}
+
+function renderPlayers() {
+  const nav = $("players");
+  nav.replaceChildren();
+  const players = [...state.players.values()].sort((a, b) => a.player.localeCompare(b.player));
+  for (const player of players) {
+    const button = document.createElement("button");
+    button.textContent = player.display_name || player.application;
+    button.classList.toggle("selected", player.player === state.selected);
+    button.classList.toggle("playing", player.playback_status === "Playing");
+    button.addEventListener("click", () => {
+      state.selected = player.player;
+      render();
+    });
+    nav.append(button);
+  }
+  $("empty").hidden = players.length > 0;
+}
+
+function renderPlayer() {
+  const player = state.players.get(state.selected);
+  $("player").hidden = !player;
+  if (!player) {
+    return;
+  }
+  const metadata = player.metadata;
+  $("title").textContent = metadata.title || player.display_name || player.name;
+  $("artist").textContent = (metadata.artist || []).join(", ");
+  $("album").textContent = metadata.album || "";
+
+  const art = $("art");
+  const source = artSource(player);
+  if (art.dataset.source !== source) {
+    art.dataset.source = source;
+    art.hidden = !source;
+    if (source) {
+      art.src = source;
+    } else {
+      art.removeAttribute("src");
+    }
+  }
+
+  const playing = player.playback_status === "Playing";
+  $("play-pause").innerHTML = playing ? "&#x23F8;" : "&#x25B6;";
+  $("play-pause").disabled = !(playing ? player.can_pause : player.can_play);
+  $("previous").disabled = !player.can_go_previous;
+  $("next").disabled = !player.can_go_next;
+  $("shuffle").classList.toggle("on", player.shuffle);
+  $("shuffle").disabled = !player.can_control;
+  $("loop").classList.toggle("on", player.loop_status !== "None");
+  $("loop").innerHTML = player.loop_status === "Track" ? "&#x1F502;" : "&#x1F501;";
+  $("loop").disabled = !player.can_control;
+  $("seek").disabled = !player.can_seek || !metadata.length;
+  $("seek").max = metadata.length || 0;
+  $("length").textContent = formatDuration(metadata.length || 0);
+  if (!state.changingVolume) {
+    $("volume").value = player.volume;
+  }
+  renderProgress();
+}
+
+function renderProgress() {
+  const player = state.players.get(state.selected);
+  if (!player || state.seeking) {
+    return;
+  }
+  const value = position(player);
+  $("seek").value = value;
+  $("elapsed").textContent = formatDuration(value);
+}
+
+function render() {
+  chooseSelected();
+  renderPlayers();
+  renderPlayer();
+}
+
+function onMessage(type, message) {
+  switch (type) {
+    case "snapshot":
+      state.players.clear();
+      message.players.forEach(update);
+      break;
+    case "overflow":
+      // The stream is closed: EventSource reconnects and starts over with a snapshot.
+      return;
+    case "player-vanished":
+      state.players.delete(message.player.player);
+      break;
+    default:
+      update(message.player);
+  }
+  if (type === "position") {
+    renderProgress();
+  } else {
+    render();
+  }
+}
+
+function connect() {
+  if (state.source) {
+    state.source.close();
+  }
+  const source = new EventSource(withToken("events"));
+  for (const type of eventTypes) {
+    source.addEventListener(type, (event) => onMessage(type, JSON.parse(event.data)));
+  }
+  source.onopen = () => setStatus("");
+  source.onerror = () => setStatus("Reconnecting…");
+  state.source = source;
+}
+
+function showLogin(message) {
+  if (state.source) {
+    state.source.close();
+    state.source = null;
+  }
+  $("remote").hidden = true;
+  $("login").hidden = false;
+  $("login-error").textContent = message || "";
+  $("token").focus();
+}
+
+async function start() {
+  try {
+    await request("GET", "players");
+  } catch (error) {
+    if (error.message !== "unauthorized") {
+      setStatus(error.message);
+    }
+    return;
+  }
+  $("login").hidden = true;
+  $("remote").hidden = false;
+  connect();
+}
+
+$("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  state.token = $("token").value;
+  localStorage.setItem("mprisctl-token", state.token);
+  start();
+});
+
+$("play-pause").addEventListener("click", () => call("POST", "play-pause"));
+$("previous").addEventListener("click", () => call("POST", "previous"));
+$("next").addEventListener("click", () => call("POST", "next"));
+$("shuffle").addEventListener("click", () => {
+  const player = state.players.get(state.selected);
+  call("PUT", "shuffle", !player.shuffle);
+});
+$("loop").addEventListener("click", () => {
+  const player = state.players.get(state.selected);
+  const next = loopStatuses[(loopStatuses.indexOf(player.loop_status) + 1) % loopStatuses.length];
+  call("PUT", "loop", next);
+});
+
+$("seek").addEventListener("input", () => {
+  state.seeking = true;
+  $("elapsed").textContent = formatDuration(Number($("seek").value));
+});
+$("seek").addEventListener("change", () => {
+  state.seeking = false;
+  call("PUT", "position", Number($("seek").value));
+});
+
+$("volume").addEventListener("input", () => {
+  state.changingVolume = true;
+  call("PUT", "volume", Number($("volume").value));
+});
+$("volume").addEventListener("change", () => {
+  state.changingVolume = false;
+});
+
+setInterval(renderProgress, 500);
+
+const fragmentToken = new URLSearchParams(location.hash.slice(1)).get("token");
+if (fragmentToken) {
+  state.token = fragmentToken;
+  localStorage.setItem("mprisctl-token", fragmentToken);
+  history.replaceState(null, "", location.pathname + location.search);
+}
+start();
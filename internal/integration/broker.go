@@ -0,0 +1,334 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Broker is a minimal MQTT 3.1.1 broker, for the bridge to be tested without installing one.
+// It keeps the retained messages, delivers everything with QoS 0, and publishes the wills.
+type Broker struct {
+	listener net.Listener
+
+	lock     sync.Mutex
+	retained map[string]string
+	sessions map[*brokerSession]bool
+}
+
+type brokerSession struct {
+	conn    net.Conn
+	lock    sync.Mutex
+	filters []string
+}
+
+// MQTT control packet types.
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// StartBroker starts a broker listening on a free port of the loopback interface.
+func StartBroker() (*Broker, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	b := &Broker{listener: listener, retained: make(map[string]string), sessions: make(map[*brokerSession]bool)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b, nil
+}
+
+// URL returns the URL to connect to the broker.
+func (b *Broker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// Stop closes the broker and its connections.
+func (b *Broker) Stop() {
+	b.listener.Close()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for session := range b.sessions {
+		session.conn.Close()
+	}
+}
+
+// Retained returns the retained message of topic, if any.
+func (b *Broker) Retained(topic string) (string, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	payload, found := b.retained[topic]
+	return payload, found
+}
+
+// Publish publishes payload on topic, as a client would.
+func (b *Broker) Publish(topic string, payload string, retain bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.publish(topic, payload, retain)
+}
+
+// publish delivers a message to the subscribed sessions. b.lock must be held.
+func (b *Broker) publish(topic string, payload string, retain bool) {
+	if retain {
+		if payload == "" {
+			delete(b.retained, topic)
+		} else {
+			b.retained[topic] = payload
+		}
+	}
+	for session := range b.sessions {
+		if session.subscribed(topic) {
+			session.send(publishPacket(topic, payload))
+		}
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	session := &brokerSession{conn: conn}
+
+	packetType, body, err := readPacket(reader)
+	if err != nil || packetType>>4 != packetConnect {
+		return
+	}
+	will, err := parseConnect(body)
+	if err != nil {
+		return
+	}
+	session.send([]byte{packetConnack << 4, 2, 0, 0})
+	b.lock.Lock()
+	b.sessions[session] = true
+	b.lock.Unlock()
+
+	disconnected := false
+	defer func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.sessions, session)
+		if disconnected == false && will != nil {
+			b.publish(will.topic, will.payload, will.retain)
+		}
+	}()
+
+	for {
+		packetType, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch packetType >> 4 {
+		case packetPublish:
+			topic, payload, packetID, err := parsePublish(packetType, body)
+			if err != nil {
+				return
+			}
+			b.Publish(topic, payload, packetType&1 == 1)
+			if (packetType>>1)&3 > 0 {
+				session.send([]byte{packetPuback << 4, 2, byte(packetID >> 8), byte(packetID)})
+			}
+		case packetSubscribe:
+			if len(body) < 2 {
+				return
+			}
+			var filters []string
+			for rest := body[2:]; len(rest) > 0; {
+				filter, next, err := readString(rest)
+				if err != nil || len(next) < 1 {
+					return
+				}
+				filters = append(filters, filter)
+				rest = next[1:]
+			}
+			session.lock.Lock()
+			session.filters = append(session.filters, filters...)
+			session.lock.Unlock()
+			granted := make([]byte, len(filters))
+			session.send(append([]byte{packetSuback << 4, byte(2 + len(filters)), body[0], body[1]}, granted...))
+			b.lock.Lock()
+			for topic, payload := range b.retained {
+				for _, filter := range filters {
+					if topicMatches(filter, topic) {
+						session.send(publishPacket(topic, payload))
+						break
+					}
+				}
+			}
+			b.lock.Unlock()
+		case packetUnsubscribe:
+			if len(body) < 2 {
+				return
+			}
+			session.send([]byte{packetUnsuback << 4, 2, body[0], body[1]})
+		case packetPingreq:
+			session.send([]byte{packetPingresp << 4, 0})
+		case packetDisconnect:
+			disconnected = true
+			return
+		}
+	}
+}
+
+func (s *brokerSession) send(packet []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.conn.Write(packet)
+}
+
+func (s *brokerSession) subscribed(topic string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, filter := range s.filters {
+		if topicMatches(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicMatches reports whether topic matches filter, with its wildcards + and #.
+func topicMatches(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+type brokerWill struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+func parseConnect(body []byte) (*brokerWill, error) {
+	_, rest, err := readString(body)
+	if err != nil || len(rest) < 4 {
+		return nil, errors.New("malformed connect")
+	}
+	flags := rest[1]
+	_, rest, err = readString(rest[4:])
+	if err != nil {
+		return nil, err
+	}
+	if flags&0x04 == 0 {
+		return nil, nil
+	}
+	will := &brokerWill{retain: flags&0x20 != 0}
+	if will.topic, rest, err = readString(rest); err != nil {
+		return nil, err
+	}
+	if will.payload, _, err = readString(rest); err != nil {
+		return nil, err
+	}
+	return will, nil
+}
+
+func parsePublish(packetType byte, body []byte) (string, string, uint16, error) {
+	topic, rest, err := readString(body)
+	if err != nil {
+		return "", "", 0, err
+	}
+	var packetID uint16
+	if (packetType>>1)&3 > 0 {
+		if len(rest) < 2 {
+			return "", "", 0, errors.New("malformed publish")
+		}
+		packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	return topic, string(rest), packetID, nil
+}
+
+func publishPacket(topic string, payload string) []byte {
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return append(appendLength([]byte{packetPublish << 4}, len(body)), body...)
+}
+
+// readPacket reads a packet, returning its first byte and its body.
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		length += int(digit&127) * multiplier
+		multiplier *= 128
+		if digit&128 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, fmt.Errorf("malformed string")
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+length {
+		return "", nil, fmt.Errorf("malformed string")
+	}
+	return string(data[2 : 2+length]), data[2+length:], nil
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s)))
+	return append(data, s...)
+}
+
+func appendLength(data []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 128
+		}
+		data = append(data, digit)
+		if length == 0 {
+			return data
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Harness runs the mprisctl binary on a Bus.
+type Harness struct {
+	// Binary is the path of the mprisctl binary under test.
+	Binary string
+	Bus    *Bus
+	// Dir, when set, holds the runtime, state and config directories of mprisctl,
+	// isolating it from the daemon and the configuration of the user.
+	Dir string
+	// Timeout bounds every command, and every wait for an output line.
+	Timeout time.Duration
+}
+
+// Result is the outcome of a command run to completion.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("exit code %d, stdout %q, stderr %q", r.ExitCode, r.Stdout, r.Stderr)
+}
+
+func (h *Harness) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(h.Binary, args...)
+	cmd.Env = append(os.Environ(), "DBUS_SESSION_BUS_ADDRESS="+h.Bus.Address)
+	if h.Dir != "" {
+		cmd.Env = append(cmd.Env,
+			"XDG_RUNTIME_DIR="+filepath.Join(h.Dir, "runtime"),
+			"XDG_STATE_HOME="+filepath.Join(h.Dir, "state"),
+			"XDG_CONFIG_HOME="+filepath.Join(h.Dir, "config"),
+		)
+	}
+	return cmd
+}
+
+// Run runs mprisctl with args until it exits.
+func (h *Harness) Run(args ...string) Result {
+	return h.run(h.command(args...))
+}
+
+// RunWithEnv runs mprisctl with args and extra environment variables, like "KEY=value".
+func (h *Harness) RunWithEnv(env []string, args ...string) Result {
+	cmd := h.command(args...)
+	cmd.Env = append(cmd.Env, env...)
+	return h.run(cmd)
+}
+
+func (h *Harness) run(cmd *exec.Cmd) Result {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return Result{Stderr: err.Error(), ExitCode: -1}
+	}
+
+	timer := time.AfterFunc(h.Timeout, func() { cmd.Process.Kill() })
+	err := cmd.Wait()
+	timer.Stop()
+
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+	return result
+}
+
+// Process is a long running mprisctl command, like watch or fake-player.
+type Process struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string
+	stderr  bytes.Buffer
+	timeout time.Duration
+	// exited is closed once the process exited and its output is read.
+	exited chan struct{}
+
+	lock   sync.Mutex
+	output []string
+}
+
+// Start starts mprisctl with args, without waiting for it.
+func (h *Harness) Start(args ...string) (*Process, error) {
+	process := &Process{
+		cmd:     h.command(args...),
+		lines:   make(chan string, 256),
+		timeout: h.Timeout,
+		exited:  make(chan struct{}),
+	}
+	stdout, stdoutWriter := io.Pipe()
+	process.cmd.Stdout = stdoutWriter
+	process.cmd.Stderr = &process.stderr
+	var err error
+	if process.stdin, err = process.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if err := process.cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			process.lines <- scanner.Text()
+		}
+		close(process.lines)
+	}()
+	go func() {
+		process.cmd.Wait()
+		stdoutWriter.Close()
+		close(process.exited)
+	}()
+	return process, nil
+}
+
+// StartFakePlayer starts the fake-player command for name, and waits for it to be on the bus.
+func (h *Harness) StartFakePlayer(name string, args ...string) (*Process, error) {
+	process, err := h.Start(append([]string{"fake-player", "--name", name}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := process.WaitFor("READY::"); err != nil {
+		process.Stop()
+		return nil, err
+	}
+	return process, nil
+}
+
+// Send writes line to the standard input of the process.
+func (p *Process) Send(line string) error {
+	_, err := io.WriteString(p.stdin, line+"\n")
+	return err
+}
+
+// WaitFor reads the output until a line containing each of parts, and returns it.
+func (p *Process) WaitFor(parts ...string) (string, error) {
+	timeout := time.After(p.timeout)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if ok == false {
+				return "", fmt.Errorf("process exited waiting for %q; output %q, stderr %q", parts, p.Output(), p.stderr.String())
+			}
+			p.lock.Lock()
+			p.output = append(p.output, line)
+			p.lock.Unlock()
+			if containsAll(line, parts) {
+				return line, nil
+			}
+		case <-timeout:
+			return "", fmt.Errorf("timeout waiting for %q; output %q", parts, p.Output())
+		}
+	}
+}
+
+// WaitForAll reads the output until each of lines, a list of parts as given to WaitFor,
+// is matched by a line, in any order.
+func (p *Process) WaitForAll(lines ...[]string) error {
+	for _, parts := range lines {
+		if p.seen(parts) {
+			continue
+		}
+		if _, err := p.WaitFor(parts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Process) seen(parts []string) bool {
+	for _, line := range p.Output() {
+		if containsAll(line, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// Output returns the lines read so far by WaitFor.
+func (p *Process) Output() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.output...)
+}
+
+// Drain reads the output until the process exits, and returns every line of it.
+func (p *Process) Drain() []string {
+	for line := range p.lines {
+		p.lock.Lock()
+		p.output = append(p.output, line)
+		p.lock.Unlock()
+	}
+	return p.Output()
+}
+
+// Stop interrupts the process and waits for it.
+func (p *Process) Stop() {
+	p.stdin.Close()
+	p.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-p.exited:
+	case <-time.After(p.timeout):
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+}
+
+func containsAll(line string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(line, part) == false {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+//go:build integration
+
+package integration
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	binaryFlag  = flag.String("mprisctl", "", "mprisctl binary to test (default is to build it)")
+	timeoutFlag = flag.Duration("command-timeout", 10*time.Second, "timeout of every command and every expected output")
+)
+
+// shared is the harness of every scenario, set up by TestMain.
+var shared struct {
+	harness *Harness
+	// skip, when not empty, is why the scenarios cannot run, and err why they fail.
+	skip string
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	cleanup := setUp()
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// setUp builds mprisctl, starts the bus and creates the directories of mprisctl, returning what undoes it.
+func setUp() func() {
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		shared.skip = "dbus-daemon is not in $PATH"
+		return cleanup
+	}
+
+	dir, err := os.MkdirTemp("", "mprisctl-integration")
+	if err != nil {
+		shared.err = err
+		return cleanup
+	}
+	cleanups = append(cleanups, func() { os.RemoveAll(dir) })
+
+	binary := *binaryFlag
+	if binary == "" {
+		binary = filepath.Join(dir, "mprisctl")
+		build := exec.Command("go", "build", "-o", binary, "github.com/webflo-dev/mpris-ctl")
+		if output, err := build.CombinedOutput(); err != nil {
+			shared.err = fmt.Errorf("building mprisctl: %w\n%s", err, output)
+			return cleanup
+		}
+	}
+
+	home := filepath.Join(dir, "home")
+	for _, name := range []string{"", "runtime", "state", "config"} {
+		if err := os.Mkdir(filepath.Join(home, name), 0o700); err != nil {
+			shared.err = err
+			return cleanup
+		}
+	}
+
+	bus, err := StartBus()
+	if err != nil {
+		shared.err = err
+		return cleanup
+	}
+	cleanups = append(cleanups, func() { bus.Close() })
+
+	shared.harness = &Harness{Binary: binary, Bus: bus, Dir: home, Timeout: *timeoutFlag}
+	return cleanup
+}
+
+// scenario returns a subtest running run on the shared harness.
+func scenario(run func(h *Harness) error) func(t *testing.T) {
+	return func(t *testing.T) {
+		if shared.skip != "" {
+			t.Skip(shared.skip)
+		}
+		if shared.err != nil {
+			t.Fatal(shared.err)
+		}
+		err := run(shared.harness)
+		if errors.Is(err, errSkipped) {
+			t.Skip(err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	t.Run("play", scenario(scenarioPlay))
+	t.Run("play-unknown-player", scenario(scenarioPlayUnknownPlayer))
+	t.Run("loop-set", scenario(scenarioLoopSet))
+	t.Run("loop-set-invalid", scenario(scenarioLoopSetInvalid))
+	t.Run("position-set", scenario(scenarioPositionSet))
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("events", scenario(scenarioWatch))
+	t.Run("capabilities", scenario(scenarioWatchCapabilities))
+	t.Run("hooks", scenario(scenarioWatchHooks))
+	t.Run("record-replay", scenario(scenarioWatchRecordReplay))
+}
+
+func TestBuses(t *testing.T) {
+	t.Run("address", scenario(scenarioBusAddress))
+	t.Run("watch-several", scenario(scenarioWatchSeveralBuses))
+	t.Run("single-bus-commands", scenario(scenarioSingleBusCommands))
+}
+
+func TestPlayers(t *testing.T) {
+	t.Run("instances", scenario(scenarioInstances))
+	t.Run("desktop-entry", scenario(scenarioDesktopEntry))
+	t.Run("launch-desktop-entry", scenario(scenarioLaunchDesktopEntry))
+	t.Run("launch-activation", scenario(scenarioLaunchActivation))
+	t.Run("playerctld", scenario(scenarioPlayerctld))
+	t.Run("active-player", scenario(scenarioActivePlayer))
+}
+
+func TestDaemon(t *testing.T) {
+	t.Run("stack", scenario(scenarioDaemon))
+	t.Run("several-buses", scenario(scenarioDaemonSeveralBuses))
+	t.Run("other-bus", scenario(scenarioDaemonOtherBus))
+	t.Run("proxy", scenario(scenarioDaemonProxy))
+	t.Run("socket", scenario(scenarioDaemonSocket))
+	t.Run("exclusive", scenario(scenarioDaemonExclusive))
+	t.Run("duck", scenario(scenarioDaemonDuck))
+}
+
+func TestTimedCommands(t *testing.T) {
+	t.Run("fades", scenario(scenarioFades))
+	t.Run("ab-repeat", scenario(scenarioABRepeat))
+	t.Run("sleep-timer", scenario(scenarioSleepTimer))
+	t.Run("sleep-after-track", scenario(scenarioSleepAfterTrack))
+}
+
+func TestServe(t *testing.T) {
+	t.Run("http", scenario(scenarioServeHTTP))
+	t.Run("streams", scenario(scenarioServeStreams))
+	t.Run("ui", scenario(scenarioServeUI))
+}
+
+func TestMQTT(t *testing.T) {
+	t.Run("bridge", scenario(scenarioMQTT))
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("files", scenario(scenarioConfig))
+}
@@ -0,0 +1,1705 @@
+//go:build integration
+
+package integration
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/gorilla/websocket"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// errSkipped is returned by the scenarios which cannot run in the current environment.
+var errSkipped = errors.New("skipped")
+
+func scenarioPlay(h *Harness) error {
+	return withFakePlayer(h, "play", nil, func(player *Process) error {
+		if err := expect(h.Run("play", "-p", "play"), 0, ""); err != nil {
+			return err
+		}
+		_, err := player.WaitFor("CALL::Play")
+		return err
+	})
+}
+
+func scenarioPlayUnknownPlayer(h *Harness) error {
+	result := h.Run("play", "-p", "missing")
+	if result.ExitCode != 1 || strings.Contains(result.Stderr, "player not found") == false {
+		return fmt.Errorf("expecting exit code 1 and player not found, got %s", result)
+	}
+	return nil
+}
+
+func scenarioLoopSet(h *Harness) error {
+	return withFakePlayer(h, "loop", nil, func(player *Process) error {
+		if err := expect(h.Run("loop", "-p", "loop"), 0, "None\n"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("loop", "-p", "loop", "--set", "Track"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set", "LoopStatus=Track"); err != nil {
+			return err
+		}
+		return expect(h.Run("loop", "-p", "loop"), 0, "Track\n")
+	})
+}
+
+func scenarioLoopSetInvalid(h *Harness) error {
+	return withFakePlayer(h, "loopinvalid", nil, func(player *Process) error {
+		result := h.Run("loop", "-p", "loopinvalid", "--set", "Sometimes")
+		if result.ExitCode != 1 || strings.Contains(result.Stderr, "Sometimes") == false {
+			return fmt.Errorf("expecting exit code 1 and an error about Sometimes, got %s", result)
+		}
+		return expect(h.Run("loop", "-p", "loopinvalid"), 0, "None\n")
+	})
+}
+
+func scenarioPositionSet(h *Harness) error {
+	return withFakePlayer(h, "position", []string{"--track", "title=A length=3m"}, func(player *Process) error {
+		if err := expect(h.Run("position", "-p", "position", "--set", "60000000"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::SetPosition", "position=60000000"); err != nil {
+			return err
+		}
+		result := h.Run("position", "-p", "position")
+		if err := expect(result, 0, "60000000\n"); err != nil {
+			return err
+		}
+
+		// Positions past the end of the track are ignored, as the specification requires.
+		if err := expect(h.Run("position", "-p", "position", "--set", "600000000"), 0, ""); err != nil {
+			return err
+		}
+		return expect(h.Run("position", "-p", "position"), 0, "60000000\n")
+	})
+}
+
+func scenarioWatch(h *Harness) error {
+	return withFakePlayer(h, "watch", []string{"--track", `title="First track" artist=A,B length=3m`}, func(player *Process) error {
+		watch, err := h.Start("watch")
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+
+		if _, err := watch.WaitFor("PLAYER::connected", "player_name=watch", `title="First track"`, `artist="A,B"`, "playback_status=Stopped"); err != nil {
+			return err
+		}
+
+		steps := []struct {
+			command string
+			expect  []string
+		}{
+			{"play", []string{"PLAYBACK_STATUS::watch", "playback_status=Playing"}},
+			{"position 1m", []string{"POSITION::watch", "elapsed=01:0"}},
+			{"set LoopStatus Playlist", []string{"LOOP::watch", "loop_status=Playlist"}},
+			{"set Shuffle true", []string{"SHUFFLE::watch", "shuffle=true"}},
+			{"set can-seek false", []string{"CAPABILITIES::watch", "can_seek=false"}},
+			{`metadata title="Second track" length=2m`, []string{"METADATA::watch", `title="Second track"`, `artist=""`}},
+			{"pause", []string{"PLAYBACK_STATUS::watch", "playback_status=Paused"}},
+			{"vanish", []string{"PLAYER::disconnected", "player_name=watch"}},
+		}
+		for _, step := range steps {
+			if err := player.Send(step.command); err != nil {
+				return err
+			}
+			if _, err := watch.WaitFor(step.expect...); err != nil {
+				return fmt.Errorf("after %q: %w", step.command, err)
+			}
+		}
+		return nil
+	})
+}
+
+func scenarioWatchCapabilities(h *Harness) error {
+	return withFakePlayer(h, "capabilities", []string{"--disable", "go-next,seek"}, func(player *Process) error {
+		watch, err := h.Start("watch")
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+
+		_, err = watch.WaitFor("PLAYER::connected", "player_name=capabilities", "can_go_next=false", "can_seek=false", "can_play=true")
+		return err
+	})
+}
+
+func scenarioWatchHooks(h *Harness) error {
+	dir, err := os.MkdirTemp(h.Dir, "hooks")
+	if err != nil {
+		return err
+	}
+	envPath := filepath.Join(dir, "env")
+	stdinPath := filepath.Join(dir, "stdin")
+	return withFakePlayer(h, "hooked", nil, func(player *Process) error {
+		watch, err := h.Start("watch",
+			"--hook", fmt.Sprintf(`track=printf '%%s|%%s|%%s\n' "$MPRIS_EVENT" "$MPRIS_PLAYER" "$MPRIS_TITLE" >> %s`, envPath),
+			"--hook", fmt.Sprintf("play,pause=cat >> %s", stdinPath),
+			"--hook", "stop=sleep 10",
+			"--hook-debounce", "300ms",
+			"--hook-timeout", "200ms",
+		)
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+		if _, err := watch.WaitFor("PLAYER::connected", "player_name=hooked"); err != nil {
+			return err
+		}
+
+		// Titles are given as they are, and the track changes are debounced to the last one.
+		odd := "Rock'n'roll; $(touch pwned) & `echo` \\n"
+		for _, command := range []string{`metadata title="First"`, fmt.Sprintf(`metadata title="%s"`, odd)} {
+			if err := player.Send(command); err != nil {
+				return err
+			}
+		}
+		if err := waitForFile(h, envPath, "track-changed|hooked|"+odd+"\n"); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+		if content, err := os.ReadFile(envPath); err != nil || strings.Count(string(content), "\n") != 1 {
+			return fmt.Errorf("expecting a single run of the debounced hook, got %q (%v)", content, err)
+		}
+
+		if err := player.Send("play"); err != nil {
+			return err
+		}
+		if err := waitForFile(h, stdinPath, `"type":"playback-changed"`, `"player":"hooked"`, `"playback_status":"Playing"`); err != nil {
+			return err
+		}
+
+		if err := player.Send("stop"); err != nil {
+			return err
+		}
+		_, err = watch.WaitFor("HOOK::failed", "event=playback-changed", "player=hooked", "timed out after 200ms")
+		return err
+	})
+}
+
+func scenarioBusAddress(h *Harness) error {
+	return withFakePlayer(h, "address", nil, func(player *Process) error {
+		unreachable := []string{"DBUS_SESSION_BUS_ADDRESS=unix:path=/nonexistent/bus"}
+		if result := h.RunWithEnv(unreachable, "loop", "-p", "address"); result.ExitCode == 0 {
+			return fmt.Errorf("expecting a failure on an unreachable bus, got %s", result)
+		}
+		return expect(h.RunWithEnv(unreachable, "--bus-address", h.Bus.Address, "loop", "-p", "address"), 0, "None\n")
+	})
+}
+
+func scenarioWatchRecordReplay(h *Harness) error {
+	dir, err := os.MkdirTemp("", "mprisctl-recording")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	recording := filepath.Join(dir, "recording.jsonl")
+
+	var watched []string
+	err = withFakePlayer(h, "record", []string{"--track", "title=A artist=X,Y length=3m", "--track", "title=B length=2m"}, func(player *Process) error {
+		watch, err := h.Start("watch", "--record", recording)
+		if err != nil {
+			return err
+		}
+		if _, err := watch.WaitFor("PLAYER::connected", "player_name=record"); err != nil {
+			watch.Stop()
+			return err
+		}
+
+		steps := []struct {
+			command string
+			expect  []string
+		}{
+			{"play", []string{"PLAYBACK_STATUS::record", "playback_status=Playing"}},
+			{"position 30s", []string{"POSITION::record"}},
+			{"next", []string{"METADATA::record", "title=\"B\""}},
+			{"set LoopStatus Track", []string{"LOOP::record", "loop_status=Track"}},
+			{"vanish", []string{"PLAYER::disconnected", "player_name=record"}},
+		}
+		for _, step := range steps {
+			if err := player.Send(step.command); err != nil {
+				watch.Stop()
+				return err
+			}
+			if _, err := watch.WaitFor(step.expect...); err != nil {
+				watch.Stop()
+				return fmt.Errorf("after %q: %w", step.command, err)
+			}
+		}
+		watch.Stop()
+		watched = watch.Drain()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	result := h.RunWithEnv([]string{"DBUS_SESSION_BUS_ADDRESS=unix:path=/nonexistent/bus"}, "watch", "--replay", recording)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("replay failed: %s", result)
+	}
+	if replayed := strings.Join(watched, "\n") + "\n"; result.Stdout != replayed {
+		return fmt.Errorf("replay differs from watch:\n%s\nreplayed:\n%s", replayed, result.Stdout)
+	}
+	return nil
+}
+
+func scenarioWatchSeveralBuses(h *Harness) error {
+	kiosk, err := StartBus()
+	if err != nil {
+		return err
+	}
+	defer kiosk.Close()
+
+	return withFakePlayer(h, "onsession", nil, func(session *Process) error {
+		other, err := h.StartFakePlayer("onkiosk", "--bus-address", kiosk.Address)
+		if err != nil {
+			return err
+		}
+		defer other.Stop()
+
+		watch, err := h.Start("watch", "--session", "--bus-address", "kiosk="+kiosk.Address)
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+
+		err = watch.WaitForAll(
+			[]string{"PLAYER::connected", "player_name=onsession@session "},
+			[]string{"PLAYER::connected", "player_name=onkiosk@kiosk "},
+		)
+		if err != nil {
+			return err
+		}
+		if err := other.Send("play"); err != nil {
+			return err
+		}
+		if _, err := watch.WaitFor("PLAYBACK_STATUS::onkiosk@kiosk playback_status=Playing"); err != nil {
+			return err
+		}
+
+		// The player of the other bus is not visible on the session bus.
+		if result := h.Run("play", "-p", "onkiosk"); result.ExitCode != 1 {
+			return fmt.Errorf("expecting exit code 1, got %s", result)
+		}
+		return expect(h.Run("--bus-address", kiosk.Address, "loop", "-p", "onkiosk"), 0, "None\n")
+	})
+}
+
+func scenarioSingleBusCommands(h *Harness) error {
+	result := h.Run("--session", "--bus-address", h.Bus.Address, "play", "-p", "any")
+	if result.ExitCode != 1 || strings.Contains(result.Stderr, "single bus") == false {
+		return fmt.Errorf("expecting exit code 1 and an error about a single bus, got %s", result)
+	}
+	return nil
+}
+
+func scenarioInstances(h *Harness) error {
+	return withFakePlayer(h, "browser.instance_1_84", nil, func(player *Process) error {
+		watch, err := h.Start("watch")
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+		if _, err := watch.WaitFor("PLAYER::connected player_name=browser instance=instance_1_84 "); err != nil {
+			return err
+		}
+
+		if err := expect(h.Run("play", "-p", "browser"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		if _, err := watch.WaitFor("PLAYBACK_STATUS::browser instance=instance_1_84 playback_status=Playing"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("loop", "-p", "browser.instance_1_84"), 0, "None\n"); err != nil {
+			return err
+		}
+
+		result := h.Run("list")
+		if result.ExitCode != 0 || containsAll(result.Stdout, []string{"browser instance=instance_1_84 player_name=browser.instance_1_84 ", "playback_status=Playing\n"}) == false {
+			return fmt.Errorf("expecting the instance in the list, got %s", result)
+		}
+
+		// Only names without an instance match any instance.
+		if result := h.Run("play", "-p", "browser.instance_2"); result.ExitCode != 1 {
+			return fmt.Errorf("expecting exit code 1, got %s", result)
+		}
+		return nil
+	})
+}
+
+func scenarioDesktopEntry(h *Harness) error {
+	dataHome, err := os.MkdirTemp("", "mprisctl-data")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dataHome)
+
+	files := map[string]string{
+		"applications/fakeapp.desktop":            "[Desktop Entry]\nType=Application\nName=Fake player\nName[fr]=Lecteur factice\nIcon=fakeapp\n\n[Desktop Action new]\nName=New window\n",
+		"icons/hicolor/48x48/apps/fakeapp.png":    "",
+		"icons/hicolor/scalable/apps/fakeapp.svg": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dataHome, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	env := []string{"XDG_DATA_HOME=" + dataHome, "XDG_DATA_DIRS=/nonexistent", "LC_ALL=", "LC_MESSAGES=", "LANG=fr_FR.UTF-8"}
+	icon := filepath.Join(dataHome, "icons/hicolor/scalable/apps/fakeapp.svg")
+
+	return withFakePlayer(h, "desktop", []string{"--identity", "Fake App", "--desktop-entry", "fakeapp"}, func(player *Process) error {
+		result := h.RunWithEnv(env, "list")
+		expected := fmt.Sprintf(`desktop player_name=desktop display_name="Lecteur factice" identity="Fake App" desktop_entry=fakeapp icon_name=fakeapp icon=%s playback_status=Stopped`, icon)
+		if result.ExitCode != 0 || strings.Contains(result.Stdout, expected) == false {
+			return fmt.Errorf("expecting %q in the list, got %s", expected, result)
+		}
+		// The other formats, like the socket, the HTTP API, MQTT and the hooks, have them too.
+		result = h.RunWithEnv(env, "list", "--format", "json")
+		expected = fmt.Sprintf(`"display_name":"Lecteur factice","icon":"fakeapp","icon_path":%q`, icon)
+		if result.ExitCode != 0 || strings.Contains(result.Stdout, expected) == false {
+			return fmt.Errorf("expecting %q in the list, got %s", expected, result)
+		}
+		result = h.RunWithEnv(env, "list", "--format", "template", "--template", "{{.DisplayName}} {{.Icon}}")
+		if err := expect(result, 0, "Lecteur factice fakeapp\n"); err != nil {
+			return err
+		}
+
+		// Without a desktop entry, the identity is the display name.
+		result = h.RunWithEnv([]string{"XDG_DATA_HOME=/nonexistent", "XDG_DATA_DIRS=/nonexistent"}, "list")
+		expected = `desktop player_name=desktop display_name="Fake App" identity="Fake App" desktop_entry=fakeapp icon_name= icon= `
+		if result.ExitCode != 0 || strings.Contains(result.Stdout, expected) == false {
+			return fmt.Errorf("expecting %q in the list, got %s", expected, result)
+		}
+		return nil
+	})
+}
+
+// quitScript makes a launched fake player quit by itself, as nobody holds it.
+const quitScript = `[{"after": "5s", "action": "quit"}]`
+
+func scenarioLaunchDesktopEntry(h *Harness) error {
+	dataHome, err := os.MkdirTemp("", "mprisctl-data")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dataHome)
+
+	script := filepath.Join(dataHome, "quit.json")
+	if err := os.WriteFile(script, []byte(quitScript), 0o644); err != nil {
+		return err
+	}
+	desktopEntry := fmt.Sprintf("[Desktop Entry]\nType=Application\nName=Launched\nExec=\"%s\" fake-player --name launched --script %s %%U\n", h.Binary, script)
+	if err := os.MkdirAll(filepath.Join(dataHome, "applications"), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dataHome, "applications", "org.example.Launched.desktop"), []byte(desktopEntry), 0o644); err != nil {
+		return err
+	}
+	env := []string{"XDG_DATA_HOME=" + dataHome, "XDG_DATA_DIRS=/nonexistent"}
+	defer quitPlayer(h, "launched")
+
+	if result := h.RunWithEnv(env, "play", "-p", "launched"); result.ExitCode != 1 {
+		return fmt.Errorf("expecting exit code 1 without --launch, got %s", result)
+	}
+	if err := expect(h.RunWithEnv(env, "play", "-p", "launched", "--launch"), 0, ""); err != nil {
+		return err
+	}
+	if err := expect(h.RunWithEnv(env, "loop", "-p", "launched"), 0, "None\n"); err != nil {
+		return err
+	}
+	// The player is running: launching again is a no-op.
+	if err := expect(h.RunWithEnv(env, "pause", "-p", "launched", "--launch"), 0, ""); err != nil {
+		return err
+	}
+
+	result := h.RunWithEnv(env, "play", "-p", "unknown", "--launch")
+	if result.ExitCode != 1 || strings.Contains(result.Stderr, "no D-Bus service nor desktop entry") == false {
+		return fmt.Errorf("expecting exit code 1 and no desktop entry, got %s", result)
+	}
+	return nil
+}
+
+func scenarioLaunchActivation(h *Harness) error {
+	services, err := os.MkdirTemp("", "mprisctl-services")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(services)
+
+	script := filepath.Join(services, "quit.json")
+	if err := os.WriteFile(script, []byte(quitScript), 0o644); err != nil {
+		return err
+	}
+	service := fmt.Sprintf("[D-Bus Service]\nName=org.mpris.MediaPlayer2.activated\nExec=/bin/sh -c 'exec \"%s\" --bus-address \"$DBUS_STARTER_ADDRESS\" fake-player --name activated --script %s'\n", h.Binary, script)
+	if err := os.WriteFile(filepath.Join(services, "activated.service"), []byte(service), 0o644); err != nil {
+		return err
+	}
+
+	bus, err := StartBusWithServices(services)
+	if err != nil {
+		return err
+	}
+	defer bus.Close()
+
+	client, err := mpris.NewWithAddress(bus.Address)
+	if err != nil {
+		return err
+	}
+	activatable, err := client.ActivatablePlayerNames(context.Background())
+	client.Close()
+	if err != nil {
+		return err
+	}
+	if len(activatable) == 0 {
+		return fmt.Errorf("%w: dbus-daemon did not load %s", errSkipped, services)
+	}
+
+	args := []string{"--bus-address", bus.Address}
+	if err := expect(h.Run(append(args, "play", "-p", "activated", "--launch")...), 0, ""); err != nil {
+		return err
+	}
+	return expect(h.Run(append(args, "loop", "-p", "activated")...), 0, "None\n")
+}
+
+func scenarioPlayerctld(h *Harness) error {
+	return withFakePlayer(h, "fronted", nil, func(player *Process) error {
+		watch, err := h.Start("watch")
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+		if _, err := watch.WaitFor("PLAYER::connected player_name=fronted "); err != nil {
+			return err
+		}
+
+		return withFakePlayer(h, "playerctld", []string{"--playerctld-players", "fronted"}, func(playerctld *Process) error {
+			result := h.Run("list")
+			if result.ExitCode != 0 || strings.Contains(result.Stdout, "player_name=playerctld") {
+				return fmt.Errorf("expecting playerctld hidden from the list, got %s", result)
+			}
+			result = h.Run("--playerctld", "show", "list")
+			if result.ExitCode != 0 || strings.Contains(result.Stdout, "player_name=playerctld") == false {
+				return fmt.Errorf("expecting playerctld in the list, got %s", result)
+			}
+
+			if err := expect(h.Run("--playerctld", "proxy", "play", "-p", "playerctld"), 0, ""); err != nil {
+				return err
+			}
+			if _, err := player.WaitFor("CALL::Play"); err != nil {
+				return err
+			}
+			if _, err := watch.WaitFor("PLAYBACK_STATUS::fronted playback_status=Playing"); err != nil {
+				return err
+			}
+			if err := expect(h.Run("play", "-p", "playerctld"), 0, ""); err != nil {
+				return err
+			}
+			if _, err := playerctld.WaitFor("CALL::Play"); err != nil {
+				return err
+			}
+			for _, line := range watch.Output() {
+				if strings.Contains(line, "playerctld") {
+					return fmt.Errorf("expecting watch to ignore playerctld, got %q", line)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func scenarioActivePlayer(h *Harness) error {
+	if result := h.Run("play"); result.ExitCode != 1 || strings.Contains(result.Stderr, "no player is running") == false {
+		return fmt.Errorf("expecting exit code 1 and no player running, got %s", result)
+	}
+	return withFakePlayer(h, "active-a", nil, func(a *Process) error {
+		return withFakePlayer(h, "active-b", nil, func(b *Process) error {
+			if err := expect(h.Run("play", "-p", "active-b"), 0, ""); err != nil {
+				return err
+			}
+			// Without the daemon, the playing player is picked.
+			if err := expect(h.Run("next"), 0, ""); err != nil {
+				return err
+			}
+			if _, err := b.WaitFor("CALL::Next"); err != nil {
+				return err
+			}
+			if result := h.Run("play", "--launch"); result.ExitCode != 1 {
+				return fmt.Errorf("expecting exit code 1, got %s", result)
+			}
+			return nil
+		})
+	})
+}
+
+func scenarioDaemon(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+
+	return withFakePlayer(h, "daemon-a", nil, func(a *Process) error {
+		return withFakePlayer(h, "daemon-b", nil, func(b *Process) error {
+			daemon, err := h.Start("daemon")
+			if err != nil {
+				return err
+			}
+			defer daemon.Stop()
+			if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+				return err
+			}
+			if result := h.Run("daemon"); result.ExitCode != 1 || strings.Contains(result.Stderr, "already running") == false {
+				return fmt.Errorf("expecting exit code 1 and already running, got %s", result)
+			}
+
+			if err := expect(h.Run("play", "-p", "daemon-b"), 0, ""); err != nil {
+				return err
+			}
+			if err := waitForFile(h, statePath, `"player": "daemon-b"`); err != nil {
+				return err
+			}
+			if err := expect(h.Run("play", "-p", "daemon-a"), 0, ""); err != nil {
+				return err
+			}
+			if err := expect(h.Run("pause", "-p", "daemon-a"), 0, ""); err != nil {
+				return err
+			}
+			if err := waitForFile(h, statePath, `"player": "daemon-a"`, `"player": "daemon-b"`); err != nil {
+				return err
+			}
+
+			// daemon-a was active last: the daemon picks it although daemon-b is the one playing.
+			if err := expect(h.Run("previous"), 0, ""); err != nil {
+				return err
+			}
+			if _, err := a.WaitFor("CALL::Previous"); err != nil {
+				return err
+			}
+
+			if err := expect(h.Run("volume", "-p", "daemon-a"), 0, "1\n"); err != nil {
+				return err
+			}
+
+			// The order survives a restart of the daemon.
+			daemon.Stop()
+			daemon, err = h.Start("daemon")
+			if err != nil {
+				return err
+			}
+			defer daemon.Stop()
+			if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+				return err
+			}
+			// Once the daemon has read the bus again.
+			if err := eventually(h, func() error {
+				conn, err := net.Dial("unix", filepath.Join(h.Dir, "runtime", "mprisctl.sock"))
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(h.Timeout))
+				if _, err := io.WriteString(conn, `{"jsonrpc":"2.0","id":1,"method":"players"}`+"\n"); err != nil {
+					return err
+				}
+				return readLine(bufio.NewReader(conn), `"id":1`, `"player":"daemon-a"`, `"player":"daemon-b"`)
+			}); err != nil {
+				return err
+			}
+			if err := expect(h.Run("next"), 0, ""); err != nil {
+				return err
+			}
+			_, err = a.WaitFor("CALL::Next")
+			return err
+		})
+	})
+}
+
+func scenarioDaemonSeveralBuses(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+	kiosk, err := StartBus()
+	if err != nil {
+		return err
+	}
+	defer kiosk.Close()
+
+	return withFakePlayer(h, "daemon-session", nil, func(session *Process) error {
+		other, err := h.StartFakePlayer("daemon-kiosk", "--bus-address", kiosk.Address)
+		if err != nil {
+			return err
+		}
+		defer other.Stop()
+		daemon, err := h.Start("daemon", "--session", "--bus-address", "kiosk="+kiosk.Address)
+		if err != nil {
+			return err
+		}
+		defer daemon.Stop()
+		if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("--bus-address", kiosk.Address, "play", "-p", "daemon-kiosk"), 0, ""); err != nil {
+			return err
+		}
+		if err := waitForFile(h, statePath, `"player": "daemon-kiosk@kiosk"`); err != nil {
+			return err
+		}
+
+		// Subscriptions designate the players with their bus too.
+		conn, err := net.Dial("unix", filepath.Join(h.Dir, "runtime", "mprisctl.sock"))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(h.Timeout))
+		reader := bufio.NewReader(conn)
+		if _, err := io.WriteString(conn, `{"jsonrpc":"2.0","id":1,"method":"subscribe","params":{"players":["daemon-kiosk@kiosk"],"types":["playback-changed"]}}`+"\n"); err != nil {
+			return err
+		}
+		if err := readLine(reader, `"id":1`, `"player":"daemon-kiosk@kiosk"`); err != nil {
+			return err
+		}
+
+		// The active player is looked for on the bus of the command: the kiosk player is not on the session bus.
+		if err := expect(h.Run("pause"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := session.WaitFor("CALL::Pause"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("--bus-address", kiosk.Address, "pause"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := other.WaitFor("CALL::Pause"); err != nil {
+			return err
+		}
+		if err := readLine(reader, `"method":"event"`, `"player":"daemon-kiosk@kiosk"`, `"playback_status":"Paused"`); err != nil {
+			return err
+		}
+
+		// Players are named on another bus with its label.
+		if err := expect(h.Run("play", "-p", "daemon-session@session"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := session.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		return eventually(h, func() error {
+			return expect(h.Run("--bus-address", "kiosk="+kiosk.Address, "loop", "-p", "daemon-kiosk@kiosk"), 0, "None\n")
+		})
+	})
+}
+
+func scenarioDaemonOtherBus(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+	system, err := StartBus()
+	if err != nil {
+		return err
+	}
+	defer system.Close()
+	env := []string{"DBUS_SYSTEM_BUS_ADDRESS=" + system.Address}
+
+	return withFakePlayer(h, "elsewhere", []string{"--volume", "0.5"}, func(session *Process) error {
+		other, err := h.StartFakePlayer("elsewhere", "--bus-address", system.Address, "--volume", "0.3")
+		if err != nil {
+			return err
+		}
+		defer other.Stop()
+		daemon, err := h.Start("daemon")
+		if err != nil {
+			return err
+		}
+		defer daemon.Stop()
+		if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("play", "-p", "elsewhere"), 0, ""); err != nil {
+			return err
+		}
+		if err := waitForFile(h, statePath, `"player": "elsewhere"`); err != nil {
+			return err
+		}
+		if err := expect(h.Run("volume", "-p", "elsewhere"), 0, "0.5\n"); err != nil {
+			return err
+		}
+
+		// The daemon watches the session bus only: the player of the system bus is read on it.
+		if err := expect(h.RunWithEnv(env, "--system", "volume", "-p", "elsewhere"), 0, "0.3\n"); err != nil {
+			return err
+		}
+
+		// The active player of the daemon is not on the system bus either.
+		return withFakePlayer(h, "session-only", nil, func(only *Process) error {
+			if err := expect(h.Run("play", "-p", "session-only"), 0, ""); err != nil {
+				return err
+			}
+			if err := waitForFile(h, statePath, `"player": "session-only"`, `"player": "elsewhere"`); err != nil {
+				return err
+			}
+			if err := expect(h.RunWithEnv(env, "--system", "pause"), 0, ""); err != nil {
+				return err
+			}
+			_, err := other.WaitFor("CALL::Pause")
+			return err
+		})
+	})
+}
+
+func scenarioDaemonProxy(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+
+	return withFakePlayer(h, "proxied", nil, func(player *Process) error {
+		if err := expect(h.Run("play", "-p", "proxied"), 0, ""); err != nil {
+			return err
+		}
+		daemon, err := h.Start("daemon", "--proxy")
+		if err != nil {
+			return err
+		}
+		defer daemon.Stop()
+		if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+			return err
+		}
+		watch, err := h.Start("watch")
+		if err != nil {
+			return err
+		}
+		defer watch.Stop()
+
+		if result := h.Run("list"); result.ExitCode != 0 || strings.Contains(result.Stdout, "player_name=mprisctl") {
+			return fmt.Errorf("expecting the proxy hidden from the list, got %s", result)
+		}
+		if result := h.Run("--playerctld", "show", "list"); result.ExitCode != 0 || strings.Contains(result.Stdout, "player_name=mprisctl") == false {
+			return fmt.Errorf("expecting the proxy in the list, got %s", result)
+		}
+
+		if err := expect(h.Run("next", "-p", "mprisctl"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Next"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("loop", "--set", "Track", "-p", "mprisctl"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set LoopStatus=Track"); err != nil {
+			return err
+		}
+
+		// Changes of the player are published by the proxy.
+		if err := expect(h.Run("shuffle", "--set", "-p", "proxied"), 0, ""); err != nil {
+			return err
+		}
+		if err := eventually(h, func() error { return expect(h.Run("shuffle", "-p", "mprisctl"), 0, "true\n") }); err != nil {
+			return err
+		}
+
+		if _, err := watch.WaitFor("SHUFFLE::proxied shuffle=true"); err != nil {
+			return err
+		}
+		for _, line := range watch.Output() {
+			if strings.Contains(line, "::mprisctl ") || strings.Contains(line, "player_name=mprisctl ") {
+				return fmt.Errorf("expecting watch to ignore the proxy, got %q", line)
+			}
+		}
+		return nil
+	})
+}
+
+func scenarioDaemonExclusive(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+
+	return withFakePlayer(h, "exclusive-a", nil, func(a *Process) error {
+		return withFakePlayer(h, "exclusive-b", nil, func(b *Process) error {
+			return withFakePlayer(h, "overlapping", nil, func(overlapping *Process) error {
+				daemon, err := h.Start("daemon", "--exclusive", "--resume", "--exclusive-allow", "overlapping")
+				if err != nil {
+					return err
+				}
+				defer daemon.Stop()
+				if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+					return err
+				}
+
+				if err := expect(h.Run("play", "-p", "exclusive-a"), 0, ""); err != nil {
+					return err
+				}
+				if err := expect(h.Run("play", "-p", "exclusive-b"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := daemon.WaitFor("DAEMON::pausing player=exclusive-a for=exclusive-b"); err != nil {
+					return err
+				}
+				if _, err := a.WaitFor("CALL::Pause"); err != nil {
+					return err
+				}
+
+				// The allowed player plays along, pausing nobody.
+				if err := expect(h.Run("play", "-p", "overlapping"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := overlapping.WaitFor("CALL::Play"); err != nil {
+					return err
+				}
+
+				// Once the interrupting player stops, the player it paused is resumed.
+				if err := expect(h.Run("pause", "-p", "exclusive-b"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := daemon.WaitFor("DAEMON::resuming player=exclusive-a after=exclusive-b"); err != nil {
+					return err
+				}
+				if _, err := a.WaitFor("CALL::Play"); err != nil {
+					return err
+				}
+				for _, line := range daemon.Output() {
+					if strings.Contains(line, "overlapping") {
+						return fmt.Errorf("expecting the allowed player left alone, got %q", line)
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func scenarioDaemonDuck(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+
+	return withFakePlayer(h, "background", []string{"--playing", "--volume", "0.8"}, func(background *Process) error {
+		return withFakePlayer(h, "fixed", []string{"--playing", "--fixed-volume"}, func(fixed *Process) error {
+			return withFakePlayer(h, "call", nil, func(call *Process) error {
+				daemon, err := h.Start("daemon", "--duck", "call", "--duck-amount", "0.5", "--duck-ramp", "200ms")
+				if err != nil {
+					return err
+				}
+				defer daemon.Stop()
+				if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+					return err
+				}
+
+				if err := expect(h.Run("play", "-p", "call"), 0, ""); err != nil {
+					return err
+				}
+				if err := daemon.WaitForAll(
+					[]string{"DAEMON::ducking player=background for=call"},
+					[]string{"DAEMON::pausing player=fixed for=call"},
+				); err != nil {
+					return err
+				}
+				if _, err := background.WaitFor("CALL::Set Volume=0.4"); err != nil {
+					return err
+				}
+				if _, err := fixed.WaitFor("CALL::Pause"); err != nil {
+					return err
+				}
+
+				// Once the priority player stops, the volume is restored and the paused player resumed.
+				if err := expect(h.Run("stop", "-p", "call"), 0, ""); err != nil {
+					return err
+				}
+				if err := daemon.WaitForAll(
+					[]string{"DAEMON::restoring player=background"},
+					[]string{"DAEMON::restoring player=fixed"},
+				); err != nil {
+					return err
+				}
+				if _, err := background.WaitFor("CALL::Set Volume=0.8"); err != nil {
+					return err
+				}
+				if _, err := fixed.WaitFor("CALL::Play"); err != nil {
+					return err
+				}
+
+				// A volume changed while ducked is kept.
+				if err := expect(h.Run("play", "-p", "call"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := daemon.WaitFor("DAEMON::ducked player=background volume=0.4"); err != nil {
+					return err
+				}
+				if err := expect(h.Run("volume", "--set", "0.6", "-p", "background"), 0, ""); err != nil {
+					return err
+				}
+				if err := expect(h.Run("stop", "-p", "call"), 0, ""); err != nil {
+					return err
+				}
+				_, err = daemon.WaitFor("DAEMON::keeping player=background volume=0.6")
+				return err
+			})
+		})
+	})
+}
+
+func scenarioFades(h *Harness) error {
+	return withFakePlayer(h, "faded", []string{"--playing", "--volume", "0.8"}, func(player *Process) error {
+		// A fade is cancelled by the next command, the volume being restored.
+		pause, err := h.Start("pause", "--fade", "1m", "-p", "faded")
+		if err != nil {
+			return err
+		}
+		defer pause.Stop()
+		if err := waitForFile(h, filepath.Join(h.Dir, "runtime", "mprisctl-fade-faded.pid"), " 0.8"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("play", "-p", "faded"), 0, ""); err != nil {
+			return err
+		}
+		pause.Drain()
+		if err := eventually(h, func() error { return expect(h.Run("volume", "-p", "faded"), 0, "0.8\n") }); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		for _, line := range player.Output() {
+			if strings.Contains(line, "CALL::Pause") {
+				return fmt.Errorf("expecting the cancelled fade not to pause, got %q", line)
+			}
+		}
+
+		if err := expect(h.Run("pause", "--fade", "200ms", "-p", "faded"), 0, ""); err != nil {
+			return err
+		}
+		if err := player.WaitForAll([]string{"CALL::Set Volume=0"}, []string{"CALL::Pause"}); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.8"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("play", "--fade-in", "200ms", "-p", "faded"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.8"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("next", "--crossfade-ish", "200ms", "-p", "faded"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Next"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.8"); err != nil {
+			return err
+		}
+		if err := expect(h.Run("volume", "--fade-to", "0.3", "--over", "200ms", "-p", "faded"), 0, ""); err != nil {
+			return err
+		}
+		// A finished fade leaves no file behind, for the next command not to cancel anything.
+		if _, err := os.Stat(filepath.Join(h.Dir, "runtime", "mprisctl-fade-faded.pid")); err == nil {
+			return errors.New("expecting the file of the fade to be removed once done")
+		}
+		return expect(h.Run("volume", "-p", "faded"), 0, "0.3\n")
+	})
+}
+
+func scenarioABRepeat(h *Harness) error {
+	err := withFakePlayer(h, "unseekable", []string{"--playing", "--disable", "seek"}, func(player *Process) error {
+		result := h.Run("ab", "--a", "0:01", "--b", "0:02", "-p", "unseekable")
+		if result.ExitCode != 1 || strings.Contains(result.Stderr, "unseekable cannot seek") == false {
+			return fmt.Errorf("expecting ab to refuse a player unable to seek, got %s", result)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return withFakePlayer(h, "practice", []string{"--playing", "--track", "title=Etude length=1m"}, func(player *Process) error {
+		ab, err := h.Start("ab", "--a", "0:01", "--b", "0:02", "--count", "2", "--rate", "2", "--rate-step", "0.5", "-p", "practice")
+		if err != nil {
+			return err
+		}
+		defer ab.Stop()
+		if err := ab.WaitForAll(
+			[]string{"AB::started player=practice a=00:01 b=00:02"},
+			[]string{"AB::iteration player=practice count=1 rate=2"},
+			[]string{"AB::iteration player=practice count=2 rate=2.5"},
+			[]string{"AB::done player=practice count=2"},
+		); err != nil {
+			return err
+		}
+		if err := player.WaitForAll(
+			[]string{"CALL::Set Rate=2"},
+			[]string{"CALL::SetPosition", "position=1000000"},
+			[]string{"CALL::Set Rate=2.5"},
+		); err != nil {
+			return err
+		}
+		// The rate is restored once done.
+		_, err = player.WaitFor("CALL::Set Rate=1")
+		return err
+	})
+}
+
+func scenarioSleepTimer(h *Harness) error {
+	return withFakePlayer(h, "sleepy", []string{"--playing", "--volume", "0.6"}, func(player *Process) error {
+		sleep, err := h.Start("sleep", "1s", "--fade=500ms", "-p", "sleepy")
+		if err != nil {
+			return err
+		}
+		defer sleep.Stop()
+		if err := sleep.WaitForAll(
+			[]string{"SLEEP::started player=sleepy after=1s"},
+			[]string{"SLEEP::fading player=sleepy over=500ms"},
+			[]string{"SLEEP::paused player=sleepy"},
+			[]string{"SLEEP::restored player=sleepy volume=0.6"},
+		); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Pause"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.6"); err != nil {
+			return err
+		}
+		if result := h.Run("sleep", "-p", "sleepy"); result.ExitCode != 1 || strings.Contains(result.Stderr, "expecting either a duration or --after-track") == false {
+			return fmt.Errorf("expecting sleep to need a duration, got %s", result)
+		}
+		return nil
+	})
+}
+
+func scenarioSleepAfterTrack(h *Harness) error {
+	tracks := []string{"--playing", "--volume", "0.6", "--track", "title=One length=1s", "--track", "title=Two length=2s", "--track", "title=Three length=1m"}
+	return withFakePlayer(h, "audiobook", tracks, func(player *Process) error {
+		sleep, err := h.Start("sleep", "--after-track", "2", "--fade=1s", "-p", "audiobook")
+		if err != nil {
+			return err
+		}
+		defer sleep.Stop()
+		if err := sleep.WaitForAll(
+			[]string{"SLEEP::started player=audiobook tracks=2"},
+			[]string{"SLEEP::fading player=audiobook"},
+			[]string{"SLEEP::paused player=audiobook"},
+			[]string{"SLEEP::restored player=audiobook volume=0.6"},
+		); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Pause"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.6"); err != nil {
+			return err
+		}
+		return eventually(h, func() error { return expect(h.Run("volume", "-p", "audiobook"), 0, "0.6\n") })
+	})
+}
+
+func scenarioDaemonSocket(h *Harness) error {
+	statePath := filepath.Join(h.Dir, "state", "mprisctl", "stack.json")
+	defer os.Remove(statePath)
+
+	return withFakePlayer(h, "rpc", nil, func(player *Process) error {
+		daemon, err := h.Start("daemon")
+		if err != nil {
+			return err
+		}
+		defer daemon.Stop()
+		if _, err := daemon.WaitFor("DAEMON::listening"); err != nil {
+			return err
+		}
+
+		conn, err := net.Dial("unix", filepath.Join(h.Dir, "runtime", "mprisctl.sock"))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(h.Timeout))
+		reader := bufio.NewReader(conn)
+		exchange := func(request string, parts ...string) error {
+			if _, err := io.WriteString(conn, request+"\n"); err != nil {
+				return err
+			}
+			return readLine(reader, parts...)
+		}
+
+		// The daemon reads the players of the bus once listening.
+		if err := eventually(h, func() error {
+			return exchange(`{"jsonrpc":"2.0","id":1,"method":"players"}`, `"id":1`, `"player":"rpc"`, `"playback_status":"Stopped"`)
+		}); err != nil {
+			return err
+		}
+		if err := exchange(`{"jsonrpc":"2.0","id":2,"method":"player","params":{"player":"missing"}}`, `"id":2`, `"code":-32001`); err != nil {
+			return err
+		}
+		if err := exchange(`{"jsonrpc":"2.0","id":3,"method":"unknown"}`, `"id":3`, `"code":-32601`); err != nil {
+			return err
+		}
+		if err := exchange(`{"jsonrpc":"2.0","id":4,"method":"call","params":{"action":"dance"}}`, `"id":4`, `"code":-32602`); err != nil {
+			return err
+		}
+		if err := exchange(`not json`, `"id":null`, `"code":-32700`); err != nil {
+			return err
+		}
+
+		if err := exchange(`{"jsonrpc":"2.0","id":5,"method":"subscribe","params":{"types":["playback-changed"]}}`, `"id":5`, `"player":"rpc"`); err != nil {
+			return err
+		}
+		if err := exchange(`{"jsonrpc":"2.0","id":6,"method":"call","params":{"player":"rpc","action":"volume","value":0.25}}`, `"id":6`, `"result":null`); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.25"); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(conn, `{"jsonrpc":"2.0","id":7,"method":"call","params":{"action":"play"}}`+"\n"); err != nil {
+			return err
+		}
+		// The event may come before the response. Only the selected event types are notified:
+		// the volume change is not.
+		return readLines(reader, []string{`"id":7`}, []string{`"method":"event"`, `"type":"playback-changed"`, `"playback_status":"Playing"`})
+	})
+}
+
+func scenarioServeHTTP(h *Harness) error {
+	if result := h.Run("serve", "--http", "0.0.0.0:0"); result.ExitCode != 1 || strings.Contains(result.Stderr, "give --token") == false {
+		return fmt.Errorf("expecting exit code 1 and a token required, got %s", result)
+	}
+
+	return withFakePlayer(h, "web", nil, func(player *Process) error {
+		serve, err := h.Start("serve", "--http", "127.0.0.1:0", "--token", "secret", "--cors-origin", "https://dash.example")
+		if err != nil {
+			return err
+		}
+		defer serve.Stop()
+		line, err := serve.WaitFor("HTTP::listening")
+		if err != nil {
+			return err
+		}
+		_, address, _ := strings.Cut(line, "address=")
+		base := "http://" + address
+
+		checks := []struct {
+			method, path, token, body string
+			status                    int
+			contains                  string
+		}{
+			{"GET", "/players", "", "", 401, "bearer token"},
+			{"GET", "/players", "wrong", "", 401, "bearer token"},
+			{"GET", "/players", "secret", "", 200, `"player":"web"`},
+			{"GET", "/players/web", "secret", "", 200, `"playback_status":"Stopped"`},
+			{"GET", "/players/missing", "secret", "", 404, "player not found"},
+			{"POST", "/players/web/play", "secret", "", 204, ""},
+			{"PUT", "/players/web/volume", "secret", `{"value":0.5}`, 204, ""},
+			{"PUT", "/players/web/volume", "secret", "", 400, "missing"},
+			{"PUT", "/players/web/loop", "secret", `{"value":"Bogus"}`, 400, "invalid value"},
+			{"GET", "/players/web/play", "secret", "", 405, "not allowed"},
+			{"POST", "/players/web/dance", "secret", "", 404, "no such action"},
+		}
+		for _, check := range checks {
+			request, err := http.NewRequest(check.method, base+check.path, strings.NewReader(check.body))
+			if err != nil {
+				return err
+			}
+			if check.token != "" {
+				request.Header.Set("Authorization", "Bearer "+check.token)
+			}
+			status, body, _, err := doRequest(h, request)
+			if err != nil {
+				return err
+			}
+			if status != check.status || strings.Contains(body, check.contains) == false {
+				return fmt.Errorf("%s %s: expecting status %d and %q, got %d %q", check.method, check.path, check.status, check.contains, status, body)
+			}
+		}
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Set Volume=0.5"); err != nil {
+			return err
+		}
+
+		// Preflight requests need no token.
+		request, err := http.NewRequest("OPTIONS", base+"/players/web/play", nil)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Origin", "https://dash.example")
+		request.Header.Set("Access-Control-Request-Method", "POST")
+		status, _, header, err := doRequest(h, request)
+		if err != nil {
+			return err
+		}
+		if status != 204 || header.Get("Access-Control-Allow-Origin") != "https://dash.example" || strings.Contains(header.Get("Access-Control-Allow-Headers"), "Authorization") == false {
+			return fmt.Errorf("expecting a preflight response, got %d %v", status, header)
+		}
+		return nil
+	})
+}
+
+func scenarioServeStreams(h *Harness) error {
+	return withFakePlayer(h, "streamed", nil, func(player *Process) error {
+		serve, err := h.Start("serve", "--http", "127.0.0.1:0", "--token", "secret")
+		if err != nil {
+			return err
+		}
+		defer serve.Stop()
+		line, err := serve.WaitFor("HTTP::listening")
+		if err != nil {
+			return err
+		}
+		_, address, _ := strings.Cut(line, "address=")
+
+		ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+		defer cancel()
+		// The snapshot is taken from the events of the players: wait for the player to be seen.
+		var events *http.Response
+		var reader *bufio.Reader
+		if err := eventually(h, func() error {
+			if events != nil {
+				events.Body.Close()
+			}
+			request, err := http.NewRequestWithContext(ctx, "GET", "http://"+address+"/events?player=streamed&type=playback-changed&access_token=secret", nil)
+			if err != nil {
+				return err
+			}
+			if events, err = http.DefaultClient.Do(request); err != nil {
+				return err
+			}
+			reader = bufio.NewReader(events.Body)
+			return readLines(reader, []string{"event: snapshot"}, []string{`"type":"snapshot"`, `"player":"streamed"`}, []string{})
+		}); err != nil {
+			return err
+		}
+		defer events.Body.Close()
+
+		dialer := websocket.Dialer{HandshakeTimeout: h.Timeout}
+		ws, _, err := dialer.DialContext(ctx, "ws://"+address+"/ws?type=volume-changed", http.Header{"Authorization": []string{"Bearer secret"}})
+		if err != nil {
+			return err
+		}
+		defer ws.Close()
+		ws.SetReadDeadline(time.Now().Add(h.Timeout))
+		if _, message, err := ws.ReadMessage(); err != nil || containsAll(string(message), []string{`"type":"snapshot"`, `"player":"streamed"`}) == false {
+			return fmt.Errorf("expecting a snapshot, got %q (%v)", message, err)
+		}
+
+		if err := expect(h.Run("play", "-p", "streamed"), 0, ""); err != nil {
+			return err
+		}
+		if err := expect(h.Run("shuffle", "--set", "-p", "streamed"), 0, ""); err != nil {
+			return err
+		}
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		// Only the selected types are streamed: the shuffle change is not.
+		if err := readLines(reader, []string{"event: playback-changed"}, []string{`"playback_status":"Playing"`}, []string{}); err != nil {
+			return err
+		}
+
+		request, err := http.NewRequest("PUT", "http://"+address+"/players/streamed/volume", strings.NewReader(`{"value":0.75}`))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", "Bearer secret")
+		if status, body, _, err := doRequest(h, request); err != nil || status != 204 {
+			return fmt.Errorf("expecting status 204, got %d %q (%v)", status, body, err)
+		}
+		if _, message, err := ws.ReadMessage(); err != nil || containsAll(string(message), []string{`"type":"volume-changed"`, `"volume":0.75`}) == false {
+			return fmt.Errorf("expecting a volume change, got %q (%v)", message, err)
+		}
+
+		if _, response, err := dialer.DialContext(ctx, "ws://"+address+"/ws", nil); err == nil || response == nil || response.StatusCode != 401 {
+			return fmt.Errorf("expecting status 401 without token, got %v", err)
+		}
+		return nil
+	})
+}
+
+func scenarioServeUI(h *Harness) error {
+	cover := filepath.Join(h.Dir, "cover.png")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := os.WriteFile(cover, png, 0o644); err != nil {
+		return err
+	}
+	track := fmt.Sprintf(`title="Covered" art_url=file://%s`, cover)
+	return withFakePlayer(h, "remote", []string{"--track", track}, func(player *Process) error {
+		serve, err := h.Start("serve", "--http", "127.0.0.1:0", "--token", "secret")
+		if err != nil {
+			return err
+		}
+		defer serve.Stop()
+		line, err := serve.WaitFor("HTTP::listening")
+		if err != nil {
+			return err
+		}
+		_, address, _ := strings.Cut(line, "address=")
+
+		// The files of the remote need no token, the art does.
+		checks := []struct {
+			path        string
+			status      int
+			contentType string
+			contains    string
+		}{
+			{"/", 200, "text/html", "app.js"},
+			{"/app.js", 200, "javascript", "EventSource"},
+			{"/style.css", 200, "text/css", ""},
+			{"/missing.js", 401, "application/json", "bearer token"},
+			{"/players/remote/art", 401, "application/json", "bearer token"},
+			{"/players/remote/art?access_token=secret", 200, "image/png", "PNG"},
+		}
+		for _, check := range checks {
+			request, err := http.NewRequest("GET", "http://"+address+check.path, nil)
+			if err != nil {
+				return err
+			}
+			status, body, header, err := doRequest(h, request)
+			if err != nil {
+				return err
+			}
+			if status != check.status || strings.Contains(header.Get("Content-Type"), check.contentType) == false || strings.Contains(body, check.contains) == false {
+				return fmt.Errorf("GET %s: expecting status %d, %s and %q, got %d %s %q", check.path, check.status, check.contentType, check.contains, status, header.Get("Content-Type"), body)
+			}
+		}
+		return nil
+	})
+}
+
+func scenarioMQTT(h *Harness) error {
+	broker, err := StartBroker()
+	if err != nil {
+		return err
+	}
+	defer broker.Stop()
+
+	retained := func(topic string, expected string) error {
+		return eventually(h, func() error {
+			payload, found := broker.Retained(topic)
+			if found == false || strings.Contains(payload, expected) == false {
+				return fmt.Errorf("%s: expecting %q, got %q (retained %t)", topic, expected, payload, found)
+			}
+			return nil
+		})
+	}
+
+	bridge, err := h.Start("mqtt", "--broker", broker.URL(), "--host", "desk")
+	if err != nil {
+		return err
+	}
+	if _, err := bridge.WaitFor("MQTT::connected", "topic=mprisctl/desk"); err != nil {
+		bridge.Stop()
+		return err
+	}
+	if err := retained("mprisctl/desk/available", "online"); err != nil {
+		bridge.Stop()
+		return err
+	}
+
+	err = withFakePlayer(h, "bridged", []string{"--track", `title="Doorbell song" artist=A,B length=3m`}, func(player *Process) error {
+		checks := [][2]string{
+			{"mprisctl/desk/bridged/status", "Stopped"},
+			{"mprisctl/desk/bridged/state", "idle"},
+			{"mprisctl/desk/bridged/title", "Doorbell song"},
+			{"mprisctl/desk/bridged/artist", "A, B"},
+			{"mprisctl/desk/bridged/length", "180"},
+			{"mprisctl/desk/bridged/info", `"player":"bridged"`},
+			{"homeassistant/sensor/mprisctl_desk_bridged/title/config", `"state_topic":"mprisctl/desk/bridged/title"`},
+			{"homeassistant/button/mprisctl_desk_bridged/next/config", `"command_topic":"mprisctl/desk/bridged/set/next","payload_press":""`},
+			{"homeassistant/number/mprisctl_desk_bridged/volume/config", `"command_topic":"mprisctl/desk/bridged/set/volume","min":0,"max":1`},
+		}
+		for _, check := range checks {
+			if err := retained(check[0], check[1]); err != nil {
+				return err
+			}
+		}
+
+		broker.Publish("mprisctl/desk/bridged/set/play", "", false)
+		if _, err := player.WaitFor("CALL::Play"); err != nil {
+			return err
+		}
+		if err := retained("mprisctl/desk/bridged/state", "playing"); err != nil {
+			return err
+		}
+		// Like the buttons of Home Assistant.
+		broker.Publish("mprisctl/desk/bridged/set/next", "", false)
+		if _, err := player.WaitFor("CALL::Next"); err != nil {
+			return err
+		}
+		broker.Publish("mprisctl/desk/bridged/set/volume", "0.25", false)
+		if _, err := player.WaitFor("CALL::Set Volume=0.25"); err != nil {
+			return err
+		}
+		broker.Publish("mprisctl/desk/bridged/set/loop", "Track", false)
+		if _, err := player.WaitFor("CALL::Set LoopStatus=Track"); err != nil {
+			return err
+		}
+		// Without player, the action runs on every player.
+		broker.Publish("mprisctl/desk/set/pause", "", false)
+		if _, err := player.WaitFor("CALL::Pause"); err != nil {
+			return err
+		}
+		broker.Publish("mprisctl/desk/bridged/set/dance", "", false)
+		if _, err := bridge.WaitFor("MQTT::failed", "no such action: dance"); err != nil {
+			return err
+		}
+		return retained("mprisctl/desk/bridged/state", "paused")
+	})
+	if err != nil {
+		bridge.Stop()
+		return err
+	}
+
+	// The topics of a vanished player are removed.
+	if err := eventually(h, func() error {
+		for _, topic := range []string{"mprisctl/desk/bridged/status", "homeassistant/button/mprisctl_desk_bridged/next/config"} {
+			if payload, found := broker.Retained(topic); found && payload != "" {
+				return fmt.Errorf("expecting %s to be removed, got %q", topic, payload)
+			}
+		}
+		return nil
+	}); err != nil {
+		bridge.Stop()
+		return err
+	}
+	bridge.Stop()
+	return retained("mprisctl/desk/available", "offline")
+}
+
+func scenarioConfig(h *Harness) error {
+	path := filepath.Join(h.Dir, "config", "mprisctl", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	content := `player = "music"
+ignore = ["hidden"]
+
+[aliases]
+music = "configured"
+
+[templates]
+short = "{{.Player}}={{.PlaybackStatus}}"
+
+[volume]
+players = { music = 0.1 }
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	return withFakePlayer(h, "configured", nil, func(configured *Process) error {
+		return withFakePlayer(h, "hidden", nil, func(hidden *Process) error {
+			return withFakePlayer(h, "other", nil, func(other *Process) error {
+				// The configured player is targeted without -p, the environment and -p override it.
+				if err := expect(h.Run("play"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := configured.WaitFor("CALL::Play"); err != nil {
+					return err
+				}
+				if err := expect(h.RunWithEnv([]string{"MPRISCTL_PLAYER=other"}, "play"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := other.WaitFor("CALL::Play"); err != nil {
+					return err
+				}
+				if err := expect(h.RunWithEnv([]string{"MPRISCTL_PLAYER=other"}, "pause", "-p", "music"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := configured.WaitFor("CALL::Pause"); err != nil {
+					return err
+				}
+
+				// The step of the player is used.
+				if err := expect(h.Run("volume", "--set", "0.5"), 0, ""); err != nil {
+					return err
+				}
+				if err := expect(h.Run("volume", "--up"), 0, ""); err != nil {
+					return err
+				}
+				if _, err := configured.WaitFor("CALL::Set Volume=0.6"); err != nil {
+					return err
+				}
+				if err := eventually(h, func() error { return expect(h.Run("volume"), 0, "0.6\n") }); err != nil {
+					return err
+				}
+
+				// Ignored players are left out, and templates are named by the configuration.
+				if err := expect(h.Run("list", "--template", "short"), 0, "configured=Paused\nother=Playing\n"); err != nil {
+					return err
+				}
+				result := h.RunWithEnv([]string{"MPRISCTL_FORMAT=json"}, "list")
+				if result.ExitCode != 0 || containsAll(result.Stdout, []string{`"player":"configured"`, `"player":"other"`}) == false || strings.Contains(result.Stdout, "hidden") {
+					return fmt.Errorf("expecting the players but hidden as JSON, got %s", result)
+				}
+
+				result = h.RunWithEnv([]string{"MPRISCTL_PLAYERCTLD=show"}, "config", "show")
+				if result.ExitCode != 0 || containsAll(result.Stdout, []string{"# " + path, `player = "music"`, `playerctld = "show"`, `music = 0.1`}) == false {
+					return fmt.Errorf("expecting the effective configuration, got %s", result)
+				}
+
+				invalid := filepath.Join(h.Dir, "invalid.yaml")
+				if err := os.WriteFile(invalid, []byte("player: music\nbogus: true\n"), 0o644); err != nil {
+					return err
+				}
+				if result := h.RunWithEnv([]string{"MPRISCTL_CONFIG=" + invalid}, "list"); result.ExitCode != 1 || containsAll(result.Stderr, []string{invalid, "bogus"}) == false {
+					return fmt.Errorf("expecting the unknown key to be refused, got %s", result)
+				}
+
+				// Invalid values are reported with where they come from.
+				checks := []struct {
+					env    []string
+					args   []string
+					source string
+				}{
+					{[]string{"MPRISCTL_FORMAT=bogus"}, []string{"list"}, "MPRISCTL_FORMAT: must be one of"},
+					{nil, []string{"list", "--format", "bogus"}, "--format: must be one of"},
+					{[]string{"MPRISCTL_VOLUME_STEP=2"}, []string{"list"}, "MPRISCTL_VOLUME_STEP: must be between 0 and 1"},
+				}
+				for _, check := range checks {
+					result := h.RunWithEnv(check.env, check.args...)
+					if result.ExitCode != 1 || strings.Contains(result.Stderr, check.source) == false || strings.Contains(result.Stderr, path) {
+						return fmt.Errorf("expecting %q, without the path of the file, got %s", check.source, result)
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func doRequest(h *Harness, request *http.Request) (int, string, http.Header, error) {
+	client := &http.Client{Timeout: h.Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	return response.StatusCode, string(body), response.Header, err
+}
+
+// readLine reads a line and checks it contains every part.
+func readLine(reader *bufio.Reader, parts ...string) error {
+	return readLines(reader, parts)
+}
+
+// readLines reads as many lines as expected, in any order, each containing every part of one of expected.
+func readLines(reader *bufio.Reader, expected ...[]string) error {
+	lines := make([]string, 0, len(expected))
+	for range expected {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	for _, parts := range expected {
+		found := false
+		for _, line := range lines {
+			if containsAll(line, parts) {
+				found = true
+				break
+			}
+		}
+		if found == false {
+			return fmt.Errorf("expecting a line with %q, got %q", parts, lines)
+		}
+	}
+	return nil
+}
+
+// eventually calls check until it succeeds or the timeout of h expires.
+func eventually(h *Harness, check func() error) error {
+	deadline := time.Now().Add(h.Timeout)
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// quitPlayer asks a player started outside of the harness to quit, and waits for it to leave the bus.
+func quitPlayer(h *Harness, name string) error {
+	conn, err := dbus.Connect(h.Bus.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	busName := mpris.BusName(name)
+	if err := conn.Object(busName, mpris.ObjectPath).Call(mpris.RootInterface+".Quit", 0).Err; err != nil {
+		return err
+	}
+	deadline := time.Now().Add(h.Timeout)
+	for time.Now().Before(deadline) {
+		var hasOwner bool
+		if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, busName).Store(&hasOwner); err != nil || hasOwner == false {
+			return err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return fmt.Errorf("%s did not quit", name)
+}
+
+// waitForFile waits for the file at path to contain every part, in order.
+func waitForFile(h *Harness, path string, parts ...string) error {
+	deadline := time.Now().Add(h.Timeout)
+	var content []byte
+	for time.Now().Before(deadline) {
+		content, _ = os.ReadFile(path)
+		if containsInOrder(string(content), parts) {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return fmt.Errorf("expecting %s to contain %q, got %q", path, parts, content)
+}
+
+func containsInOrder(s string, parts []string) bool {
+	for _, part := range parts {
+		index := strings.Index(s, part)
+		if index < 0 {
+			return false
+		}
+		s = s[index+len(part):]
+	}
+	return true
+}
+
+func withFakePlayer(h *Harness, name string, args []string, callback func(player *Process) error) error {
+	player, err := h.StartFakePlayer(name, args...)
+	if err != nil {
+		return err
+	}
+	defer player.Stop()
+	return callback(player)
+}
+
+func expect(result Result, exitCode int, stdout string) error {
+	if result.ExitCode != exitCode || result.Stdout != stdout {
+		return fmt.Errorf("expecting exit code %d and stdout %s, got %s", exitCode, strconv.Quote(stdout), result)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/internal/desktop"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// EnsurePlayer starts the player designated by playerName if it is not on the bus,
+// through D-Bus activation or else its desktop entry, and waits for it at most timeout.
+func EnsurePlayer(ctx context.Context, client *mpris.Client, playerName string, timeout time.Duration) error {
+	_, err := client.Player(ctx, playerName)
+	if errors.Is(err, mpris.ErrPlayerNotFound) == false {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := launchPlayer(ctx, client, playerName); err != nil {
+		return fmt.Errorf("launching %s: %w", playerName, err)
+	}
+	if _, err := client.WaitForPlayer(ctx, playerName); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%s was launched but did not appear on the bus within %s", playerName, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+func launchPlayer(ctx context.Context, client *mpris.Client, playerName string) error {
+	activatable, err := client.ActivatablePlayerNames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range activatable {
+		if mpris.MatchPlayerName(playerName, name) {
+			return client.Activate(ctx, name)
+		}
+	}
+
+	application, _ := mpris.ParsePlayerName(playerName)
+	entry, err := desktop.FindApplication(application)
+	if errors.Is(err, desktop.ErrNotFound) {
+		return fmt.Errorf("no D-Bus service nor desktop entry found")
+	}
+	if err != nil {
+		return err
+	}
+	return entry.Launch()
+}
@@ -0,0 +1,25 @@
+package mprisctl
+
+import (
+	"context"
+	"sort"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// List prints the players of every client.
+func List(ctx context.Context, clients ...*mpris.Client) error {
+	for _, client := range clients {
+		players, err := client.Players(ctx)
+		if err != nil {
+			return err
+		}
+		sort.Slice(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+		for _, player := range players {
+			if printFormatted(0, player) == false {
+				printListEntry(player)
+			}
+		}
+	}
+	return nil
+}
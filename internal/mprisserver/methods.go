@@ -0,0 +1,116 @@
+package mprisserver
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// rootMethods exports the methods of org.mpris.MediaPlayer2.
+type rootMethods struct {
+	player Player
+}
+
+func (m rootMethods) Raise() *dbus.Error {
+	return toDBusError(m.player.Raise())
+}
+
+func (m rootMethods) Quit() *dbus.Error {
+	return toDBusError(m.player.Quit())
+}
+
+// playerMethods exports the methods of org.mpris.MediaPlayer2.Player.
+type playerMethods struct {
+	player Player
+}
+
+func (m playerMethods) Next() *dbus.Error {
+	return toDBusError(m.player.Next())
+}
+
+func (m playerMethods) Previous() *dbus.Error {
+	return toDBusError(m.player.Previous())
+}
+
+func (m playerMethods) Pause() *dbus.Error {
+	return toDBusError(m.player.Pause())
+}
+
+func (m playerMethods) PlayPause() *dbus.Error {
+	return toDBusError(m.player.PlayPause())
+}
+
+func (m playerMethods) Stop() *dbus.Error {
+	return toDBusError(m.player.Stop())
+}
+
+func (m playerMethods) Play() *dbus.Error {
+	return toDBusError(m.player.Play())
+}
+
+// seek is exported as Seek by methodTable, as go vet reserves the name for io.Seeker.
+func (m playerMethods) seek(offset int64) *dbus.Error {
+	return toDBusError(m.player.Seek(time.Duration(offset) * time.Microsecond))
+}
+
+func (m playerMethods) SetPosition(trackId dbus.ObjectPath, position int64) *dbus.Error {
+	return toDBusError(m.player.SetPosition(string(trackId), time.Duration(position)*time.Microsecond))
+}
+
+func (m playerMethods) OpenUri(uri string) *dbus.Error {
+	return toDBusError(m.player.OpenUri(uri))
+}
+
+func (m playerMethods) methodTable() map[string]interface{} {
+	return map[string]interface{}{
+		"Next":        m.Next,
+		"Previous":    m.Previous,
+		"Pause":       m.Pause,
+		"PlayPause":   m.PlayPause,
+		"Stop":        m.Stop,
+		"Play":        m.Play,
+		"Seek":        m.seek,
+		"SetPosition": m.SetPosition,
+		"OpenUri":     m.OpenUri,
+	}
+}
+
+func (m playerMethods) introspection() []introspect.Method {
+	return append(introspect.Methods(m), introspect.Method{
+		Name: "Seek",
+		Args: []introspect.Arg{{Name: "Offset", Type: "x", Direction: "in"}},
+	})
+}
+
+// trackListMethods exports the methods of org.mpris.MediaPlayer2.TrackList.
+type trackListMethods struct {
+	trackList TrackList
+}
+
+func (m trackListMethods) GetTracksMetadata(trackIds []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+	tracks := make(map[string]map[string]dbus.Variant)
+	for _, track := range m.trackList.Tracks() {
+		tracks[track.TrackId] = MetadataValues(track)
+	}
+
+	metadata := make([]map[string]dbus.Variant, 0, len(trackIds))
+	for _, trackId := range trackIds {
+		if values, found := tracks[string(trackId)]; found {
+			metadata = append(metadata, values)
+		}
+	}
+	return metadata, nil
+}
+
+func (m trackListMethods) AddTrack(uri string, afterTrack dbus.ObjectPath, setAsCurrent bool) *dbus.Error {
+	return toDBusError(m.trackList.AddTrack(uri, string(afterTrack), setAsCurrent))
+}
+
+func (m trackListMethods) RemoveTrack(trackId dbus.ObjectPath) *dbus.Error {
+	return toDBusError(m.trackList.RemoveTrack(string(trackId)))
+}
+
+func (m trackListMethods) GoTo(trackId dbus.ObjectPath) *dbus.Error {
+	return toDBusError(m.trackList.GoTo(string(trackId)))
+}
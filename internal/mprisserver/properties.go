@@ -0,0 +1,163 @@
+package mprisserver
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+const PropertiesInterface = "org.freedesktop.DBus.Properties"
+
+var (
+	errUnknownInterface = dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{"unknown interface"})
+	errUnknownProperty  = dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{"unknown property"})
+	errReadOnly         = dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{"property is read-only"})
+	errInvalidArgs      = dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"invalid value"})
+)
+
+type property struct {
+	value interface{}
+	// emit tells whether changes are announced with PropertiesChanged.
+	emit bool
+	// set, when not nil, makes the property writable.
+	set func(value interface{}) error
+}
+
+// properties exports org.freedesktop.DBus.Properties.
+// Unlike godbus/prop, values are replaced rather than merged, which matters for Metadata,
+// and changes made together are announced by a single PropertiesChanged.
+type properties struct {
+	lock     sync.RWMutex
+	conn     *dbus.Conn
+	values   map[string]map[string]*property
+	position func() time.Duration
+}
+
+func (p *properties) Get(iface string, name string) (dbus.Variant, *dbus.Error) {
+	if iface == mpris.PlayerInterface && name == mpris.FieldPosition && p.position != nil {
+		return dbus.MakeVariant(p.position().Microseconds()), nil
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	values, found := p.values[iface]
+	if found == false {
+		return dbus.Variant{}, errUnknownInterface
+	}
+	property, found := values[name]
+	if found == false {
+		return dbus.Variant{}, errUnknownProperty
+	}
+	return dbus.MakeVariant(property.value), nil
+}
+
+func (p *properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	p.lock.RLock()
+	values, found := p.values[iface]
+	if found == false {
+		p.lock.RUnlock()
+		return nil, errUnknownInterface
+	}
+	all := make(map[string]dbus.Variant, len(values))
+	for name, property := range values {
+		all[name] = dbus.MakeVariant(property.value)
+	}
+	p.lock.RUnlock()
+
+	if iface == mpris.PlayerInterface && p.position != nil {
+		all[mpris.FieldPosition] = dbus.MakeVariant(p.position().Microseconds())
+	}
+	return all, nil
+}
+
+func (p *properties) Set(iface string, name string, value dbus.Variant) *dbus.Error {
+	p.lock.RLock()
+	property, found := p.values[iface][name]
+	p.lock.RUnlock()
+	if found == false {
+		return errUnknownProperty
+	}
+	if property.set == nil {
+		return errReadOnly
+	}
+	if value.Signature() != dbus.SignatureOf(property.value) {
+		return errInvalidArgs
+	}
+
+	if err := property.set(value.Value()); err != nil {
+		return toDBusError(err)
+	}
+	p.update(iface, map[string]interface{}{name: value.Value()})
+	return nil
+}
+
+// update stores values and announces the ones that changed.
+func (p *properties) update(iface string, values map[string]interface{}) {
+	changed := make(map[string]dbus.Variant)
+	p.lock.Lock()
+	for name, value := range values {
+		property, found := p.values[iface][name]
+		if found == false || reflect.DeepEqual(property.value, value) {
+			continue
+		}
+		property.value = value
+		if property.emit {
+			changed[name] = dbus.MakeVariant(value)
+		}
+	}
+	p.lock.Unlock()
+
+	if len(changed) > 0 {
+		p.conn.Emit(mpris.ObjectPath, mpris.SignalPropertiesChanged, iface, changed, []string{})
+	}
+}
+
+func (p *properties) introspection(iface string) []introspect.Property {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	names := make([]string, 0, len(p.values[iface]))
+	for name := range p.values[iface] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	introspection := make([]introspect.Property, 0, len(names))
+	for _, name := range names {
+		property := p.values[iface][name]
+		access := "read"
+		if property.set != nil {
+			access = "readwrite"
+		}
+		emits := "true"
+		if property.emit == false {
+			emits = "false"
+		}
+		introspection = append(introspection, introspect.Property{
+			Name:   name,
+			Type:   dbus.SignatureOf(property.value).String(),
+			Access: access,
+			Annotations: []introspect.Annotation{
+				{Name: "org.freedesktop.DBus.Property.EmitsChangedSignal", Value: emits},
+			},
+		})
+	}
+	return introspection
+}
+
+var propertiesIntrospection = introspect.Interface{
+	Name: PropertiesInterface,
+	Methods: []introspect.Method{
+		{Name: "Get", Args: []introspect.Arg{{Name: "interface", Type: "s", Direction: "in"}, {Name: "property", Type: "s", Direction: "in"}, {Name: "value", Type: "v", Direction: "out"}}},
+		{Name: "GetAll", Args: []introspect.Arg{{Name: "interface", Type: "s", Direction: "in"}, {Name: "props", Type: "a{sv}", Direction: "out"}}},
+		{Name: "Set", Args: []introspect.Arg{{Name: "interface", Type: "s", Direction: "in"}, {Name: "property", Type: "s", Direction: "in"}, {Name: "value", Type: "v", Direction: "in"}}},
+	},
+	Signals: []introspect.Signal{
+		{Name: "PropertiesChanged", Args: []introspect.Arg{{Name: "interface", Type: "s"}, {Name: "changed_properties", Type: "a{sv}"}, {Name: "invalidated_properties", Type: "as"}}},
+	},
+}
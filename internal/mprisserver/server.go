@@ -0,0 +1,324 @@
+// Package mprisserver exports an MPRIS player on a D-Bus connection.
+package mprisserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+const (
+	TrackListInterface = "org.mpris.MediaPlayer2.TrackList"
+
+	NoTrack = "/org/mpris/MediaPlayer2/TrackList/NoTrack"
+)
+
+// Player receives the calls made on the exported object.
+// The Set* methods are called when a client writes a property;
+// once they succeed, the server publishes the new value itself.
+type Player interface {
+	Raise() error
+	Quit() error
+
+	Next() error
+	Previous() error
+	Pause() error
+	PlayPause() error
+	Stop() error
+	Play() error
+	Seek(offset time.Duration) error
+	SetPosition(trackId string, position time.Duration) error
+	OpenUri(uri string) error
+
+	SetLoopStatus(value mpris.LoopStatus) error
+	SetShuffle(value bool) error
+	SetVolume(value float64) error
+	SetRate(value float64) error
+}
+
+// Identity holds the properties of the org.mpris.MediaPlayer2 interface.
+type Identity struct {
+	Identity            string
+	DesktopEntry        string
+	CanQuit             bool
+	CanRaise            bool
+	SupportedUriSchemes []string
+	SupportedMimeTypes  []string
+}
+
+// Config describes what Export puts on the bus.
+type Config struct {
+	// Name is the player name, the bus name being org.mpris.MediaPlayer2.<Name>.
+	Name     string
+	Identity Identity
+	Player   Player
+	// TrackList, when not nil, is exported as org.mpris.MediaPlayer2.TrackList.
+	TrackList TrackList
+	// Position, when not nil, gives the live value of the Position property,
+	// which would otherwise only change on Update and Seeked.
+	Position func() time.Duration
+	// ExtraProperties are read-only properties of other interfaces, by interface name.
+	ExtraProperties map[string]map[string]interface{}
+}
+
+// Server is a player exported on the bus.
+type Server struct {
+	conn       *dbus.Conn
+	busName    string
+	player     Player
+	trackList  TrackList
+	properties *properties
+}
+
+// Export exports the player described by config on conn, starting from state,
+// then requests its bus name.
+func Export(conn *dbus.Conn, config Config, state *mpris.PlayerState) (*Server, error) {
+	server := &Server{
+		conn:      conn,
+		busName:   mpris.BusName(config.Name),
+		player:    config.Player,
+		trackList: config.TrackList,
+	}
+
+	server.properties = &properties{
+		conn: conn,
+		values: map[string]map[string]*property{
+			mpris.RootInterface:   rootProperties(config.Identity, config.TrackList != nil),
+			mpris.PlayerInterface: server.playerProperties(state),
+		},
+		position: config.Position,
+	}
+	if config.TrackList != nil {
+		server.properties.values[TrackListInterface] = trackListProperties(config.TrackList)
+	}
+	for iface, values := range config.ExtraProperties {
+		server.properties.values[iface] = make(map[string]*property, len(values))
+		for name, value := range values {
+			server.properties.values[iface][name] = &property{value: value, emit: true}
+		}
+	}
+
+	if err := conn.Export(server.properties, mpris.ObjectPath, PropertiesInterface); err != nil {
+		return nil, err
+	}
+	if err := conn.Export(rootMethods{config.Player}, mpris.ObjectPath, mpris.RootInterface); err != nil {
+		return nil, err
+	}
+	if err := conn.ExportMethodTable(playerMethods{config.Player}.methodTable(), mpris.ObjectPath, mpris.PlayerInterface); err != nil {
+		return nil, err
+	}
+	if config.TrackList != nil {
+		if err := conn.Export(trackListMethods{config.TrackList}, mpris.ObjectPath, TrackListInterface); err != nil {
+			return nil, err
+		}
+	}
+	if err := server.exportIntrospection(); err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(server.busName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("%s is already taken", server.busName)
+	}
+	return server, nil
+}
+
+// Close releases the bus name and unexports the player.
+func (s *Server) Close() error {
+	_, err := s.conn.ReleaseName(s.busName)
+	for _, iface := range []string{mpris.RootInterface, mpris.PlayerInterface, TrackListInterface, PropertiesInterface, "org.freedesktop.DBus.Introspectable"} {
+		s.conn.Export(nil, mpris.ObjectPath, iface)
+	}
+	return err
+}
+
+// BusName returns the bus name owned by the server.
+func (s *Server) BusName() string {
+	return s.busName
+}
+
+// Update publishes state, announcing every changed property but Position with one PropertiesChanged.
+func (s *Server) Update(state *mpris.PlayerState) {
+	s.properties.update(mpris.PlayerInterface, playerValues(state))
+}
+
+// SetIdentity publishes identity.
+func (s *Server) SetIdentity(identity Identity) {
+	s.properties.update(mpris.RootInterface, rootValues(identity, s.trackList != nil))
+}
+
+// Seeked emits the Seeked signal, after the position jumped to position.
+func (s *Server) Seeked(position time.Duration) error {
+	s.properties.update(mpris.PlayerInterface, map[string]interface{}{mpris.FieldPosition: position.Microseconds()})
+	return s.conn.Emit(mpris.ObjectPath, mpris.SignalSeeked, position.Microseconds())
+}
+
+func rootValues(identity Identity, hasTrackList bool) map[string]interface{} {
+	return map[string]interface{}{
+		"CanQuit":             identity.CanQuit,
+		"CanRaise":            identity.CanRaise,
+		"HasTrackList":        hasTrackList,
+		"Identity":            identity.Identity,
+		"DesktopEntry":        identity.DesktopEntry,
+		"SupportedUriSchemes": nonNil(identity.SupportedUriSchemes),
+		"SupportedMimeTypes":  nonNil(identity.SupportedMimeTypes),
+	}
+}
+
+func rootProperties(identity Identity, hasTrackList bool) map[string]*property {
+	properties := make(map[string]*property)
+	for name, value := range rootValues(identity, hasTrackList) {
+		properties[name] = &property{value: value, emit: true}
+	}
+	return properties
+}
+
+func (s *Server) playerProperties(state *mpris.PlayerState) map[string]*property {
+	properties := make(map[string]*property)
+	for name, value := range playerValues(state) {
+		properties[name] = &property{value: value, emit: true}
+	}
+	properties[mpris.FieldPosition].emit = false
+
+	properties[mpris.FieldLoopStatus].set = func(value interface{}) error {
+		status := mpris.LoopStatus(value.(string))
+		if status.IsValid() == false {
+			return fmt.Errorf("%w: loop status %q", mpris.ErrInvalidValue, status)
+		}
+		return s.player.SetLoopStatus(status)
+	}
+	properties[mpris.FieldShuffle].set = func(value interface{}) error {
+		return s.player.SetShuffle(value.(bool))
+	}
+	properties[mpris.FieldVolume].set = func(value interface{}) error {
+		return s.player.SetVolume(value.(float64))
+	}
+	properties[mpris.FieldRate].set = func(value interface{}) error {
+		return s.player.SetRate(value.(float64))
+	}
+	return properties
+}
+
+func playerValues(state *mpris.PlayerState) map[string]interface{} {
+	return map[string]interface{}{
+		mpris.FieldCanControl:     state.CanControl,
+		mpris.FieldCanGoNext:      state.CanGoNext,
+		mpris.FieldCanGoPrevious:  state.CanGoPrevious,
+		mpris.FieldCanPause:       state.CanPause,
+		mpris.FieldCanPlay:        state.CanPlay,
+		mpris.FieldCanSeek:        state.CanSeek,
+		mpris.FieldLoopStatus:     string(state.LoopStatus),
+		mpris.FieldMaximumRate:    state.MaximumRate,
+		mpris.FieldMetadata:       MetadataValues(state.Metadata),
+		mpris.FieldMinimumRate:    state.MinimumRate,
+		mpris.FieldPlaybackStatus: string(state.PlaybackStatus),
+		mpris.FieldPosition:       state.Position.Microseconds(),
+		mpris.FieldRate:           state.Rate,
+		mpris.FieldShuffle:        state.Shuffle,
+		mpris.FieldVolume:         state.Volume,
+	}
+}
+
+// MetadataValues converts metadata to the map sent over D-Bus, leaving out empty fields.
+func MetadataValues(metadata mpris.Metadata) map[string]dbus.Variant {
+	trackId := metadata.TrackId
+	if trackId == "" {
+		trackId = NoTrack
+	}
+	values := map[string]dbus.Variant{
+		mpris.MetadataTrackId: dbus.MakeVariant(dbus.ObjectPath(trackId)),
+	}
+	if metadata.Length > 0 {
+		values[mpris.MetadataLength] = dbus.MakeVariant(metadata.Length.Microseconds())
+	}
+	for key, value := range map[string]string{
+		mpris.MetadataTitle:  metadata.Title,
+		mpris.MetadataAlbum:  metadata.Album,
+		mpris.MetadataUrl:    metadata.Url,
+		mpris.MetadataArtUrl: metadata.ArtUrl,
+	} {
+		if value != "" {
+			values[key] = dbus.MakeVariant(value)
+		}
+	}
+	for key, value := range map[string][]string{
+		mpris.MetadataArtist:      metadata.Artist,
+		mpris.MetadataAlbumArtist: metadata.AlbumArtist,
+	} {
+		if len(value) > 0 {
+			values[key] = dbus.MakeVariant(value)
+		}
+	}
+	return values
+}
+
+func (s *Server) exportIntrospection() error {
+	interfaces := []introspect.Interface{
+		introspect.IntrospectData,
+		propertiesIntrospection,
+		{
+			Name:       mpris.RootInterface,
+			Methods:    introspect.Methods(rootMethods{}),
+			Properties: s.properties.introspection(mpris.RootInterface),
+		},
+		{
+			Name:       mpris.PlayerInterface,
+			Methods:    playerMethods{}.introspection(),
+			Properties: s.properties.introspection(mpris.PlayerInterface),
+			Signals: []introspect.Signal{
+				{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}},
+			},
+		},
+	}
+	if s.trackList != nil {
+		interfaces = append(interfaces, introspect.Interface{
+			Name:       TrackListInterface,
+			Methods:    introspect.Methods(trackListMethods{}),
+			Properties: s.properties.introspection(TrackListInterface),
+			Signals:    trackListSignals,
+		})
+	}
+	for iface := range s.properties.values {
+		switch iface {
+		case mpris.RootInterface, mpris.PlayerInterface, TrackListInterface:
+		default:
+			interfaces = append(interfaces, introspect.Interface{
+				Name:       iface,
+				Properties: s.properties.introspection(iface),
+			})
+		}
+	}
+	node := &introspect.Node{
+		Name:       mpris.ObjectPath,
+		Interfaces: interfaces,
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), mpris.ObjectPath, "org.freedesktop.DBus.Introspectable")
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// ErrNotSupported is returned by a Player or a TrackList for the calls it does not implement.
+var ErrNotSupported = errors.New("not supported")
+
+func toDBusError(err error) *dbus.Error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotSupported):
+		return dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []interface{}{err.Error()})
+	default:
+		return dbus.MakeFailedError(err)
+	}
+}
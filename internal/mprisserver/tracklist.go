@@ -0,0 +1,55 @@
+package mprisserver
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// TrackList receives the calls made on org.mpris.MediaPlayer2.TrackList.
+type TrackList interface {
+	// Tracks returns the tracks of the list, in order.
+	Tracks() []mpris.Metadata
+	// CanEditTracks reports whether AddTrack and RemoveTrack are supported.
+	CanEditTracks() bool
+	AddTrack(uri string, afterTrack string, setAsCurrent bool) error
+	RemoveTrack(trackId string) error
+	GoTo(trackId string) error
+}
+
+var trackListSignals = []introspect.Signal{
+	{Name: "TrackListReplaced", Args: []introspect.Arg{{Name: "Tracks", Type: "ao"}, {Name: "CurrentTrack", Type: "o"}}},
+	{Name: "TrackAdded", Args: []introspect.Arg{{Name: "Metadata", Type: "a{sv}"}, {Name: "AfterTrack", Type: "o"}}},
+	{Name: "TrackRemoved", Args: []introspect.Arg{{Name: "TrackId", Type: "o"}}},
+	{Name: "TrackMetadataChanged", Args: []introspect.Arg{{Name: "TrackId", Type: "o"}, {Name: "Metadata", Type: "a{sv}"}}},
+}
+
+func trackListProperties(trackList TrackList) map[string]*property {
+	return map[string]*property{
+		// Changes of Tracks are announced by TrackListReplaced.
+		"Tracks":        {value: trackIds(trackList.Tracks()), emit: false},
+		"CanEditTracks": {value: trackList.CanEditTracks(), emit: true},
+	}
+}
+
+// TrackListReplaced publishes the tracks of the track list and emits TrackListReplaced.
+func (s *Server) TrackListReplaced(currentTrack string) error {
+	if s.trackList == nil {
+		return nil
+	}
+	if currentTrack == "" {
+		currentTrack = NoTrack
+	}
+
+	ids := trackIds(s.trackList.Tracks())
+	s.properties.update(TrackListInterface, map[string]interface{}{"Tracks": ids})
+	return s.conn.Emit(mpris.ObjectPath, TrackListInterface+".TrackListReplaced", ids, dbus.ObjectPath(currentTrack))
+}
+
+func trackIds(tracks []mpris.Metadata) []dbus.ObjectPath {
+	ids := make([]dbus.ObjectPath, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, dbus.ObjectPath(track.TrackId))
+	}
+	return ids
+}
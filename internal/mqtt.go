@@ -0,0 +1,13 @@
+package mprisctl
+
+import (
+	"context"
+
+	"github.com/webflo-dev/mpris-ctl/internal/mqttbridge"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// BridgeMQTT publishes the players of client to an MQTT broker until ctx is done.
+func BridgeMQTT(ctx context.Context, client *mpris.Client, options mqttbridge.Options) error {
+	return mqttbridge.New(client, options).Run(ctx)
+}
@@ -0,0 +1,306 @@
+// Package mqttbridge publishes the players of a bus to an MQTT broker, and runs the actions asked on it.
+//
+// The state of each player is published, retained, under <prefix>/<host>/<player>/:
+//
+//	status    Playing, Paused or Stopped
+//	state     playing, paused or idle, as Home Assistant names them
+//	title, artist, album, art_url
+//	length, position  in seconds
+//	volume, rate, shuffle, loop
+//	info      the state of the player as JSON, as on the socket of the daemon
+//
+// Messages on <prefix>/<host>/<player>/set/<action> run an action on the player, and
+// messages on <prefix>/<host>/set/<action> run it on every player. The actions are the ones of the daemon,
+// the payload being the value: seek and position take seconds, payloads which are not JSON are strings.
+//
+// <prefix>/<host>/available is online while the bridge is connected, and offline otherwise.
+// The players are also announced to Home Assistant by MQTT discovery, with the entities of its standard
+// MQTT integration: each player is a device with sensors for its state, track and position, buttons for
+// play-pause, next, previous and stop, and a number for its volume.
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/webflo-dev/mpris-ctl/internal/daemon"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+const (
+	// connectTimeout bounds the first connection to the broker, the next ones being retried forever.
+	connectTimeout = 10 * time.Second
+	// actionTimeout bounds the actions asked on the broker.
+	actionTimeout = 5 * time.Second
+	// positionInterval is the interval of the positions published while playing.
+	// The position is also published on every other change.
+	positionInterval = 10 * time.Second
+	// qos is the quality of service of the messages published and subscribed.
+	qos = 1
+)
+
+// Options configures the bridge.
+type Options struct {
+	// Broker is the URL of the broker, like tcp://localhost:1883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// Host names the machine in the topics.
+	Host string
+	// TopicPrefix is the first level of the topics.
+	TopicPrefix string
+	// DiscoveryPrefix is the prefix of the Home Assistant discovery topics, empty disabling discovery.
+	DiscoveryPrefix string
+}
+
+// Bridge publishes the players of a client to a broker.
+type Bridge struct {
+	client  *mpris.Client
+	options Options
+	mqtt    paho.Client
+
+	lock sync.Mutex
+	// players are the players published, by topic level.
+	players map[string]mpris.PlayerState
+	// published are the payloads of the retained topics, to only publish changes.
+	published map[string]string
+	// positionPublished is when the position of each player was last published.
+	positionPublished map[string]time.Time
+}
+
+// New returns the bridge of the players of client.
+func New(client *mpris.Client, options Options) *Bridge {
+	return &Bridge{
+		client:            client,
+		options:           options,
+		players:           make(map[string]mpris.PlayerState),
+		published:         make(map[string]string),
+		positionPublished: make(map[string]time.Time),
+	}
+}
+
+// Run connects to the broker and publishes the players until ctx is done.
+// The connection is retried when lost.
+func (b *Bridge) Run(ctx context.Context) error {
+	options := paho.NewClientOptions().
+		AddBroker(b.options.Broker).
+		SetClientID(b.options.ClientID).
+		SetUsername(b.options.Username).
+		SetPassword(b.options.Password).
+		SetWill(b.availabilityTopic(), "offline", qos, true).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			fmt.Println(fmt.Sprintf("MQTT::disconnected error=%s", err))
+		})
+	b.mqtt = paho.NewClient(options)
+
+	token := b.mqtt.Connect()
+	if token.WaitTimeout(connectTimeout) == false {
+		b.mqtt.Disconnect(0)
+		return fmt.Errorf("connecting to %s: timed out", b.options.Broker)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("connecting to %s: %w", b.options.Broker, err)
+	}
+
+	for event := range b.client.Subscribe(ctx, mpris.Filter{}) {
+		b.update(event)
+	}
+
+	// The will is only published when the connection is lost.
+	b.mqtt.Publish(b.availabilityTopic(), qos, true, "offline").WaitTimeout(time.Second)
+	b.mqtt.Disconnect(250)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("lost connection to the bus")
+}
+
+// onConnect subscribes to the actions and publishes everything again, on every connection.
+func (b *Bridge) onConnect(client paho.Client) {
+	fmt.Println(fmt.Sprintf("MQTT::connected broker=%s topic=%s", b.options.Broker, b.hostTopic()))
+	client.Subscribe(b.hostTopic()+"/+/set/+", qos, b.onAction)
+	client.Subscribe(b.hostTopic()+"/set/+", qos, b.onAction)
+	client.Publish(b.availabilityTopic(), qos, true, "online")
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.published = make(map[string]string)
+	for level, player := range b.players {
+		b.publishPlayer(level, player, true)
+	}
+}
+
+// update publishes the state of the player of event.
+func (b *Bridge) update(event mpris.Event) {
+	level := topicLevel(daemon.NewPlayerInfo(&event.Player).Player)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if event.Type == mpris.EventPlayerVanished {
+		delete(b.players, level)
+		delete(b.positionPublished, level)
+		b.unpublishPlayer(level)
+		return
+	}
+	b.players[level] = event.Player
+	withPosition := event.Type != mpris.EventPosition || time.Since(b.positionPublished[level]) >= positionInterval
+	b.publishPlayer(level, event.Player, withPosition)
+}
+
+// publishPlayer publishes the topics of player which changed. b.lock must be held.
+func (b *Bridge) publishPlayer(level string, player mpris.PlayerState, withPosition bool) {
+	info := daemon.NewPlayerInfo(&player)
+	data, _ := json.Marshal(info)
+	topics := map[string]string{
+		"status":  info.PlaybackStatus,
+		"state":   homeAssistantState(player.PlaybackStatus),
+		"title":   info.Metadata.Title,
+		"artist":  strings.Join(info.Metadata.Artist, ", "),
+		"album":   info.Metadata.Album,
+		"art_url": info.Metadata.ArtUrl,
+		"length":  formatSeconds(player.Metadata.Length),
+		"volume":  strconv.FormatFloat(info.Volume, 'f', -1, 64),
+		"rate":    strconv.FormatFloat(info.Rate, 'f', -1, 64),
+		"shuffle": strconv.FormatBool(info.Shuffle),
+		"loop":    info.LoopStatus,
+		"info":    string(data),
+	}
+	if withPosition {
+		topics["position"] = formatSeconds(player.Position)
+		b.positionPublished[level] = time.Now()
+	}
+	for name, payload := range topics {
+		b.publishRetained(b.playerTopic(level)+"/"+name, payload)
+	}
+	if b.options.DiscoveryPrefix != "" {
+		for topic, config := range b.discoveryConfigs(level, player) {
+			b.publishRetained(topic, config)
+		}
+	}
+}
+
+// unpublishPlayer removes the retained topics of a player. b.lock must be held.
+func (b *Bridge) unpublishPlayer(level string) {
+	prefix := b.playerTopic(level) + "/"
+	for topic := range b.published {
+		if strings.HasPrefix(topic, prefix) || (b.options.DiscoveryPrefix != "" && b.isDiscoveryTopic(level, topic)) {
+			// An empty retained message removes the retained one.
+			b.mqtt.Publish(topic, qos, true, "")
+			delete(b.published, topic)
+		}
+	}
+}
+
+// publishRetained publishes payload on topic, unless it was already. b.lock must be held.
+func (b *Bridge) publishRetained(topic string, payload string) {
+	if previous, found := b.published[topic]; found && previous == payload {
+		return
+	}
+	b.published[topic] = payload
+	b.mqtt.Publish(topic, qos, true, payload)
+}
+
+// onAction runs the action asked by message.
+func (b *Bridge) onAction(_ paho.Client, message paho.Message) {
+	topic := message.Topic()
+	levels := strings.Split(strings.TrimPrefix(topic, b.hostTopic()+"/"), "/")
+	actionName := levels[len(levels)-1]
+	action, found := daemon.Actions[actionName]
+	if found == false {
+		b.fail(topic, fmt.Errorf("no such action: %s", actionName))
+		return
+	}
+	value, err := actionValue(actionName, message.Payload())
+	if err != nil {
+		b.fail(topic, err)
+		return
+	}
+
+	var playerNames []string
+	b.lock.Lock()
+	if len(levels) == 2 {
+		for _, player := range b.players {
+			playerNames = append(playerNames, player.Name)
+		}
+	} else if player, found := b.players[levels[0]]; found {
+		playerNames = append(playerNames, player.Name)
+	} else {
+		playerNames = append(playerNames, levels[0])
+	}
+	b.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	for _, playerName := range playerNames {
+		if err := action(ctx, b.client, playerName, value); err != nil {
+			b.fail(topic, fmt.Errorf("%s: %w", playerName, err))
+		}
+	}
+}
+
+func (b *Bridge) fail(topic string, err error) {
+	fmt.Println(fmt.Sprintf("MQTT::failed topic=%s error=%s", topic, err))
+}
+
+// actionValue converts the payload of a message to the value of an action.
+func actionValue(actionName string, payload []byte) (json.RawMessage, error) {
+	text := strings.TrimSpace(string(payload))
+	switch {
+	case text == "":
+		return nil, nil
+	case actionName == "seek" || actionName == "position":
+		seconds, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return nil, fmt.Errorf("%w: %s takes seconds, got %q", mpris.ErrInvalidValue, actionName, text)
+		}
+		return json.RawMessage(strconv.FormatInt(int64(seconds*1e6), 10)), nil
+	case json.Valid([]byte(text)):
+		return json.RawMessage(text), nil
+	default:
+		return json.Marshal(text)
+	}
+}
+
+func (b *Bridge) hostTopic() string {
+	return b.options.TopicPrefix + "/" + topicLevel(b.options.Host)
+}
+
+func (b *Bridge) availabilityTopic() string {
+	return b.hostTopic() + "/available"
+}
+
+func (b *Bridge) playerTopic(level string) string {
+	return b.hostTopic() + "/" + level
+}
+
+// topicLevel returns name as a topic level, without the characters having a meaning in topics.
+func topicLevel(name string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(name)
+}
+
+func formatSeconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)
+}
+
+func homeAssistantState(status mpris.PlaybackStatus) string {
+	switch status {
+	case mpris.PlaybackPlaying:
+		return "playing"
+	case mpris.PlaybackPaused:
+		return "paused"
+	default:
+		return "idle"
+	}
+}
@@ -0,0 +1,136 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// objectIDReplacer replaces the characters not allowed in the IDs of the entities.
+var objectIDReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// discoveryDevice is the device grouping the entities of a player in Home Assistant.
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discoveryConfig is the discovery config of a sensor, button or number entity, as read by the MQTT
+// integration of Home Assistant. Home Assistant has no MQTT media_player entity: each player is
+// a device with sensors for its state and track, buttons for its transport and a number for its volume.
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	ObjectID          string          `json:"object_id"`
+	Device            discoveryDevice `json:"device"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	Icon              string          `json:"icon,omitempty"`
+
+	StateTopic        string `json:"state_topic,omitempty"`
+	DeviceClass       string `json:"device_class,omitempty"`
+	UnitOfMeasurement string `json:"unit_of_measurement,omitempty"`
+
+	CommandTopic string `json:"command_topic,omitempty"`
+	// PayloadPress is the payload of buttons, empty for the actions to take no value.
+	PayloadPress *string  `json:"payload_press,omitempty"`
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+	Step         float64  `json:"step,omitempty"`
+	Mode         string   `json:"mode,omitempty"`
+}
+
+// discoveryEntity describes an entity of a player, its topics being relative to the topic of the player.
+type discoveryEntity struct {
+	component string
+	key       string
+	name      string
+	icon      string
+	// state is the topic of the state of sensors and numbers, and command the one of buttons and numbers.
+	state   string
+	command string
+	// seconds makes a sensor a duration.
+	seconds bool
+}
+
+// discoveryEntities are the entities announced for every player.
+var discoveryEntities = []discoveryEntity{
+	{component: "sensor", key: "state", name: "State", icon: "mdi:play-pause", state: "state"},
+	{component: "sensor", key: "title", name: "Title", icon: "mdi:music", state: "title"},
+	{component: "sensor", key: "artist", name: "Artist", icon: "mdi:account-music", state: "artist"},
+	{component: "sensor", key: "album", name: "Album", icon: "mdi:album", state: "album"},
+	{component: "sensor", key: "position", name: "Position", icon: "mdi:timer-outline", state: "position", seconds: true},
+	{component: "sensor", key: "length", name: "Length", icon: "mdi:timer-outline", state: "length", seconds: true},
+	{component: "button", key: "play_pause", name: "Play/Pause", icon: "mdi:play-pause", command: "set/play-pause"},
+	{component: "button", key: "next", name: "Next", icon: "mdi:skip-next", command: "set/next"},
+	{component: "button", key: "previous", name: "Previous", icon: "mdi:skip-previous", command: "set/previous"},
+	{component: "button", key: "stop", name: "Stop", icon: "mdi:stop", command: "set/stop"},
+	{component: "number", key: "volume", name: "Volume", icon: "mdi:volume-high", state: "volume", command: "set/volume"},
+}
+
+func (b *Bridge) objectID(level string) string {
+	return objectIDReplacer.ReplaceAllString("mprisctl_"+b.options.Host+"_"+level, "_")
+}
+
+// discoveryTopic returns the topic of the discovery config of an entity of a player:
+// <discovery prefix>/<component>/<player object ID>/<entity>/config.
+func (b *Bridge) discoveryTopic(level string, entity discoveryEntity) string {
+	return b.options.DiscoveryPrefix + "/" + entity.component + "/" + b.objectID(level) + "/" + entity.key + "/config"
+}
+
+// isDiscoveryTopic reports whether topic is the one of the discovery config of an entity of a player.
+func (b *Bridge) isDiscoveryTopic(level string, topic string) bool {
+	rest, found := strings.CutPrefix(topic, b.options.DiscoveryPrefix+"/")
+	levels := strings.Split(rest, "/")
+	return found && len(levels) == 4 && levels[1] == b.objectID(level) && levels[3] == "config"
+}
+
+// discoveryConfigs returns the discovery configs of the entities of a player, by topic.
+func (b *Bridge) discoveryConfigs(level string, player mpris.PlayerState) map[string]string {
+	topic := b.playerTopic(level)
+	name := player.Identity
+	if name == "" {
+		name = player.Name
+	}
+	device := discoveryDevice{
+		Identifiers:  []string{b.objectID(level)},
+		Name:         name + " on " + b.options.Host,
+		Manufacturer: "mprisctl",
+		Model:        player.Name,
+	}
+
+	configs := make(map[string]string, len(discoveryEntities))
+	for _, entity := range discoveryEntities {
+		config := discoveryConfig{
+			Name:              entity.name,
+			UniqueID:          b.objectID(level) + "_" + entity.key,
+			ObjectID:          b.objectID(level) + "_" + entity.key,
+			Device:            device,
+			AvailabilityTopic: b.availabilityTopic(),
+			Icon:              entity.icon,
+		}
+		if entity.state != "" {
+			config.StateTopic = topic + "/" + entity.state
+		}
+		if entity.command != "" {
+			config.CommandTopic = topic + "/" + entity.command
+		}
+		if entity.seconds {
+			config.DeviceClass, config.UnitOfMeasurement = "duration", "s"
+		}
+		switch entity.component {
+		case "button":
+			empty := ""
+			config.PayloadPress = &empty
+		case "number":
+			low, high := 0.0, 1.0
+			config.Min, config.Max, config.Step, config.Mode = &low, &high, 0.01, "slider"
+		}
+		data, _ := json.Marshal(config)
+		configs[b.discoveryTopic(level, entity)] = string(data)
+	}
+	return configs
+}
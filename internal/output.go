@@ -2,74 +2,104 @@ package mprisctl
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/webflo-dev/mpris-ctl/internal/desktop"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
 )
 
-func printMetadataValues(player *Player) string {
-	metadata := player.Info[FieldMetadata].(map[string]interface{})
+// displayName names player in the output by its application name, stable across restarts,
+// tagged with its bus when watching several buses, and followed by its instance if any.
+func displayName(player *mpris.PlayerState) string {
+	name := player.Application
+	if player.Bus != "" {
+		name += "@" + player.Bus
+	}
+	if player.Instance != "" {
+		name += " instance=" + player.Instance
+	}
+	return name
+}
+
+func printIdentityValues(player *mpris.PlayerState) string {
+	identity := desktop.IdentifyPlayer(player)
+	return fmt.Sprintf("display_name=\"%s\" identity=\"%s\" desktop_entry=%s icon_name=%s icon=%s",
+		identity.DisplayName,
+		player.Identity,
+		player.DesktopEntry,
+		identity.Icon,
+		identity.IconPath,
+	)
+}
+
+func printMetadataValues(player *mpris.PlayerState) string {
+	metadata := player.Metadata
 	return fmt.Sprintf("owner=\"%s\" artist=\"%s\" title=\"%s\" album=\"%s\" track_id=\"%s\" length=%d duration=%s url=%s art_url=%s",
 		player.Owner,
-		metadata[MetadataArtist],
-		metadata[MetadataTitle],
-		metadata[MetadataAlbum],
-		metadata[MetadataTrackId],
-		metadata[MetadataLength],
-		metadata[MetadataDuration],
-		metadata[MetadataUrl],
-		metadata[MetadataArtUrl],
+		strings.Join(metadata.Artist, ","),
+		metadata.Title,
+		metadata.Album,
+		metadata.TrackId,
+		metadata.Length.Microseconds(),
+		convertToDuration(metadata.Length),
+		metadata.Url,
+		metadata.ArtUrl,
 	)
 }
 
-func printCapabilitiesValues(player *Player) string {
+func printCapabilitiesValues(player *mpris.PlayerState) string {
 	return fmt.Sprintf("can_control=%t can_go_next=%t can_go_previous=%t can_pause=%t can_play=%t can_seek=%t",
-		player.Info[FieldCanControl],
-		player.Info[FieldCanGoNext],
-		player.Info[FieldCanGoPrevious],
-		player.Info[FieldCanPause],
-		player.Info[FieldCanPlay],
-		player.Info[FieldCanSeek],
+		player.CanControl,
+		player.CanGoNext,
+		player.CanGoPrevious,
+		player.CanPause,
+		player.CanPlay,
+		player.CanSeek,
 	)
 }
 
-func printShuffleStatusValues(player *Player) string {
-	return fmt.Sprintf("shuffle=%t", player.Info[FieldShuffle])
+func printShuffleStatusValues(player *mpris.PlayerState) string {
+	return fmt.Sprintf("shuffle=%t", player.Shuffle)
 }
 
-func printLoopStatusValues(player *Player) string {
-	return fmt.Sprintf("loop_status=%s", player.Info[FieldLoopStatus])
+func printLoopStatusValues(player *mpris.PlayerState) string {
+	return fmt.Sprintf("loop_status=%s", player.LoopStatus)
 }
 
-func printPlaybackStatusValues(player *Player) string {
-	return fmt.Sprintf("playback_status=%s", player.Info[FieldPlaybackStatus])
+func printPlaybackStatusValues(player *mpris.PlayerState) string {
+	return fmt.Sprintf("playback_status=%s", player.PlaybackStatus)
 }
 
-func printMetadata(player *Player) {
-	fmt.Println(fmt.Sprintf("METADATA::%s %s", player.Name, printMetadataValues(player)))
+func printMetadata(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("METADATA::%s %s", displayName(player), printMetadataValues(player)))
 }
 
-func printCapabilities(player *Player) {
-	fmt.Println(fmt.Sprintf("CAPABILITIES::%s %s", player.Name, printCapabilitiesValues(player)))
+func printCapabilities(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("CAPABILITIES::%s %s", displayName(player), printCapabilitiesValues(player)))
 }
 
-func printPlaybackStatus(player *Player) {
-	fmt.Println(fmt.Sprintf("PLAYBACK_STATUS::%s %s", player.Name, printPlaybackStatusValues(player)))
+func printPlaybackStatus(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("PLAYBACK_STATUS::%s %s", displayName(player), printPlaybackStatusValues(player)))
 }
 
-func printPosition(position uint64, playerName string, remaining_raw uint64) {
-	elapsed, _, _, _ := convertToDuration(position)
-	remaining, _, _, _ := convertToDuration(remaining_raw)
-	fmt.Println(fmt.Sprintf("POSITION::%s elapsed=%s elasped_raw=%d remaining=%s remaining_raw=%d", playerName, elapsed, position, remaining, remaining_raw))
+func printPosition(player *mpris.PlayerState) {
+	remainingRaw := player.Metadata.Length - player.Position
+	elapsed := convertToDuration(player.Position)
+	remaining := convertToDuration(remainingRaw)
+	fmt.Println(fmt.Sprintf("POSITION::%s elapsed=%s elasped_raw=%d remaining=%s remaining_raw=%d", displayName(player), elapsed, player.Position.Microseconds(), remaining, remainingRaw.Microseconds()))
 }
 
-func printConnectionStatus(player *Player, connected bool) {
+func printConnectionStatus(player *mpris.PlayerState, connected bool) {
 	var status string
 	if connected {
 		status = "connected"
 	} else {
 		status = "disconnected"
 	}
-	fmt.Println(fmt.Sprintf("PLAYER::%s player_name=%s %s %s %s %s %s",
+	fmt.Println(fmt.Sprintf("PLAYER::%s player_name=%s %s %s %s %s %s %s",
 		status,
-		player.Name,
+		displayName(player),
+		printIdentityValues(player),
 		printMetadataValues(player),
 		printCapabilitiesValues(player),
 		printPlaybackStatusValues(player),
@@ -78,10 +108,14 @@ func printConnectionStatus(player *Player, connected bool) {
 	))
 }
 
-func printShuffleStatus(player *Player) {
-	fmt.Println(fmt.Sprintf("SHUFFLE::%s %s", player.Name, printShuffleStatusValues(player)))
+func printShuffleStatus(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("SHUFFLE::%s %s", displayName(player), printShuffleStatusValues(player)))
+}
+
+func printLoopStatus(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("LOOP::%s %s", displayName(player), printLoopStatusValues(player)))
 }
 
-func printLoopStatus(player *Player) {
-	fmt.Println(fmt.Sprintf("LOOP::%s %s", player.Name, printLoopStatusValues(player)))
+func printListEntry(player *mpris.PlayerState) {
+	fmt.Println(fmt.Sprintf("%s player_name=%s %s %s", displayName(player), player.Name, printIdentityValues(player), printPlaybackStatusValues(player)))
 }
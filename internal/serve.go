@@ -0,0 +1,75 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/internal/httpapi"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// ServeOptions tells what Serve serves.
+type ServeOptions struct {
+	// HTTPAddress is the address to serve the HTTP API on.
+	HTTPAddress string
+	HTTP        httpapi.Options
+}
+
+// Serve serves the players of client until ctx is done.
+func Serve(ctx context.Context, client *mpris.Client, options ServeOptions) error {
+	if options.HTTPAddress == "" {
+		return errors.New("nothing to serve: give --http")
+	}
+	if options.HTTP.Token == "" && isLoopback(options.HTTPAddress) == false {
+		return fmt.Errorf("serving %s without a token would let anyone on the network drive the players: give --token", options.HTTPAddress)
+	}
+
+	listener, err := net.Listen("tcp", options.HTTPAddress)
+	if err != nil {
+		return err
+	}
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	api := httpapi.New(client, options.HTTP)
+	server := &http.Server{
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Streams of events end with the requests, once serving stops.
+		BaseContext: func(net.Listener) context.Context { return serveCtx },
+	}
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- api.Run(serveCtx)
+		cancel()
+	}()
+	go func() {
+		<-serveCtx.Done()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		server.Shutdown(shutdownCtx)
+	}()
+	fmt.Println(fmt.Sprintf("HTTP::listening address=%s", listener.Addr()))
+
+	if err := server.Serve(listener); errors.Is(err, http.ErrServerClosed) == false {
+		return err
+	}
+	cancel()
+	return <-runErr
+}
+
+// isLoopback reports whether address only listens on the loopback interface.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
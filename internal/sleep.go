@@ -0,0 +1,200 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// restoreTimeout bounds the calls made once Sleep is interrupted.
+const restoreTimeout = 5 * time.Second
+
+// SleepOptions configures Sleep.
+type SleepOptions struct {
+	// After is the time before pausing, unless Tracks is set.
+	After time.Duration
+	// Tracks is the number of track changes before pausing: 1 pauses at the end of the current track.
+	Tracks int
+	// Fade is how long the volume fades out before pausing.
+	Fade time.Duration
+	// Stop stops the player instead of pausing it.
+	Stop bool
+}
+
+// sleeper is the state of Sleep.
+type sleeper struct {
+	client  *mpris.Client
+	player  string
+	options SleepOptions
+
+	// volume is the volume to restore once faded out.
+	volume float64
+	// fading is set from the start of the fade out.
+	fading bool
+	// cancelFade stops the fade out, and fadeDone is closed once it stopped.
+	cancelFade context.CancelFunc
+	fadeDone   chan struct{}
+}
+
+// Sleep pauses the player after a time or at the end of some tracks, fading out its volume first
+// when options.Fade is set. The volume is restored once paused, or when ctx is done meanwhile.
+func Sleep(ctx context.Context, client *mpris.Client, playerName string, options SleepOptions) error {
+	player, err := client.Player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	s := &sleeper{client: client, player: player.Name, options: options}
+
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := client.Subscribe(eventsCtx, mpris.Filter{Players: []string{player.Name}})
+
+	var fadeAt, pauseAt <-chan time.Time
+	if options.Tracks > 0 {
+		fmt.Println(fmt.Sprintf("SLEEP::started player=%s tracks=%d", player.Name, options.Tracks))
+	} else {
+		fmt.Println(fmt.Sprintf("SLEEP::started player=%s after=%s", player.Name, options.After))
+		pauseAt = time.After(options.After)
+		if options.Fade > 0 {
+			fadeAt = time.After(max(options.After-options.Fade, 0))
+		}
+	}
+	remaining := options.Tracks
+	last := *player
+	for {
+		select {
+		case <-ctx.Done():
+			s.restore()
+			return nil
+		case <-fadeAt:
+			fadeAt = nil
+			if options.Tracks > 0 {
+				// Fading over the rest of the last track.
+				s.fade(ctx, left(&last))
+			} else {
+				s.fade(ctx, min(options.Fade, options.After))
+			}
+		case <-pauseAt:
+			return s.pause()
+		case event, ok := <-events:
+			if ok == false {
+				if ctx.Err() != nil {
+					s.restore()
+					return nil
+				}
+				return errors.New("lost connection to the bus")
+			}
+			previous := last
+			last = event.Player
+			switch event.Type {
+			case mpris.EventPlayerVanished:
+				return fmt.Errorf("%s left the bus", player.Name)
+			case mpris.EventTrackChanged:
+				if options.Tracks == 0 || sameTrack(event.Player.Metadata, previous.Metadata) {
+					break
+				}
+				remaining--
+				if remaining == 0 {
+					return s.pause()
+				}
+			case mpris.EventPlaybackChanged:
+				if options.Tracks > 0 && event.Player.PlaybackStatus == mpris.PlaybackStopped {
+					// The player reached the end of its tracks.
+					s.restore()
+					fmt.Println(fmt.Sprintf("SLEEP::stopped player=%s", player.Name))
+					return nil
+				}
+			}
+			if options.Tracks > 0 && remaining == 1 && options.Fade > 0 && s.fading == false {
+				fadeAt = s.scheduleFade(&last)
+			}
+		}
+	}
+}
+
+// scheduleFade returns when to start fading out the last track played by player, or nil when it is not playing.
+func (s *sleeper) scheduleFade(player *mpris.PlayerState) <-chan time.Time {
+	if player.PlaybackStatus != mpris.PlaybackPlaying || player.Metadata.Length <= 0 {
+		return nil
+	}
+	return time.After(max(left(player)-s.options.Fade, 0))
+}
+
+// left returns the playing time left in the current track of player.
+func left(player *mpris.PlayerState) time.Duration {
+	if player.Rate <= 0 || player.Metadata.Length <= player.Position {
+		return 0
+	}
+	return time.Duration(float64(player.Metadata.Length-player.Position) / player.Rate)
+}
+
+// fade fades out the volume over duration, in the background.
+func (s *sleeper) fade(ctx context.Context, duration time.Duration) {
+	volume, err := s.client.Volume(ctx, s.player)
+	if err != nil {
+		return
+	}
+	s.volume = volume
+	s.fading = true
+	fmt.Println(fmt.Sprintf("SLEEP::fading player=%s over=%s", s.player, duration))
+
+	fadeCtx, cancel := context.WithCancel(ctx)
+	s.cancelFade = cancel
+	s.fadeDone = make(chan struct{})
+	go func() {
+		defer close(s.fadeDone)
+		s.client.FadeVolume(fadeCtx, s.player, volume, 0, duration)
+	}()
+}
+
+// pause pauses or stops the player, and restores its volume.
+func (s *sleeper) pause() error {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	s.stopFade()
+	action, verb := s.client.Pause, "paused"
+	if s.options.Stop {
+		action, verb = s.client.Stop, "stopped"
+	}
+	if err := action(ctx, s.player); err != nil {
+		s.restore()
+		return err
+	}
+	fmt.Println(fmt.Sprintf("SLEEP::%s player=%s", verb, s.player))
+	s.restore()
+	return nil
+}
+
+func (s *sleeper) stopFade() {
+	if s.cancelFade != nil {
+		s.cancelFade()
+		<-s.fadeDone
+		s.cancelFade = nil
+	}
+}
+
+// restore restores the volume faded out.
+func (s *sleeper) restore() {
+	s.stopFade()
+	if s.fading == false {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	if err := s.client.SetVolume(ctx, s.player, s.volume); err == nil {
+		fmt.Println(fmt.Sprintf("SLEEP::restored player=%s volume=%v", s.player, s.volume))
+	}
+	s.fading = false
+}
+
+// sameTrack reports whether a and b are the metadata of the same track,
+// the players updating the metadata of a track while playing it.
+func sameTrack(a mpris.Metadata, b mpris.Metadata) bool {
+	if a.TrackId != "" || b.TrackId != "" {
+		return a.TrackId == b.TrackId
+	}
+	return a.Url == b.Url && a.Title == b.Title
+}
@@ -1,5 +0,0 @@
-package mprisctl
-
-func zeroValue[T any]() (ret T) {
-	return
-}
@@ -0,0 +1,117 @@
+package mprisctl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/webflo-dev/mpris-ctl/internal/hooks"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+var printMapping = map[mpris.EventType]func(player *mpris.PlayerState){
+	mpris.EventPlayerAppeared:      func(player *mpris.PlayerState) { printConnectionStatus(player, true) },
+	mpris.EventPlayerVanished:      func(player *mpris.PlayerState) { printConnectionStatus(player, false) },
+	mpris.EventTrackChanged:        printMetadata,
+	mpris.EventPlaybackChanged:     printPlaybackStatus,
+	mpris.EventShuffleChanged:      printShuffleStatus,
+	mpris.EventLoopStatusChanged:   printLoopStatus,
+	mpris.EventCapabilitiesChanged: printCapabilities,
+	mpris.EventPosition:            printPosition,
+}
+
+// Watch prints the events of every player of every client until ctx is done,
+// running the hooks of runner on them unless it is nil.
+func Watch(ctx context.Context, runner *hooks.Runner, clients ...*mpris.Client) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	printEvents(subscribe(watchCtx, cancel, clients), runner)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("lost connection to the bus")
+}
+
+// subscribe merges the subscriptions to every client. As a subscription ends when its bus is lost,
+// cancel is called to end the other ones. The channel is closed when all of them ended.
+func subscribe(ctx context.Context, cancel context.CancelFunc, clients []*mpris.Client) <-chan mpris.Event {
+	events := make(chan mpris.Event)
+	var group sync.WaitGroup
+	for _, client := range clients {
+		group.Add(1)
+		go func(subscription <-chan mpris.Event) {
+			defer group.Done()
+			for event := range subscription {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+				}
+			}
+			cancel()
+		}(client.Subscribe(ctx, mpris.Filter{}))
+	}
+	go func() {
+		group.Wait()
+		close(events)
+	}()
+	return events
+}
+
+// Record is like Watch, and also writes a recording of the bus to w, for Replay.
+func Record(ctx context.Context, runner *hooks.Runner, client *mpris.Client, w io.Writer) error {
+	recording := &errorWriter{writer: w}
+	printEvents(client.Record(ctx, mpris.Filter{}, recording), runner)
+	if recording.err != nil {
+		return recording.err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("lost connection to the bus")
+}
+
+// Replay prints the events of a recording made by Record, as Watch printed them,
+// running the hooks of runner on them too.
+func Replay(ctx context.Context, runner *hooks.Runner, r io.Reader) error {
+	events, err := mpris.Replay(ctx, r, mpris.Filter{})
+	if err != nil {
+		return err
+	}
+	printEvents(events, runner)
+	return nil
+}
+
+// printEvents prints events, and runs the hooks of runner on them unless it is nil.
+// It returns once the hooks are done.
+func printEvents(events <-chan mpris.Event, runner *hooks.Runner) {
+	if runner != nil {
+		defer runner.Close()
+	}
+	for event := range events {
+		if printFormatted(event.Type, &event.Player) == false {
+			if printer, printable := printMapping[event.Type]; printable {
+				printer(&event.Player)
+			}
+		}
+		if runner != nil {
+			runner.Handle(event)
+		}
+	}
+}
+
+// errorWriter remembers the first error of writer.
+type errorWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (w *errorWriter) Write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.writer.Write(data)
+	w.err = err
+	return n, err
+}
@@ -1,6 +1,6 @@
 package main
 
-import "mprisctl/cmd"
+import "github.com/webflo-dev/mpris-ctl/cmd"
 
 func main() {
 	cmd.Execute()
@@ -0,0 +1,106 @@
+package mpris
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Bus is the part of a D-Bus connection used by Client.
+// It is implemented by connections to a real bus, see ConnectSessionBus,
+// and by the in-memory bus of the mpristest package.
+type Bus interface {
+	// Call invokes method on the object at path owned by dest.
+	Call(ctx context.Context, dest string, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call
+	// GetProperty reads property, given as "interface.Name", of the object at path owned by dest.
+	GetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string) (dbus.Variant, error)
+	// SetProperty writes property, given as "interface.Name", of the object at path owned by dest.
+	SetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string, value interface{}) error
+	// AddMatchSignal asks the bus to deliver the signals matching options.
+	AddMatchSignal(options ...dbus.MatchOption) error
+	// RemoveMatchSignal undoes AddMatchSignal.
+	RemoveMatchSignal(options ...dbus.MatchOption) error
+	// Signal registers channel to receive the signals delivered by the bus.
+	Signal(channel chan<- *dbus.Signal)
+	// RemoveSignal unregisters channel.
+	RemoveSignal(channel chan<- *dbus.Signal)
+	Close() error
+}
+
+type connBus struct {
+	connection *dbus.Conn
+}
+
+// ConnectSessionBus opens a private connection to the session bus.
+func ConnectSessionBus() (Bus, error) {
+	connection, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	return NewConnBus(connection), nil
+}
+
+// ConnectSystemBus opens a private connection to the system bus.
+func ConnectSystemBus() (Bus, error) {
+	connection, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return NewConnBus(connection), nil
+}
+
+// ConnectBus opens a private connection to the bus at address,
+// or to the session bus when address is empty.
+func ConnectBus(address string) (Bus, error) {
+	if address == "" {
+		return ConnectSessionBus()
+	}
+	connection, err := dbus.Connect(address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnBus(connection), nil
+}
+
+// NewConnBus returns a Bus using an established connection.
+func NewConnBus(connection *dbus.Conn) Bus {
+	return &connBus{
+		connection: connection,
+	}
+}
+
+func (b *connBus) Call(ctx context.Context, dest string, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	return b.connection.Object(dest, path).CallWithContext(ctx, method, 0, args...)
+}
+
+func (b *connBus) GetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string) (dbus.Variant, error) {
+	var value dbus.Variant
+	iface, name := splitProperty(property)
+	err := b.Call(ctx, dest, path, methodGet, iface, name).Store(&value)
+	return value, err
+}
+
+func (b *connBus) SetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string, value interface{}) error {
+	iface, name := splitProperty(property)
+	return b.Call(ctx, dest, path, methodSet, iface, name, dbus.MakeVariant(value)).Err
+}
+
+func (b *connBus) AddMatchSignal(options ...dbus.MatchOption) error {
+	return b.connection.AddMatchSignal(options...)
+}
+
+func (b *connBus) RemoveMatchSignal(options ...dbus.MatchOption) error {
+	return b.connection.RemoveMatchSignal(options...)
+}
+
+func (b *connBus) Signal(channel chan<- *dbus.Signal) {
+	b.connection.Signal(channel)
+}
+
+func (b *connBus) RemoveSignal(channel chan<- *dbus.Signal) {
+	b.connection.RemoveSignal(channel)
+}
+
+func (b *connBus) Close() error {
+	return b.connection.Close()
+}
@@ -0,0 +1,106 @@
+package mpris
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func zeroValue[T any]() (ret T) {
+	return
+}
+
+func convertToString(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case dbus.ObjectPath:
+		return string(value), true
+	case string:
+		return value, true
+	default:
+		return "", false
+	}
+}
+
+func convertToStrings(value interface{}) ([]string, bool) {
+	switch value := value.(type) {
+	case []string:
+		return value, true
+	case string:
+		return []string{value}, true
+	default:
+		return nil, false
+	}
+}
+
+func convertToInt64(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		return int64(value), true
+	case int32:
+		return int64(value), true
+	case uint32:
+		return int64(value), true
+	case float64:
+		return int64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// convertToDuration reads a value expressed in microseconds, as MPRIS does for positions and lengths.
+func convertToDuration(value interface{}) (time.Duration, bool) {
+	microseconds, ok := convertToInt64(value)
+	return time.Duration(microseconds) * time.Microsecond, ok
+}
+
+func convertToBool(value interface{}) (bool, bool) {
+	switch value := value.(type) {
+	case bool:
+		return value, true
+	default:
+		return false, false
+	}
+}
+
+func convertToFloat64(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+func convertToPlaybackStatus(value interface{}) (PlaybackStatus, bool) {
+	if status, ok := convertToString(value); ok {
+		return PlaybackStatus(status), true
+	}
+	return PlaybackStopped, false
+}
+
+func convertToLoopStatus(value interface{}) (LoopStatus, bool) {
+	if status, ok := convertToString(value); ok {
+		return LoopStatus(status), true
+	}
+	return LoopStatusNone, false
+}
+
+func convertToMetadata(value interface{}) (Metadata, bool) {
+	var metadata Metadata
+	values, ok := value.(map[string]dbus.Variant)
+	if ok == false {
+		return metadata, false
+	}
+
+	metadata.TrackId, _ = convertToString(values[MetadataTrackId].Value())
+	metadata.Title, _ = convertToString(values[MetadataTitle].Value())
+	metadata.Artist, _ = convertToStrings(values[MetadataArtist].Value())
+	metadata.Album, _ = convertToString(values[MetadataAlbum].Value())
+	metadata.AlbumArtist, _ = convertToStrings(values[MetadataAlbumArtist].Value())
+	metadata.Length, _ = convertToDuration(values[MetadataLength].Value())
+	metadata.Url, _ = convertToString(values[MetadataUrl].Value())
+	metadata.ArtUrl, _ = convertToString(values[MetadataArtUrl].Value())
+	return metadata, true
+}
@@ -0,0 +1,126 @@
+package mpris
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestConvertToString(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		want   string
+		wantOk bool
+	}{
+		{"title", "title", true},
+		{dbus.ObjectPath("/track/1"), "/track/1", true},
+		{int64(1), "", false},
+		{nil, "", false},
+	}
+	for _, test := range tests {
+		if got, ok := convertToString(test.value); got != test.want || ok != test.wantOk {
+			t.Errorf("convertToString(%#v) = %q, %v; want %q, %v", test.value, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestConvertToStrings(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		want   []string
+		wantOk bool
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, true},
+		// Some players send a single artist as a string.
+		{"a", []string{"a"}, true},
+		{int64(1), nil, false},
+	}
+	for _, test := range tests {
+		if got, ok := convertToStrings(test.value); reflect.DeepEqual(got, test.want) == false || ok != test.wantOk {
+			t.Errorf("convertToStrings(%#v) = %q, %v; want %q, %v", test.value, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestConvertToDuration(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		want   time.Duration
+		wantOk bool
+	}{
+		{int64(1_500_000), 1500 * time.Millisecond, true},
+		{uint64(2_000_000), 2 * time.Second, true},
+		{int32(1000), time.Millisecond, true},
+		{uint32(1000), time.Millisecond, true},
+		// Some players send lengths as doubles.
+		{float64(3_000_000), 3 * time.Second, true},
+		{"1", 0, false},
+	}
+	for _, test := range tests {
+		if got, ok := convertToDuration(test.value); got != test.want || ok != test.wantOk {
+			t.Errorf("convertToDuration(%#v) = %v, %v; want %v, %v", test.value, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestConvertToScalars(t *testing.T) {
+	if got, ok := convertToBool(true); got != true || ok == false {
+		t.Errorf("convertToBool(true) = %v, %v", got, ok)
+	}
+	if _, ok := convertToBool("true"); ok {
+		t.Error("convertToBool accepted a string")
+	}
+	if got, ok := convertToFloat64(0.5); got != 0.5 || ok == false {
+		t.Errorf("convertToFloat64(0.5) = %v, %v", got, ok)
+	}
+	if _, ok := convertToFloat64(int64(1)); ok {
+		t.Error("convertToFloat64 accepted an integer")
+	}
+	if got, ok := convertToPlaybackStatus("Playing"); got != PlaybackPlaying || ok == false {
+		t.Errorf("convertToPlaybackStatus(Playing) = %v, %v", got, ok)
+	}
+	if got, ok := convertToPlaybackStatus(nil); got != PlaybackStopped || ok {
+		t.Errorf("convertToPlaybackStatus(nil) = %v, %v; want Stopped, false", got, ok)
+	}
+	if got, ok := convertToLoopStatus("Playlist"); got != LoopStatusPlaylist || ok == false {
+		t.Errorf("convertToLoopStatus(Playlist) = %v, %v", got, ok)
+	}
+	if got, ok := convertToLoopStatus(1); got != LoopStatusNone || ok {
+		t.Errorf("convertToLoopStatus(1) = %v, %v; want None, false", got, ok)
+	}
+}
+
+func TestConvertToMetadata(t *testing.T) {
+	got, ok := convertToMetadata(map[string]dbus.Variant{
+		MetadataTrackId:     dbus.MakeVariant(dbus.ObjectPath("/track/1")),
+		MetadataTitle:       dbus.MakeVariant("Title"),
+		MetadataArtist:      dbus.MakeVariant([]string{"Artist"}),
+		MetadataAlbum:       dbus.MakeVariant("Album"),
+		MetadataAlbumArtist: dbus.MakeVariant("Album Artist"),
+		MetadataLength:      dbus.MakeVariant(int64(180_000_000)),
+		MetadataUrl:         dbus.MakeVariant("file:///song.ogg"),
+		MetadataArtUrl:      dbus.MakeVariant("file:///cover.png"),
+	})
+	want := Metadata{
+		TrackId:     "/track/1",
+		Title:       "Title",
+		Artist:      []string{"Artist"},
+		Album:       "Album",
+		AlbumArtist: []string{"Album Artist"},
+		Length:      3 * time.Minute,
+		Url:         "file:///song.ogg",
+		ArtUrl:      "file:///cover.png",
+	}
+	if ok == false || reflect.DeepEqual(got, want) == false {
+		t.Errorf("convertToMetadata = %+v, %v; want %+v", got, ok, want)
+	}
+
+	if got, ok := convertToMetadata(map[string]dbus.Variant{}); ok == false || reflect.DeepEqual(got, Metadata{}) == false {
+		t.Errorf("convertToMetadata(empty) = %+v, %v; want empty metadata", got, ok)
+	}
+	if _, ok := convertToMetadata("title"); ok {
+		t.Error("convertToMetadata accepted a string")
+	}
+}
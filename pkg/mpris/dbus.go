@@ -0,0 +1,75 @@
+package mpris
+
+import (
+	"context"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	DBusName      = "org.freedesktop.DBus"
+	DBusPath      = "/org/freedesktop/DBus"
+	DBusInterface = "org.freedesktop.DBus"
+
+	methodGet    = "org.freedesktop.DBus.Properties.Get"
+	methodGetAll = "org.freedesktop.DBus.Properties.GetAll"
+	methodSet    = "org.freedesktop.DBus.Properties.Set"
+)
+
+type dbusWrapper struct {
+	bus Bus
+}
+
+func newDBus(bus Bus) *dbusWrapper {
+	return &dbusWrapper{
+		bus: bus,
+	}
+}
+
+func (_dbus *dbusWrapper) close() error {
+	return _dbus.bus.Close()
+}
+
+func (_dbus *dbusWrapper) watchSignal(channel chan *dbus.Signal) {
+	_dbus.bus.Signal(channel)
+}
+
+func (_dbus *dbusWrapper) unwatchSignal(channel chan *dbus.Signal) {
+	_dbus.bus.RemoveSignal(channel)
+}
+
+func (_dbus *dbusWrapper) callMethodWithBusObject(ctx context.Context, methodName string, args ...interface{}) *dbus.Call {
+	return _dbus.callMethod(ctx, DBusName, DBusPath, methodName, args...)
+}
+
+func (_dbus *dbusWrapper) callMethod(ctx context.Context, dest string, path dbus.ObjectPath, methodName string, args ...interface{}) *dbus.Call {
+	return _dbus.bus.Call(ctx, dest, path, methodName, args...)
+}
+
+func (_dbus *dbusWrapper) getProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string) (dbus.Variant, error) {
+	return _dbus.bus.GetProperty(ctx, dest, path, property)
+}
+
+func (_dbus *dbusWrapper) setProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string, value interface{}) error {
+	return _dbus.bus.SetProperty(ctx, dest, path, property, value)
+}
+
+func (_dbus *dbusWrapper) getAll(ctx context.Context, dest string, path dbus.ObjectPath, iface string) (map[string]dbus.Variant, error) {
+	var values map[string]dbus.Variant
+	err := _dbus.callMethod(ctx, dest, path, methodGetAll, iface).Store(&values)
+	return values, err
+}
+
+func (_dbus *dbusWrapper) addMatchSignal(options ...dbus.MatchOption) error {
+	return _dbus.bus.AddMatchSignal(options...)
+}
+
+func (_dbus *dbusWrapper) removeMatchSignal(options ...dbus.MatchOption) error {
+	return _dbus.bus.RemoveMatchSignal(options...)
+}
+
+func splitProperty(property string) (string, string) {
+	i := strings.LastIndex(property, ".")
+	return property[:i], property[i+1:]
+}
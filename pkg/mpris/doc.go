@@ -0,0 +1,10 @@
+// Package mpris controls and observes MPRIS media players over D-Bus.
+//
+// A Client exposes the org.mpris.MediaPlayer2.Player interface of every
+// player on the bus. Players are addressed by name, the part of their bus
+// name following "org.mpris.MediaPlayer2.", and their properties are read
+// into typed values such as PlayerState and Metadata.
+//
+// The package follows semantic versioning: exported identifiers are not
+// removed or changed incompatibly within a major version.
+package mpris
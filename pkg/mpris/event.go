@@ -0,0 +1,119 @@
+package mpris
+
+import (
+	"fmt"
+)
+
+// EventType identifies what changed in an Event.
+type EventType int
+
+const (
+	// EventPlayerAppeared is sent for every player on the bus when subscribing,
+	// then whenever a player connects.
+	EventPlayerAppeared EventType = iota + 1
+	// EventPlayerVanished is sent when a player leaves the bus.
+	EventPlayerVanished
+	// EventTrackChanged is sent when the metadata of the current track changes.
+	EventTrackChanged
+	// EventPlaybackChanged is sent when the player starts, pauses or stops playing.
+	EventPlaybackChanged
+	// EventLoopStatusChanged is sent when the loop status changes.
+	EventLoopStatusChanged
+	// EventShuffleChanged is sent when shuffle is turned on or off.
+	EventShuffleChanged
+	// EventSeeked is sent when the position jumps.
+	EventSeeked
+	// EventVolumeChanged is sent when the volume changes.
+	EventVolumeChanged
+	// EventCapabilitiesChanged is sent when one of the Can* properties changes.
+	EventCapabilitiesChanged
+	// EventPosition is sent every second while a player is playing.
+	EventPosition
+)
+
+var eventTypeNames = map[EventType]string{
+	EventPlayerAppeared:      "player-appeared",
+	EventPlayerVanished:      "player-vanished",
+	EventTrackChanged:        "track-changed",
+	EventPlaybackChanged:     "playback-changed",
+	EventLoopStatusChanged:   "loop-status-changed",
+	EventShuffleChanged:      "shuffle-changed",
+	EventSeeked:              "seeked",
+	EventVolumeChanged:       "volume-changed",
+	EventCapabilitiesChanged: "capabilities-changed",
+	EventPosition:            "position",
+}
+
+// EventTypes lists every EventType.
+var EventTypes = []EventType{
+	EventPlayerAppeared,
+	EventPlayerVanished,
+	EventTrackChanged,
+	EventPlaybackChanged,
+	EventLoopStatusChanged,
+	EventShuffleChanged,
+	EventSeeked,
+	EventVolumeChanged,
+	EventCapabilitiesChanged,
+	EventPosition,
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// ParseEventType returns the EventType named name, as printed by EventType.String.
+func ParseEventType(name string) (EventType, error) {
+	for eventType, eventName := range eventTypeNames {
+		if eventName == name {
+			return eventType, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: event type %q", ErrInvalidValue, name)
+}
+
+// Event reports a change of a player.
+type Event struct {
+	Type EventType
+	// Player is a snapshot of the player once the change is applied.
+	Player PlayerState
+}
+
+// Filter selects the events delivered by Subscribe.
+// An empty field matches everything. Players are matched with MatchPlayerName.
+type Filter struct {
+	Players []string
+	Types   []EventType
+}
+
+// Match reports whether event is selected by the filter.
+func (f Filter) Match(event Event) bool {
+	return f.matchPlayer(event.Player.Name) && f.matchType(event.Type)
+}
+
+func (f Filter) matchPlayer(playerName string) bool {
+	if len(f.Players) == 0 {
+		return true
+	}
+	for _, name := range f.Players {
+		if MatchPlayerName(name, playerName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) matchType(eventType EventType) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package mpris
+
+import (
+	"context"
+	"time"
+)
+
+// FadeInterval is the time between the volume changes of FadeVolume.
+const FadeInterval = 50 * time.Millisecond
+
+// FadeVolume changes the volume of the player from one value to another, linearly over duration.
+// It returns once the volume is to, or with the error of ctx when cancelled meanwhile,
+// or with the error of the first volume change failing.
+func (c *Client) FadeVolume(ctx context.Context, playerName string, from float64, to float64, duration time.Duration) error {
+	start := time.Now()
+	ticker := time.NewTicker(FadeInterval)
+	defer ticker.Stop()
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= duration {
+			return c.SetVolume(ctx, playerName, to)
+		}
+		value := from + (to-from)*float64(elapsed)/float64(duration)
+		if err := c.SetVolume(ctx, playerName, value); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package mpris
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	SignalNameOwnerChanged  = DBusInterface + ".NameOwnerChanged"
+	SignalPropertiesChanged = "org.freedesktop.DBus.Properties.PropertiesChanged"
+)
+
+type mprisMonitor struct {
+	ctx    context.Context
+	client *Client
+	filter Filter
+	events chan Event
+	// recorder, when not nil, records what is read from the bus.
+	recorder *recorder
+	// replayer, when not nil, replaces the bus, client being nil.
+	replayer *replayer
+	lock     sync.Mutex
+	players  map[string]*PlayerState
+	tickers  map[string]*resumableTicker
+}
+
+func newMprisMonitor(ctx context.Context, client *Client, filter Filter) *mprisMonitor {
+	return &mprisMonitor{
+		ctx:     ctx,
+		client:  client,
+		filter:  filter,
+		events:  make(chan Event, 64),
+		players: make(map[string]*PlayerState),
+		tickers: make(map[string]*resumableTicker),
+	}
+}
+
+var eventMapping = map[string]EventType{
+	FieldMetadata:       EventTrackChanged,
+	FieldPlaybackStatus: EventPlaybackChanged,
+	FieldLoopStatus:     EventLoopStatusChanged,
+	FieldShuffle:        EventShuffleChanged,
+	FieldVolume:         EventVolumeChanged,
+	FieldCanControl:     EventCapabilitiesChanged,
+	FieldCanGoNext:      EventCapabilitiesChanged,
+	FieldCanGoPrevious:  EventCapabilitiesChanged,
+	FieldCanPause:       EventCapabilitiesChanged,
+	FieldCanPlay:        EventCapabilitiesChanged,
+	FieldCanSeek:        EventCapabilitiesChanged,
+}
+
+var signalMapping = map[string]func(monitor *mprisMonitor, signal *dbus.Signal){
+	SignalNameOwnerChanged:  onNameOwnerChanged,
+	SignalPropertiesChanged: onPropertiesChanged,
+	SignalSeeked:            onSeeked,
+}
+
+func onNameOwnerChanged(monitor *mprisMonitor, signal *dbus.Signal) {
+	var busName, oldOwner, newOwner string
+	if err := dbus.Store(signal.Body, &busName, &oldOwner, &newOwner); err != nil {
+		return
+	}
+	playerName, isMprisPlayer := PlayerName(busName)
+	if isMprisPlayer == false || IsProxy(playerName) || (monitor.client != nil && monitor.client.Ignores(playerName)) {
+		return
+	}
+
+	if player, found := monitor.players[oldOwner]; found {
+		monitor.unregisterPlayer(player)
+		monitor.emit(EventPlayerVanished, player)
+	}
+	if newOwner != "" {
+		player, err := monitor.loadPlayer(playerName)
+		if err != nil {
+			return
+		}
+		monitor.registerPlayer(player)
+		monitor.emit(EventPlayerAppeared, player)
+	}
+}
+
+func onPropertiesChanged(monitor *mprisMonitor, signal *dbus.Signal) {
+	player, found := monitor.players[signal.Sender]
+	if found == false {
+		return
+	}
+
+	var iface string
+	var values map[string]dbus.Variant
+	var invalidated []string
+	if err := dbus.Store(signal.Body, &iface, &values, &invalidated); err != nil {
+		return
+	}
+	if iface == RootInterface {
+		for _, field := range []string{FieldIdentity, FieldDesktopEntry} {
+			if value, found := values[field]; found {
+				player.Update(map[string]dbus.Variant{field: value})
+			}
+		}
+		return
+	}
+	if iface != PlayerInterface {
+		return
+	}
+
+	shouldUpdateTicker := false
+	eventTypes := make(map[EventType]bool)
+	for _, updateKey := range player.Update(values) {
+		if eventType, mapped := eventMapping[updateKey]; mapped {
+			eventTypes[eventType] = true
+		}
+		if updateKey == FieldPosition || updateKey == FieldPlaybackStatus {
+			shouldUpdateTicker = true
+		}
+	}
+	for _, eventType := range EventTypes {
+		if eventTypes[eventType] {
+			monitor.emit(eventType, player)
+		}
+	}
+
+	if shouldUpdateTicker {
+		monitor.updateTicker(player.Owner, player.PlaybackStatus, untilNextSecond(player.Position))
+	}
+}
+
+func onSeeked(monitor *mprisMonitor, signal *dbus.Signal) {
+	if player, found := monitor.players[signal.Sender]; found {
+		var position int64
+		if err := dbus.Store(signal.Body, &position); err != nil {
+			return
+		}
+		player.Position = time.Duration(position) * time.Microsecond
+		monitor.emit(EventSeeked, player)
+		monitor.updateTicker(player.Owner, player.PlaybackStatus, untilNextSecond(player.Position))
+	}
+}
+
+// Subscribe watches the players on the bus and sends the events selected by filter.
+// Every player already on the bus is first reported with EventPlayerAppeared.
+// The channel is closed once ctx is done, or if the bus cannot be watched.
+func (c *Client) Subscribe(ctx context.Context, filter Filter) <-chan Event {
+	monitor := newMprisMonitor(ctx, c, filter)
+	go monitor.run()
+	return monitor.events
+}
+
+func (m *mprisMonitor) run() {
+	defer close(m.events)
+
+	if m.replayer != nil {
+		m.replay()
+		return
+	}
+
+	signals, err := m.watchSignal()
+	if err != nil {
+		return
+	}
+	defer m.unwatchSignal(signals)
+
+	playerNames, err := m.client.PlayerNames(m.ctx)
+	if err != nil {
+		return
+	}
+
+	m.lock.Lock()
+	for _, playerName := range playerNames {
+		// Proxies forward the events of another player, which would be reported twice.
+		if IsProxy(playerName) {
+			continue
+		}
+		player, err := m.loadPlayer(playerName)
+		if errors.Is(err, ErrPlayerNotFound) {
+			continue
+		}
+		if err != nil {
+			m.lock.Unlock()
+			return
+		}
+		m.appear(player)
+	}
+	m.lock.Unlock()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case signal, ok := <-signals:
+			if ok == false {
+				return
+			}
+			m.lock.Lock()
+			m.recorder.signal(signal)
+			if handler, supported := signalMapping[signal.Name]; supported {
+				handler(m, signal)
+			}
+			failed := m.recorder != nil && m.recorder.err != nil
+			m.lock.Unlock()
+			if failed {
+				return
+			}
+		}
+	}
+}
+
+// loadPlayer reads the state of a player, from the bus or from the recording being replayed.
+func (m *mprisMonitor) loadPlayer(playerName string) (*PlayerState, error) {
+	if m.replayer != nil {
+		return m.replayer.snapshot(playerName)
+	}
+	player, values, err := m.client.playerValues(m.ctx, playerName)
+	m.recorder.snapshot(playerName, player, values, err)
+	return player, err
+}
+
+// appear reports a player found on the bus when subscribing.
+func (m *mprisMonitor) appear(player *PlayerState) {
+	m.registerPlayer(player)
+	m.emit(EventPlayerAppeared, player)
+	m.updateTicker(player.Owner, player.PlaybackStatus, untilNextSecond(player.Position))
+}
+
+func (m *mprisMonitor) emit(eventType EventType, player *PlayerState) {
+	event := Event{
+		Type:   eventType,
+		Player: *player,
+	}
+	if m.filter.Match(event) == false {
+		return
+	}
+	select {
+	case m.events <- event:
+	case <-m.ctx.Done():
+	}
+}
+
+func untilNextSecond(position time.Duration) time.Duration {
+	elapsed := position % time.Second
+	return time.Second - elapsed
+}
+
+func (m *mprisMonitor) watchSignal() (chan *dbus.Signal, error) {
+	err := m.client.dbus.addMatchSignal(
+		dbus.WithMatchObjectPath(DBusPath),
+		dbus.WithMatchInterface(DBusInterface),
+		dbus.WithMatchSender(DBusName),
+		dbus.WithMatchMember("NameOwnerChanged"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	channel := make(chan *dbus.Signal, 10)
+	m.client.dbus.watchSignal(channel)
+	return channel, nil
+}
+
+func (m *mprisMonitor) unwatchSignal(channel chan *dbus.Signal) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.client.dbus.unwatchSignal(channel)
+	m.client.dbus.removeMatchSignal(
+		dbus.WithMatchObjectPath(DBusPath),
+		dbus.WithMatchInterface(DBusInterface),
+		dbus.WithMatchSender(DBusName),
+		dbus.WithMatchMember("NameOwnerChanged"),
+	)
+	for _, player := range m.players {
+		m.unregisterPlayer(player)
+	}
+}
+
+func (m *mprisMonitor) registerPlayer(player *PlayerState) {
+	m.players[player.Owner] = player
+	if m.replayer != nil {
+		return
+	}
+	m.client.dbus.addMatchSignal(
+		dbus.WithMatchObjectPath(ObjectPath),
+		dbus.WithMatchSender(player.BusName),
+	)
+	m.addTicker(player)
+}
+
+func (m *mprisMonitor) unregisterPlayer(player *PlayerState) {
+	delete(m.players, player.Owner)
+	if m.replayer != nil {
+		return
+	}
+	m.client.dbus.removeMatchSignal(
+		dbus.WithMatchObjectPath(ObjectPath),
+		dbus.WithMatchSender(player.BusName),
+	)
+	m.removeTicker(player.Owner)
+}
+
+func (m *mprisMonitor) addTicker(player *PlayerState) {
+	tickCallback := func() {
+		position, err := m.client.Position(m.ctx, player.Name)
+		if err != nil {
+			return
+		}
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if m.players[player.Owner] != player {
+			return
+		}
+		m.recorder.position(player.Owner, position)
+		player.Position = position
+		m.emit(EventPosition, player)
+	}
+
+	ticker := newTicker(1*time.Second, tickCallback)
+	m.tickers[player.Owner] = ticker
+}
+
+func (m *mprisMonitor) removeTicker(owner string) {
+	if ticker, ok := m.tickers[owner]; ok {
+		ticker.stop()
+		delete(m.tickers, owner)
+	}
+}
+
+func (m *mprisMonitor) updateTicker(owner string, playbackStatus PlaybackStatus, delay time.Duration) {
+	if ticker, ok := m.tickers[owner]; ok {
+		switch playbackStatus {
+		case PlaybackPaused:
+			ticker.pause()
+		case PlaybackPlaying:
+			ticker.resumeOrStartAfter(delay)
+		case PlaybackStopped:
+			ticker.stop()
+		}
+	}
+}
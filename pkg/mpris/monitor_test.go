@@ -0,0 +1,176 @@
+package mpris_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris/mpristest"
+)
+
+// nextEvent returns the next event other than EventPosition, failing the test after a while.
+func nextEvent(t *testing.T, events <-chan mpris.Event) mpris.Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if ok == false {
+				t.Fatal("events closed")
+			}
+			if event.Type != mpris.EventPosition {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func subscribe(t *testing.T, bus *mpristest.Bus, filter mpris.Filter) <-chan mpris.Event {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return mpris.NewWithBus(bus).Subscribe(ctx, filter)
+}
+
+func TestSubscribeReportsPlayersOnTheBus(t *testing.T) {
+	bus := mpristest.NewBus()
+	bus.AddPlayer("vlc").Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPaused))
+
+	event := nextEvent(t, subscribe(t, bus, mpris.Filter{}))
+	if event.Type != mpris.EventPlayerAppeared || event.Player.Name != "vlc" {
+		t.Fatalf("got %s of %q, want player-appeared of vlc", event.Type, event.Player.Name)
+	}
+	if event.Player.PlaybackStatus != mpris.PlaybackPaused {
+		t.Errorf("got status %s, want Paused", event.Player.PlaybackStatus)
+	}
+}
+
+func TestOnNameOwnerChanged(t *testing.T) {
+	bus := mpristest.NewBus()
+	bus.AddPlayer("vlc")
+	events := subscribe(t, bus, mpris.Filter{})
+	// Once the players on the bus are reported, the next ones are announced.
+	nextEvent(t, events)
+
+	player := bus.AddPlayer("spotify")
+	event := nextEvent(t, events)
+	if event.Type != mpris.EventPlayerAppeared || event.Player.Name != "spotify" || event.Player.Owner != player.Owner {
+		t.Fatalf("got %s of %q (%s), want player-appeared of spotify (%s)", event.Type, event.Player.Name, event.Player.Owner, player.Owner)
+	}
+
+	bus.RemovePlayer("spotify")
+	event = nextEvent(t, events)
+	if event.Type != mpris.EventPlayerVanished || event.Player.Name != "spotify" {
+		t.Fatalf("got %s of %q, want player-vanished of spotify", event.Type, event.Player.Name)
+	}
+}
+
+func TestOnNameOwnerChangedSkipsProxies(t *testing.T) {
+	bus := mpristest.NewBus()
+	bus.AddPlayer("vlc")
+	events := subscribe(t, bus, mpris.Filter{})
+	nextEvent(t, events)
+
+	bus.AddPlayer(mpris.PlayerctldName)
+	bus.AddPlayer("spotify")
+	if event := nextEvent(t, events); event.Player.Name != "spotify" {
+		t.Fatalf("got %s of %q, want player-appeared of spotify", event.Type, event.Player.Name)
+	}
+}
+
+func TestOnPropertiesChanged(t *testing.T) {
+	bus := mpristest.NewBus()
+	player := bus.AddPlayer("vlc")
+	events := subscribe(t, bus, mpris.Filter{})
+	nextEvent(t, events)
+
+	tests := []struct {
+		name   string
+		change func()
+		want   []mpris.EventType
+		check  func(state mpris.PlayerState) bool
+	}{
+		{
+			name:   "playback",
+			change: func() { player.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPaused)) },
+			want:   []mpris.EventType{mpris.EventPlaybackChanged},
+			check:  func(state mpris.PlayerState) bool { return state.PlaybackStatus == mpris.PlaybackPaused },
+		},
+		{
+			name:   "metadata",
+			change: func() { player.SetMetadata(map[string]interface{}{mpris.MetadataTitle: "Song"}) },
+			want:   []mpris.EventType{mpris.EventTrackChanged},
+			check:  func(state mpris.PlayerState) bool { return state.Metadata.Title == "Song" },
+		},
+		{
+			name:   "several at once, in the order of EventTypes",
+			change: func() { player.Set(mpris.FieldVolume, 0.5, mpris.FieldLoopStatus, string(mpris.LoopStatusTrack)) },
+			want:   []mpris.EventType{mpris.EventLoopStatusChanged, mpris.EventVolumeChanged},
+			check: func(state mpris.PlayerState) bool {
+				return state.Volume == 0.5 && state.LoopStatus == mpris.LoopStatusTrack
+			},
+		},
+		{
+			name:   "capabilities",
+			change: func() { player.Set(mpris.FieldCanSeek, false, mpris.FieldCanGoNext, false) },
+			want:   []mpris.EventType{mpris.EventCapabilitiesChanged},
+			check:  func(state mpris.PlayerState) bool { return state.CanSeek == false && state.CanGoNext == false },
+		},
+		{
+			name:   "seeked",
+			change: func() { player.Seek(42 * time.Second) },
+			want:   []mpris.EventType{mpris.EventSeeked},
+			check:  func(state mpris.PlayerState) bool { return state.Position == 42*time.Second },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.change()
+			for _, want := range test.want {
+				event := nextEvent(t, events)
+				if event.Type != want {
+					t.Fatalf("got %s, want %s", event.Type, want)
+				}
+				if test.check(event.Player) == false {
+					t.Errorf("unexpected state %+v", event.Player)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeDoesNotBlockTheBus(t *testing.T) {
+	bus := mpristest.NewBus()
+	player := bus.AddPlayer("vlc")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Never read: the monitor stops reading the bus once its buffers are full.
+	mpris.NewWithBus(bus).Subscribe(ctx, mpris.Filter{})
+	waitForMatches(t, bus)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			player.Set(mpris.FieldVolume, float64(i%100)/100)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("changing the player blocked")
+	}
+}
+
+// waitForMatches waits for a subscriber to watch the bus.
+func waitForMatches(t *testing.T, bus *mpristest.Bus) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); len(bus.Matches()) == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the bus is not watched")
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package mpris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	BusNamePrefix   = "org.mpris.MediaPlayer2."
+	ObjectPath      = "/org/mpris/MediaPlayer2"
+	RootInterface   = "org.mpris.MediaPlayer2"
+	PlayerInterface = "org.mpris.MediaPlayer2.Player"
+
+	methodGetOwner             = DBusInterface + ".GetNameOwner"
+	methodListNames            = DBusInterface + ".ListNames"
+	methodNameHasOwner         = DBusInterface + ".NameHasOwner"
+	methodListActivatableNames = DBusInterface + ".ListActivatableNames"
+	methodStartServiceByName   = DBusInterface + ".StartServiceByName"
+
+	propertyLoopStatus     = PlayerInterface + "." + FieldLoopStatus
+	propertyMetadata       = PlayerInterface + "." + FieldMetadata
+	propertyPlaybackStatus = PlayerInterface + "." + FieldPlaybackStatus
+	propertyPosition       = PlayerInterface + "." + FieldPosition
+	propertyRate           = PlayerInterface + "." + FieldRate
+	propertyShuffle        = PlayerInterface + "." + FieldShuffle
+	propertyVolume         = PlayerInterface + "." + FieldVolume
+
+	SignalSeeked = PlayerInterface + ".Seeked"
+
+	methodRaise = RootInterface + ".Raise"
+
+	methodNext        = PlayerInterface + ".Next"
+	methodOpenUri     = PlayerInterface + ".OpenUri"
+	methodPause       = PlayerInterface + ".Pause"
+	methodPlay        = PlayerInterface + ".Play"
+	methodPlayPause   = PlayerInterface + ".PlayPause"
+	methodPrevious    = PlayerInterface + ".Previous"
+	methodSeek        = PlayerInterface + ".Seek"
+	methodSetPosition = PlayerInterface + ".SetPosition"
+	methodStop        = PlayerInterface + ".Stop"
+)
+
+var (
+	// ErrPlayerNotFound is returned when the requested player is not on the bus.
+	ErrPlayerNotFound = errors.New("mpris: player not found")
+	// ErrInvalidValue is returned when a setter receives a value outside of what MPRIS allows.
+	ErrInvalidValue = errors.New("mpris: invalid value")
+)
+
+// Client talks to MPRIS media players over D-Bus.
+// A Client is safe for concurrent use.
+type Client struct {
+	dbus *dbusWrapper
+
+	// lock guards the settings below, which can change while c is used.
+	lock           sync.RWMutex
+	busLabel       string
+	playerctldMode PlayerctldMode
+	// ignoredPlayers are the patterns of the players left out, see SetIgnoredPlayers.
+	ignoredPlayers []string
+}
+
+// New connects to the session bus and returns a Client.
+func New() (*Client, error) {
+	bus, err := ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithBus(bus), nil
+}
+
+// NewWithAddress returns a Client connected to the bus at address,
+// like "unix:path=/run/user/1000/bus", or to the session bus when address is empty.
+func NewWithAddress(address string) (*Client, error) {
+	bus, err := ConnectBus(address)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithBus(bus), nil
+}
+
+// NewWithBus returns a Client using bus.
+// Closing the Client closes bus.
+func NewWithBus(bus Bus) *Client {
+	return &Client{
+		dbus: newDBus(bus),
+	}
+}
+
+// SetBusLabel sets the Bus field of the player states returned by c,
+// for programs using several buses at once.
+func (c *Client) SetBusLabel(label string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.busLabel = label
+}
+
+// BusLabel returns the label set by SetBusLabel.
+func (c *Client) BusLabel() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.busLabel
+}
+
+// Close closes the underlying bus connection.
+func (c *Client) Close() error {
+	return c.dbus.close()
+}
+
+// BusName returns the well-known bus name of a player.
+func BusName(playerName string) string {
+	return BusNamePrefix + playerName
+}
+
+// ParsePlayerName splits a player name into the name of the application and the
+// instance suffix some players append to run several instances at once,
+// like "firefox" and "instance_1_84" for "firefox.instance_1_84".
+// Only a last component starting with "instance" is an instance: instance is empty for
+// players without one, the dots of names like "io.github.celluloid" being kept.
+func ParsePlayerName(playerName string) (application string, instance string) {
+	if index := strings.LastIndex(playerName, "."); index > 0 && strings.HasPrefix(playerName[index+1:], "instance") {
+		return playerName[:index], playerName[index+1:]
+	}
+	return playerName, ""
+}
+
+// MatchPlayerName reports whether pattern designates playerName: either it is
+// playerName, or it is the application name of an instance, like "firefox" for "firefox.instance_1_84".
+func MatchPlayerName(pattern string, playerName string) bool {
+	if pattern == playerName {
+		return true
+	}
+	application, instance := ParsePlayerName(playerName)
+	return instance != "" && pattern == application
+}
+
+// PlayerName returns the player name of a well-known bus name,
+// and false if busName does not belong to an MPRIS player.
+func PlayerName(busName string) (string, bool) {
+	if _, playerName, ok := strings.Cut(busName, BusNamePrefix); ok && playerName != "" {
+		return playerName, true
+	}
+	return "", false
+}
+
+// PlayerNames lists the names of the players currently on the bus.
+func (c *Client) PlayerNames(ctx context.Context) ([]string, error) {
+	allPlayerNames, err := c.allPlayerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	showProxies := c.playerctld() == PlayerctldShow
+	playerNames := make([]string, 0, len(allPlayerNames))
+	for _, playerName := range allPlayerNames {
+		if (IsProxy(playerName) && showProxies == false) || c.Ignores(playerName) {
+			continue
+		}
+		playerNames = append(playerNames, playerName)
+	}
+	return playerNames, nil
+}
+
+// allPlayerNames lists the names of every player on the bus, including the proxies and the ones ignored.
+func (c *Client) allPlayerNames(ctx context.Context) ([]string, error) {
+	var busNames []string
+	if err := c.dbus.callMethodWithBusObject(ctx, methodListNames).Store(&busNames); err != nil {
+		return nil, err
+	}
+
+	playerNames := make([]string, 0)
+	for _, busName := range busNames {
+		if playerName, isMprisPlayer := PlayerName(busName); isMprisPlayer {
+			playerNames = append(playerNames, playerName)
+		}
+	}
+	return playerNames, nil
+}
+
+// SetIgnoredPlayers leaves the players matching one of patterns out of the lists and the events.
+// They can still be controlled by name. Patterns match as in MatchPlayerName.
+func (c *Client) SetIgnoredPlayers(patterns []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ignoredPlayers = append([]string(nil), patterns...)
+}
+
+// Ignores reports whether playerName is left out by SetIgnoredPlayers.
+func (c *Client) Ignores(playerName string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, pattern := range c.ignoredPlayers {
+		if MatchPlayerName(pattern, playerName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivatablePlayerNames lists the names of the players the bus can start, see Activate.
+func (c *Client) ActivatablePlayerNames(ctx context.Context) ([]string, error) {
+	var busNames []string
+	if err := c.dbus.callMethodWithBusObject(ctx, methodListActivatableNames).Store(&busNames); err != nil {
+		return nil, err
+	}
+
+	playerNames := make([]string, 0)
+	for _, busName := range busNames {
+		if playerName, isMprisPlayer := PlayerName(busName); isMprisPlayer {
+			playerNames = append(playerNames, playerName)
+		}
+	}
+	return playerNames, nil
+}
+
+// Activate asks the bus to start the player named playerName, which must be
+// one of ActivatablePlayerNames, and returns once it owns its name.
+func (c *Client) Activate(ctx context.Context, playerName string) error {
+	var reply uint32
+	return c.dbus.callMethodWithBusObject(ctx, methodStartServiceByName, BusName(playerName), uint32(0)).Store(&reply)
+}
+
+// WaitForPlayer waits until a player designated by playerName, see MatchPlayerName,
+// is on the bus, and returns its state.
+func (c *Client) WaitForPlayer(ctx context.Context, playerName string) (*PlayerState, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	filter := Filter{Players: []string{playerName}, Types: []EventType{EventPlayerAppeared}}
+	if event, ok := <-c.Subscribe(ctx, filter); ok {
+		return &event.Player, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("mpris: lost connection to the bus")
+}
+
+// Players returns the state of every player currently on the bus.
+func (c *Client) Players(ctx context.Context) ([]*PlayerState, error) {
+	playerNames, err := c.PlayerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make([]*PlayerState, 0, len(playerNames))
+	for _, playerName := range playerNames {
+		player, err := c.Player(ctx, playerName)
+		if errors.Is(err, ErrPlayerNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, player)
+	}
+	return players, nil
+}
+
+// Player returns the full state of a player.
+func (c *Client) Player(ctx context.Context, playerName string) (*PlayerState, error) {
+	player, _, err := c.playerValues(ctx, playerName)
+	return player, err
+}
+
+// playerValues returns the state of a player along with the properties it was read from.
+func (c *Client) playerValues(ctx context.Context, playerName string) (*PlayerState, map[string]dbus.Variant, error) {
+	busName, err := c.resolve(ctx, playerName)
+	if err != nil {
+		return nil, nil, err
+	}
+	playerName, _ = PlayerName(busName)
+
+	owner, err := c.owner(ctx, busName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := c.dbus.getAll(ctx, busName, ObjectPath, PlayerInterface)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The root interface is optional for the purpose of reading a state: some players get it wrong.
+	if rootValues, err := c.dbus.getAll(ctx, busName, ObjectPath, RootInterface); err == nil {
+		for _, field := range []string{FieldIdentity, FieldDesktopEntry} {
+			if value, found := rootValues[field]; found {
+				values[field] = value
+			}
+		}
+	}
+
+	player := newPlayerState(playerName, busName, owner)
+	player.Bus = c.BusLabel()
+	player.Update(values)
+	return player, values, nil
+}
+
+// resolve returns the bus name of playerName. A name without an instance,
+// like "firefox", also matches the instances of the player, like "firefox.instance_1_84".
+// The players ignored, see SetIgnoredPlayers, are resolved too.
+// With PlayerctldProxy, playerctld resolves to the player it fronts.
+func (c *Client) resolve(ctx context.Context, playerName string) (string, error) {
+	if playerName == PlayerctldName && c.playerctld() == PlayerctldProxy {
+		activePlayer, err := c.PlayerctldActivePlayer(ctx)
+		if err != nil {
+			return "", err
+		}
+		playerName = activePlayer
+	}
+
+	busName := BusName(playerName)
+	hasOwner := false
+	if err := c.dbus.callMethodWithBusObject(ctx, methodNameHasOwner, busName).Store(&hasOwner); err != nil {
+		return "", err
+	}
+	if hasOwner {
+		return busName, nil
+	}
+
+	if _, instance := ParsePlayerName(playerName); instance == "" {
+		playerNames, err := c.allPlayerNames(ctx)
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(playerNames)
+		for _, name := range playerNames {
+			if MatchPlayerName(playerName, name) {
+				return BusName(name), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrPlayerNotFound, playerName)
+}
+
+func (c *Client) owner(ctx context.Context, busName string) (string, error) {
+	var owner string
+	err := c.dbus.callMethodWithBusObject(ctx, methodGetOwner, busName).Store(&owner)
+	return owner, err
+}
+
+func (c *Client) callMethod(ctx context.Context, playerName string, method string, args ...interface{}) error {
+	busName, err := c.resolve(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	return c.dbus.callMethod(ctx, busName, ObjectPath, method, args...).Err
+}
+
+func getProperty[T any](ctx context.Context, c *Client, playerName string, property string, converter func(interface{}) (T, bool)) (T, error) {
+	busName, err := c.resolve(ctx, playerName)
+	if err != nil {
+		return zeroValue[T](), err
+	}
+	variant, err := c.dbus.getProperty(ctx, busName, ObjectPath, property)
+	if err != nil {
+		return zeroValue[T](), err
+	}
+	value, _ := converter(variant.Value())
+	return value, nil
+}
+
+func (c *Client) setProperty(ctx context.Context, playerName string, property string, value interface{}) error {
+	busName, err := c.resolve(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	return c.dbus.setProperty(ctx, busName, ObjectPath, property, value)
+}
+
+// Raise brings the user interface of the player to the front.
+func (c *Client) Raise(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodRaise)
+}
+
+// Play starts or resumes playback.
+func (c *Client) Play(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodPlay)
+}
+
+// Pause pauses playback.
+func (c *Client) Pause(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodPause)
+}
+
+// PlayPause toggles between playing and paused.
+func (c *Client) PlayPause(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodPlayPause)
+}
+
+// Next skips to the next track.
+func (c *Client) Next(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodNext)
+}
+
+// Previous skips to the previous track.
+func (c *Client) Previous(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodPrevious)
+}
+
+// Stop stops playback.
+func (c *Client) Stop(ctx context.Context, playerName string) error {
+	return c.callMethod(ctx, playerName, methodStop)
+}
+
+// Seek moves the position forward, or backward when offset is negative.
+func (c *Client) Seek(ctx context.Context, playerName string, offset time.Duration) error {
+	return c.callMethod(ctx, playerName, methodSeek, offset.Microseconds())
+}
+
+// OpenUri asks the player to open and play uri.
+func (c *Client) OpenUri(ctx context.Context, playerName string, uri string) error {
+	return c.callMethod(ctx, playerName, methodOpenUri, uri)
+}
+
+// Position returns the current position in the track.
+func (c *Client) Position(ctx context.Context, playerName string) (time.Duration, error) {
+	return getProperty(ctx, c, playerName, propertyPosition, convertToDuration)
+}
+
+// SetPosition moves to position in the current track.
+func (c *Client) SetPosition(ctx context.Context, playerName string, position time.Duration) error {
+	metadata, err := c.Metadata(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	return c.callMethod(ctx, playerName, methodSetPosition, dbus.ObjectPath(metadata.TrackId), position.Microseconds())
+}
+
+// Metadata returns the metadata of the current track.
+func (c *Client) Metadata(ctx context.Context, playerName string) (Metadata, error) {
+	return getProperty(ctx, c, playerName, propertyMetadata, convertToMetadata)
+}
+
+// PlaybackStatus returns whether the player is playing, paused or stopped.
+func (c *Client) PlaybackStatus(ctx context.Context, playerName string) (PlaybackStatus, error) {
+	return getProperty(ctx, c, playerName, propertyPlaybackStatus, convertToPlaybackStatus)
+}
+
+// LoopStatus returns the loop status of the player.
+func (c *Client) LoopStatus(ctx context.Context, playerName string) (LoopStatus, error) {
+	return getProperty(ctx, c, playerName, propertyLoopStatus, convertToLoopStatus)
+}
+
+// SetLoopStatus changes the loop status of the player.
+func (c *Client) SetLoopStatus(ctx context.Context, playerName string, value LoopStatus) error {
+	if value.IsValid() == false {
+		return fmt.Errorf("%w: loop status %q", ErrInvalidValue, value)
+	}
+	return c.setProperty(ctx, playerName, propertyLoopStatus, string(value))
+}
+
+// Shuffle returns whether the player plays tracks in a random order.
+func (c *Client) Shuffle(ctx context.Context, playerName string) (bool, error) {
+	return getProperty(ctx, c, playerName, propertyShuffle, convertToBool)
+}
+
+// SetShuffle turns shuffle on or off.
+func (c *Client) SetShuffle(ctx context.Context, playerName string, value bool) error {
+	return c.setProperty(ctx, playerName, propertyShuffle, value)
+}
+
+// Volume returns the volume of the player, 1.0 being the nominal volume.
+func (c *Client) Volume(ctx context.Context, playerName string) (float64, error) {
+	return getProperty(ctx, c, playerName, propertyVolume, convertToFloat64)
+}
+
+// SetVolume changes the volume of the player. Negative values are clamped to 0.
+func (c *Client) SetVolume(ctx context.Context, playerName string, value float64) error {
+	if value < 0 {
+		value = 0
+	}
+	return c.setProperty(ctx, playerName, propertyVolume, value)
+}
+
+// Rate returns the playback rate of the player, 1.0 being the normal speed.
+func (c *Client) Rate(ctx context.Context, playerName string) (float64, error) {
+	return getProperty(ctx, c, playerName, propertyRate, convertToFloat64)
+}
+
+// SetRate changes the playback rate of the player.
+func (c *Client) SetRate(ctx context.Context, playerName string, value float64) error {
+	if value <= 0 {
+		return fmt.Errorf("%w: rate %v", ErrInvalidValue, value)
+	}
+	return c.setProperty(ctx, playerName, propertyRate, value)
+}
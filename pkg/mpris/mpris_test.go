@@ -0,0 +1,111 @@
+package mpris_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris/mpristest"
+)
+
+func TestSetPosition(t *testing.T) {
+	bus := mpristest.NewBus()
+	player := bus.AddPlayer("vlc")
+	player.SetMetadata(map[string]interface{}{
+		mpris.MetadataTrackId: dbus.ObjectPath("/org/videolan/vlc/track/3"),
+		mpris.MetadataLength:  int64(3 * time.Minute / time.Microsecond),
+	})
+	client := mpris.NewWithBus(bus)
+
+	if err := client.SetPosition(context.Background(), "vlc", 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	calls := player.Calls()
+	if len(calls) != 1 || calls[0].Method != "SetPosition" {
+		t.Fatalf("got calls %v, want a single SetPosition", calls)
+	}
+	if trackId := calls[0].Args[0]; trackId != dbus.ObjectPath("/org/videolan/vlc/track/3") {
+		t.Errorf("got track id %v, want the one of the current track", trackId)
+	}
+	if position := calls[0].Args[1]; position != int64(90_000_000) {
+		t.Errorf("got position %v, want microseconds", position)
+	}
+	if position, err := client.Position(context.Background(), "vlc"); err != nil || position != 90*time.Second {
+		t.Errorf("got position %v (%v), want 1m30s", position, err)
+	}
+}
+
+func TestSetPositionUnknownPlayer(t *testing.T) {
+	client := mpris.NewWithBus(mpristest.NewBus())
+	if err := client.SetPosition(context.Background(), "vlc", time.Second); err == nil {
+		t.Fatal("expecting an error")
+	}
+}
+
+func TestIgnoredPlayersAreControlledByName(t *testing.T) {
+	bus := mpristest.NewBus()
+	firefox := bus.AddPlayer("firefox.instance_1_84")
+	bus.AddPlayer("vlc")
+	client := mpris.NewWithBus(bus)
+	client.SetIgnoredPlayers([]string{"firefox"})
+
+	playerNames, err := client.PlayerNames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playerNames) != 1 || playerNames[0] != "vlc" {
+		t.Errorf("got players %v, want vlc only", playerNames)
+	}
+
+	// Both the full name and the application name resolve the instance.
+	for _, name := range []string{"firefox.instance_1_84", "firefox"} {
+		if err := client.Play(context.Background(), name); err != nil {
+			t.Fatalf("playing %s: %v", name, err)
+		}
+	}
+	if calls := firefox.Calls(); len(calls) != 2 {
+		t.Errorf("got calls %v, want 2 Play", calls)
+	}
+}
+
+func TestParsePlayerName(t *testing.T) {
+	tests := []struct {
+		playerName  string
+		application string
+		instance    string
+	}{
+		{"vlc", "vlc", ""},
+		{"firefox.instance_1_84", "firefox", "instance_1_84"},
+		{"chromium.instance2417", "chromium", "instance2417"},
+		{"io.github.celluloid", "io.github.celluloid", ""},
+		{"io.github.celluloid.instance3", "io.github.celluloid", "instance3"},
+		{"kdeconnect.mpris_phone", "kdeconnect.mpris_phone", ""},
+	}
+	for _, test := range tests {
+		application, instance := mpris.ParsePlayerName(test.playerName)
+		if application != test.application || instance != test.instance {
+			t.Errorf("ParsePlayerName(%q) = %q, %q, want %q, %q", test.playerName, application, instance, test.application, test.instance)
+		}
+	}
+}
+
+func TestMatchPlayerName(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		playerName string
+		match      bool
+	}{
+		{"firefox", "firefox.instance_1_84", true},
+		{"firefox.instance_1_84", "firefox.instance_1_84", true},
+		{"firefox.instance_2", "firefox.instance_1_84", false},
+		{"io", "io.github.celluloid", false},
+		{"io.github", "io.github.celluloid", false},
+	}
+	for _, test := range tests {
+		if match := mpris.MatchPlayerName(test.pattern, test.playerName); match != test.match {
+			t.Errorf("MatchPlayerName(%q, %q) = %v, want %v", test.pattern, test.playerName, match, test.match)
+		}
+	}
+}
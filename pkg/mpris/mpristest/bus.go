@@ -0,0 +1,320 @@
+// Package mpristest provides an in-memory mpris.Bus with scriptable players,
+// to test code using mpris.Client without a D-Bus daemon.
+package mpristest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+const (
+	methodGet    = "org.freedesktop.DBus.Properties.Get"
+	methodGetAll = "org.freedesktop.DBus.Properties.GetAll"
+	methodSet    = "org.freedesktop.DBus.Properties.Set"
+
+	methodGetNameOwner = mpris.DBusInterface + ".GetNameOwner"
+	methodListNames    = mpris.DBusInterface + ".ListNames"
+	methodNameHasOwner = mpris.DBusInterface + ".NameHasOwner"
+)
+
+var (
+	errUnknownMethod   = dbus.NewError("org.freedesktop.DBus.Error.UnknownMethod", nil)
+	errUnknownProperty = dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", nil)
+	errNameHasNoOwner  = dbus.NewError("org.freedesktop.DBus.Error.NameHasNoOwner", nil)
+	errServiceUnknown  = dbus.NewError("org.freedesktop.DBus.Error.ServiceUnknown", nil)
+)
+
+// Bus is an in-memory mpris.Bus.
+// Signals are delivered to every registered channel regardless of match rules,
+// in the order they were emitted. Each channel is fed by its own goroutine, so
+// a channel not read does not block the players or the other channels.
+type Bus struct {
+	lock        sync.Mutex
+	nextId      int
+	players     map[string]*Player
+	subscribers []*subscriber
+	matches     map[string]int
+	closed      bool
+}
+
+// subscriber queues the signals of a channel registered with Signal.
+type subscriber struct {
+	channel chan<- *dbus.Signal
+	lock    sync.Mutex
+	queue   []*dbus.Signal
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newSubscriber(channel chan<- *dbus.Signal) *subscriber {
+	s := &subscriber{
+		channel: channel,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go s.deliver()
+	return s
+}
+
+func (s *subscriber) push(signal *dbus.Signal) {
+	s.lock.Lock()
+	s.queue = append(s.queue, signal)
+	s.lock.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) deliver() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+		for {
+			s.lock.Lock()
+			if len(s.queue) == 0 {
+				s.lock.Unlock()
+				break
+			}
+			signal := s.queue[0]
+			s.queue = s.queue[1:]
+			s.lock.Unlock()
+
+			select {
+			case s.channel <- signal:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// NewBus returns an empty Bus.
+func NewBus() *Bus {
+	return &Bus{
+		nextId:  1,
+		players: make(map[string]*Player),
+		matches: make(map[string]int),
+	}
+}
+
+// AddPlayer puts a player named name on the bus and announces it with NameOwnerChanged.
+func (b *Bus) AddPlayer(name string) *Player {
+	b.lock.Lock()
+	player := newPlayer(b, name, fmt.Sprintf(":1.%d", b.nextId))
+	b.nextId++
+	b.players[player.BusName] = player
+	b.lock.Unlock()
+
+	b.emit(&dbus.Signal{
+		Sender: mpris.DBusName,
+		Path:   mpris.DBusPath,
+		Name:   mpris.SignalNameOwnerChanged,
+		Body:   []interface{}{player.BusName, "", player.Owner},
+	})
+	return player
+}
+
+// RemovePlayer takes a player off the bus and announces it with NameOwnerChanged.
+func (b *Bus) RemovePlayer(name string) {
+	b.lock.Lock()
+	player, found := b.players[mpris.BusName(name)]
+	delete(b.players, mpris.BusName(name))
+	b.lock.Unlock()
+
+	if found {
+		b.emit(&dbus.Signal{
+			Sender: mpris.DBusName,
+			Path:   mpris.DBusPath,
+			Name:   mpris.SignalNameOwnerChanged,
+			Body:   []interface{}{player.BusName, player.Owner, ""},
+		})
+	}
+}
+
+// Player returns the player named name, or nil.
+func (b *Bus) Player(name string) *Player {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.players[mpris.BusName(name)]
+}
+
+// Matches returns the match rules currently added, as strings.
+func (b *Bus) Matches() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	matches := make([]string, 0, len(b.matches))
+	for rule, count := range b.matches {
+		for i := 0; i < count; i++ {
+			matches = append(matches, rule)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func (b *Bus) player(dest string) (*Player, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if player, found := b.players[dest]; found {
+		return player, nil
+	}
+	for _, player := range b.players {
+		if player.Owner == dest {
+			return player, nil
+		}
+	}
+	return nil, errServiceUnknown
+}
+
+func (b *Bus) emit(signal *dbus.Signal) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	// Queued under the lock, so concurrent emitters are seen in the same order by every channel.
+	for _, subscriber := range b.subscribers {
+		subscriber.push(signal)
+	}
+}
+
+func (b *Bus) Call(ctx context.Context, dest string, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	call := &dbus.Call{
+		Destination: dest,
+		Path:        path,
+		Method:      method,
+		Args:        args,
+	}
+	if err := ctx.Err(); err != nil {
+		call.Err = err
+		return call
+	}
+	if dest == mpris.DBusName {
+		call.Body, call.Err = b.callDBus(method, args)
+		return call
+	}
+
+	player, err := b.player(dest)
+	if err != nil {
+		call.Err = err
+		return call
+	}
+	if path != mpris.ObjectPath {
+		call.Err = errUnknownMethod
+		return call
+	}
+	call.Body, call.Err = player.call(method, args)
+	return call
+}
+
+func (b *Bus) callDBus(method string, args []interface{}) ([]interface{}, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch method {
+	case methodListNames:
+		names := []string{mpris.DBusName}
+		for busName, player := range b.players {
+			names = append(names, busName, player.Owner)
+		}
+		sort.Strings(names)
+		return []interface{}{names}, nil
+	case methodNameHasOwner:
+		_, found := b.players[args[0].(string)]
+		return []interface{}{found}, nil
+	case methodGetNameOwner:
+		if player, found := b.players[args[0].(string)]; found {
+			return []interface{}{player.Owner}, nil
+		}
+		return nil, errNameHasNoOwner
+	default:
+		return nil, errUnknownMethod
+	}
+}
+
+func (b *Bus) GetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string) (dbus.Variant, error) {
+	var value dbus.Variant
+	iface, name := splitProperty(property)
+	err := b.Call(ctx, dest, path, methodGet, iface, name).Store(&value)
+	return value, err
+}
+
+func (b *Bus) SetProperty(ctx context.Context, dest string, path dbus.ObjectPath, property string, value interface{}) error {
+	iface, name := splitProperty(property)
+	return b.Call(ctx, dest, path, methodSet, iface, name, dbus.MakeVariant(value)).Err
+}
+
+func (b *Bus) AddMatchSignal(options ...dbus.MatchOption) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.matches[matchRule(options)]++
+	return nil
+}
+
+func (b *Bus) RemoveMatchSignal(options ...dbus.MatchOption) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	rule := matchRule(options)
+	if b.matches[rule] > 1 {
+		b.matches[rule]--
+	} else {
+		delete(b.matches, rule)
+	}
+	return nil
+}
+
+func (b *Bus) Signal(channel chan<- *dbus.Signal) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers = append(b.subscribers, newSubscriber(channel))
+}
+
+func (b *Bus) RemoveSignal(channel chan<- *dbus.Signal) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i, subscriber := range b.subscribers {
+		if subscriber.channel == channel {
+			close(subscriber.done)
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Bus) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	return nil
+}
+
+// Closed reports whether Close was called.
+func (b *Bus) Closed() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.closed
+}
+
+// matchRule renders options like the match rules sent to a real bus, with sorted keys.
+func matchRule(options []dbus.MatchOption) string {
+	parts := make([]string, 0, len(options))
+	for _, option := range options {
+		// dbus.MatchOption does not export its key and value.
+		key, value, _ := strings.Cut(strings.Trim(fmt.Sprint(option), "{}"), " ")
+		parts = append(parts, fmt.Sprintf("%s='%s'", key, value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func splitProperty(property string) (string, string) {
+	i := strings.LastIndex(property, ".")
+	return property[:i], property[i+1:]
+}
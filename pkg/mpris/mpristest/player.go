@@ -0,0 +1,212 @@
+package mpristest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/webflo-dev/mpris-ctl/pkg/mpris"
+)
+
+// Call records a method called on a Player.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Player is a player of a Bus.
+// Its transport methods update PlaybackStatus and Position like a real player would,
+// and every change is announced with PropertiesChanged or Seeked.
+type Player struct {
+	Name    string
+	BusName string
+	Owner   string
+
+	// OnCall, when set, is invoked instead of the default behaviour of the
+	// org.mpris.MediaPlayer2.Player methods. method is the short method name, like "Play".
+	OnCall func(player *Player, method string, args []interface{}) error
+
+	bus        *Bus
+	lock       sync.Mutex
+	properties map[string]dbus.Variant
+	calls      []Call
+}
+
+func newPlayer(bus *Bus, name string, owner string) *Player {
+	return &Player{
+		Name:    name,
+		BusName: mpris.BusName(name),
+		Owner:   owner,
+		bus:     bus,
+		properties: map[string]dbus.Variant{
+			mpris.FieldCanControl:     dbus.MakeVariant(true),
+			mpris.FieldCanGoNext:      dbus.MakeVariant(true),
+			mpris.FieldCanGoPrevious:  dbus.MakeVariant(true),
+			mpris.FieldCanPause:       dbus.MakeVariant(true),
+			mpris.FieldCanPlay:        dbus.MakeVariant(true),
+			mpris.FieldCanSeek:        dbus.MakeVariant(true),
+			mpris.FieldLoopStatus:     dbus.MakeVariant(string(mpris.LoopStatusNone)),
+			mpris.FieldMaximumRate:    dbus.MakeVariant(1.0),
+			mpris.FieldMetadata:       dbus.MakeVariant(map[string]dbus.Variant{}),
+			mpris.FieldMinimumRate:    dbus.MakeVariant(1.0),
+			mpris.FieldPlaybackStatus: dbus.MakeVariant(string(mpris.PlaybackStopped)),
+			mpris.FieldPosition:       dbus.MakeVariant(int64(0)),
+			mpris.FieldRate:           dbus.MakeVariant(1.0),
+			mpris.FieldShuffle:        dbus.MakeVariant(false),
+			mpris.FieldVolume:         dbus.MakeVariant(1.0),
+		},
+	}
+}
+
+// Calls returns the methods called on the player so far.
+func (p *Player) Calls() []Call {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// Get returns the value of a property of the org.mpris.MediaPlayer2.Player interface.
+func (p *Player) Get(field string) interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.properties[field].Value()
+}
+
+// Set changes properties of the org.mpris.MediaPlayer2.Player interface,
+// given as field/value pairs, and announces them with a single PropertiesChanged.
+// Like real players, changes of Position are not announced; use Seek for that.
+func (p *Player) Set(fieldValues ...interface{}) {
+	changed := make(map[string]dbus.Variant)
+	p.lock.Lock()
+	for i := 0; i+1 < len(fieldValues); i += 2 {
+		field := fieldValues[i].(string)
+		value := dbus.MakeVariant(fieldValues[i+1])
+		p.properties[field] = value
+		if field != mpris.FieldPosition {
+			changed[field] = value
+		}
+	}
+	p.lock.Unlock()
+
+	if len(changed) > 0 {
+		p.bus.emit(&dbus.Signal{
+			Sender: p.Owner,
+			Path:   mpris.ObjectPath,
+			Name:   mpris.SignalPropertiesChanged,
+			Body:   []interface{}{mpris.PlayerInterface, changed, []string{}},
+		})
+	}
+}
+
+// SetMetadata replaces the metadata of the current track.
+func (p *Player) SetMetadata(metadata map[string]interface{}) {
+	values := make(map[string]dbus.Variant, len(metadata))
+	for key, value := range metadata {
+		values[key] = dbus.MakeVariant(value)
+	}
+	p.Set(mpris.FieldMetadata, values)
+}
+
+// Seek moves to position and announces it with Seeked.
+func (p *Player) Seek(position time.Duration) {
+	p.lock.Lock()
+	p.properties[mpris.FieldPosition] = dbus.MakeVariant(position.Microseconds())
+	p.lock.Unlock()
+
+	p.bus.emit(&dbus.Signal{
+		Sender: p.Owner,
+		Path:   mpris.ObjectPath,
+		Name:   mpris.SignalSeeked,
+		Body:   []interface{}{position.Microseconds()},
+	})
+}
+
+func (p *Player) call(method string, args []interface{}) ([]interface{}, error) {
+	switch method {
+	case methodGet:
+		return p.getProperty(args[0].(string), args[1].(string))
+	case methodGetAll:
+		return p.getAll(args[0].(string))
+	case methodSet:
+		return nil, p.setProperty(args[0].(string), args[1].(string), args[2].(dbus.Variant))
+	}
+
+	iface, name := splitProperty(method)
+	if iface != mpris.PlayerInterface {
+		return nil, errUnknownMethod
+	}
+
+	p.lock.Lock()
+	p.calls = append(p.calls, Call{Method: name, Args: args})
+	onCall := p.OnCall
+	p.lock.Unlock()
+
+	if onCall != nil {
+		return nil, onCall(p, name, args)
+	}
+	return nil, p.defaultCall(name, args)
+}
+
+func (p *Player) defaultCall(method string, args []interface{}) error {
+	switch method {
+	case "Play":
+		p.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPlaying))
+	case "Pause":
+		p.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPaused))
+	case "PlayPause":
+		if p.Get(mpris.FieldPlaybackStatus) == string(mpris.PlaybackPlaying) {
+			p.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPaused))
+		} else {
+			p.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackPlaying))
+		}
+	case "Stop":
+		p.Set(mpris.FieldPlaybackStatus, string(mpris.PlaybackStopped))
+		p.Seek(0)
+	case "Seek":
+		position := p.Get(mpris.FieldPosition).(int64) + args[0].(int64)
+		p.Seek(time.Duration(max(position, 0)) * time.Microsecond)
+	case "SetPosition":
+		p.Seek(time.Duration(args[1].(int64)) * time.Microsecond)
+	case "Next", "Previous", "OpenUri":
+	default:
+		return errUnknownMethod
+	}
+	return nil
+}
+
+func (p *Player) getProperty(iface string, name string) ([]interface{}, error) {
+	if iface != mpris.PlayerInterface {
+		return nil, errUnknownProperty
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if value, found := p.properties[name]; found {
+		return []interface{}{value}, nil
+	}
+	return nil, errUnknownProperty
+}
+
+func (p *Player) getAll(iface string) ([]interface{}, error) {
+	values := make(map[string]dbus.Variant)
+	if iface == mpris.PlayerInterface {
+		p.lock.Lock()
+		for key, value := range p.properties {
+			values[key] = value
+		}
+		p.lock.Unlock()
+	}
+	return []interface{}{values}, nil
+}
+
+func (p *Player) setProperty(iface string, name string, value dbus.Variant) error {
+	if iface != mpris.PlayerInterface {
+		return errUnknownProperty
+	}
+	switch name {
+	case mpris.FieldLoopStatus, mpris.FieldShuffle, mpris.FieldVolume, mpris.FieldRate:
+		p.Set(name, value.Value())
+		return nil
+	default:
+		return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", nil)
+	}
+}
@@ -0,0 +1,181 @@
+package mpris
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// PlaybackStatus is the playback state of a player.
+type PlaybackStatus string
+
+const (
+	PlaybackPlaying PlaybackStatus = "Playing"
+	PlaybackPaused  PlaybackStatus = "Paused"
+	PlaybackStopped PlaybackStatus = "Stopped"
+)
+
+// LoopStatus is the loop behaviour of a player.
+type LoopStatus string
+
+const (
+	LoopStatusNone     LoopStatus = "None"
+	LoopStatusTrack    LoopStatus = "Track"
+	LoopStatusPlaylist LoopStatus = "Playlist"
+)
+
+// LoopStatuses lists every valid LoopStatus.
+var LoopStatuses = []LoopStatus{LoopStatusNone, LoopStatusPlaylist, LoopStatusTrack}
+
+// IsValid reports whether l is one of the values defined by MPRIS.
+func (l LoopStatus) IsValid() bool {
+	for _, status := range LoopStatuses {
+		if l == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Names of the properties of the org.mpris.MediaPlayer2.Player interface.
+const (
+	FieldCanControl     = "CanControl"
+	FieldCanGoNext      = "CanGoNext"
+	FieldCanGoPrevious  = "CanGoPrevious"
+	FieldCanPause       = "CanPause"
+	FieldCanPlay        = "CanPlay"
+	FieldCanSeek        = "CanSeek"
+	FieldLoopStatus     = "LoopStatus"
+	FieldMaximumRate    = "MaximumRate"
+	FieldMetadata       = "Metadata"
+	FieldMinimumRate    = "MinimumRate"
+	FieldPlaybackStatus = "PlaybackStatus"
+	FieldPosition       = "Position"
+	FieldRate           = "Rate"
+	FieldShuffle        = "Shuffle"
+	FieldVolume         = "Volume"
+)
+
+// Names of the properties of the org.mpris.MediaPlayer2 interface kept in PlayerState.
+const (
+	FieldIdentity     = "Identity"
+	FieldDesktopEntry = "DesktopEntry"
+)
+
+// Metadata keys understood by Metadata.
+const (
+	MetadataArtist      = "xesam:artist"
+	MetadataTitle       = "xesam:title"
+	MetadataAlbum       = "xesam:album"
+	MetadataAlbumArtist = "xesam:albumArtist"
+	MetadataTrackId     = "mpris:trackid"
+	MetadataLength      = "mpris:length"
+	MetadataUrl         = "xesam:url"
+	MetadataArtUrl      = "mpris:artUrl"
+)
+
+// Metadata describes the current track of a player.
+type Metadata struct {
+	TrackId     string
+	Title       string
+	Artist      []string
+	Album       string
+	AlbumArtist []string
+	Length      time.Duration
+	Url         string
+	ArtUrl      string
+}
+
+// Capabilities tells which actions a player accepts.
+type Capabilities struct {
+	CanControl    bool
+	CanGoNext     bool
+	CanGoPrevious bool
+	CanPause      bool
+	CanPlay       bool
+	CanSeek       bool
+}
+
+// PlayerState is a snapshot of the properties of a player.
+type PlayerState struct {
+	// Name is the part of the bus name following "org.mpris.MediaPlayer2.".
+	Name string
+	// Application and Instance split Name, see ParsePlayerName.
+	// Application is stable across restarts while Instance usually is not.
+	Application string
+	Instance    string
+	// BusName is the well-known bus name of the player.
+	BusName string
+	// Owner is the unique bus name owning BusName.
+	Owner string
+	// Bus labels the bus the player is on, see Client.SetBusLabel. It is empty by default.
+	Bus string
+	// Identity is the friendly name of the player, like "VLC media player".
+	Identity string
+	// DesktopEntry is the basename of the .desktop file of the player, like "vlc".
+	DesktopEntry string
+
+	PlaybackStatus PlaybackStatus
+	LoopStatus     LoopStatus
+	Shuffle        bool
+	Volume         float64
+	Position       time.Duration
+	Rate           float64
+	MinimumRate    float64
+	MaximumRate    float64
+	Metadata       Metadata
+	Capabilities
+}
+
+func newPlayerState(name string, busName string, owner string) *PlayerState {
+	application, instance := ParsePlayerName(name)
+	return &PlayerState{
+		Name:           name,
+		Application:    application,
+		Instance:       instance,
+		BusName:        busName,
+		Owner:          owner,
+		PlaybackStatus: PlaybackStopped,
+		LoopStatus:     LoopStatusNone,
+		Rate:           1,
+		MinimumRate:    1,
+		MaximumRate:    1,
+	}
+}
+
+var fieldConfigs = map[string]func(p *PlayerState, value interface{}){
+	FieldCanControl:     func(p *PlayerState, value interface{}) { p.CanControl, _ = convertToBool(value) },
+	FieldCanGoNext:      func(p *PlayerState, value interface{}) { p.CanGoNext, _ = convertToBool(value) },
+	FieldCanGoPrevious:  func(p *PlayerState, value interface{}) { p.CanGoPrevious, _ = convertToBool(value) },
+	FieldCanPause:       func(p *PlayerState, value interface{}) { p.CanPause, _ = convertToBool(value) },
+	FieldCanPlay:        func(p *PlayerState, value interface{}) { p.CanPlay, _ = convertToBool(value) },
+	FieldCanSeek:        func(p *PlayerState, value interface{}) { p.CanSeek, _ = convertToBool(value) },
+	FieldMaximumRate:    func(p *PlayerState, value interface{}) { p.MaximumRate, _ = convertToFloat64(value) },
+	FieldMinimumRate:    func(p *PlayerState, value interface{}) { p.MinimumRate, _ = convertToFloat64(value) },
+	FieldRate:           func(p *PlayerState, value interface{}) { p.Rate, _ = convertToFloat64(value) },
+	FieldVolume:         func(p *PlayerState, value interface{}) { p.Volume, _ = convertToFloat64(value) },
+	FieldLoopStatus:     func(p *PlayerState, value interface{}) { p.LoopStatus, _ = convertToLoopStatus(value) },
+	FieldPlaybackStatus: func(p *PlayerState, value interface{}) { p.PlaybackStatus, _ = convertToPlaybackStatus(value) },
+	FieldShuffle:        func(p *PlayerState, value interface{}) { p.Shuffle, _ = convertToBool(value) },
+	FieldPosition:       func(p *PlayerState, value interface{}) { p.Position, _ = convertToDuration(value) },
+	FieldMetadata:       func(p *PlayerState, value interface{}) { p.Metadata, _ = convertToMetadata(value) },
+	FieldIdentity:       func(p *PlayerState, value interface{}) { p.Identity, _ = convertToString(value) },
+	FieldDesktopEntry:   func(p *PlayerState, value interface{}) { p.DesktopEntry, _ = convertToString(value) },
+}
+
+// Update applies properties of the org.mpris.MediaPlayer2.Player interface,
+// or the Identity and DesktopEntry properties of the org.mpris.MediaPlayer2 interface,
+// as returned by GetAll or carried by PropertiesChanged,
+// and returns the names of the fields it updated.
+func (p *PlayerState) Update(values map[string]dbus.Variant) []string {
+	updated := make([]string, 0, len(values))
+	for key, value := range values {
+		setter, supported := fieldConfigs[key]
+		if supported == false {
+			continue
+		}
+		setter(p, value.Value())
+		updated = append(updated, key)
+	}
+	return updated
+}
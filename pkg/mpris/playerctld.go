@@ -0,0 +1,91 @@
+package mpris
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// PlayerctldName is the player name of playerctld, the daemon of playerctl,
+	// which is itself a player forwarding to the most recently active player.
+	PlayerctldName = "playerctld"
+	// ProxyName is the player name of the proxy exported by the mprisctl daemon,
+	// which forwards to the most recently active player.
+	ProxyName = "mprisctl"
+	// PlayerctldInterface is the interface of playerctld listing the players it tracks.
+	PlayerctldInterface = "com.github.altdesktop.playerctld"
+
+	propertyPlayerctldPlayerNames = PlayerctldInterface + ".PlayerNames"
+)
+
+// IsProxy reports whether playerName forwards to other players, like playerctld and the proxy of mprisctl.
+func IsProxy(playerName string) bool {
+	return playerName == PlayerctldName || playerName == ProxyName
+}
+
+// PlayerctldMode tells how a Client treats playerctld, and alike the proxy of mprisctl.
+//
+// Whatever the mode, Subscribe ignores them: their events are those of the player they front.
+type PlayerctldMode int
+
+const (
+	// PlayerctldHide leaves playerctld and the proxy of mprisctl out of PlayerNames and Players.
+	// They can still be addressed by name.
+	PlayerctldHide PlayerctldMode = iota
+	// PlayerctldShow lists playerctld and the proxy of mprisctl like any other player.
+	PlayerctldShow
+	// PlayerctldProxy hides them as PlayerctldHide does,
+	// and resolves the name of playerctld to the player it fronts.
+	PlayerctldProxy
+)
+
+var playerctldModeNames = map[PlayerctldMode]string{
+	PlayerctldHide:  "hide",
+	PlayerctldShow:  "show",
+	PlayerctldProxy: "proxy",
+}
+
+func (m PlayerctldMode) String() string {
+	if name, ok := playerctldModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("PlayerctldMode(%d)", int(m))
+}
+
+// ParsePlayerctldMode returns the PlayerctldMode named name: hide, show or proxy.
+func ParsePlayerctldMode(name string) (PlayerctldMode, error) {
+	for mode, modeName := range playerctldModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: playerctld mode %q", ErrInvalidValue, name)
+}
+
+// SetPlayerctldMode sets how c treats playerctld. The default is PlayerctldHide.
+func (c *Client) SetPlayerctldMode(mode PlayerctldMode) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.playerctldMode = mode
+}
+
+func (c *Client) playerctld() PlayerctldMode {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.playerctldMode
+}
+
+// PlayerctldActivePlayer returns the name of the player playerctld currently fronts.
+func (c *Client) PlayerctldActivePlayer(ctx context.Context) (string, error) {
+	value, err := c.dbus.getProperty(ctx, BusName(PlayerctldName), ObjectPath, propertyPlayerctldPlayerNames)
+	if err != nil {
+		return "", err
+	}
+	busNames, _ := convertToStrings(value.Value())
+	for _, busName := range busNames {
+		if playerName, isMprisPlayer := PlayerName(busName); isMprisPlayer && IsProxy(playerName) == false {
+			return playerName, nil
+		}
+	}
+	return "", fmt.Errorf("%w: playerctld fronts no player", ErrPlayerNotFound)
+}
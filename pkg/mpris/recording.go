@@ -0,0 +1,274 @@
+package mpris
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	recordSignal   = "signal"
+	recordSnapshot = "snapshot"
+	recordPosition = "position"
+)
+
+// recordEntry is a line of a recording: something the monitor read from the bus.
+type recordEntry struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+
+	// Signal is set for signals.
+	Signal *recordedSignal `json:"signal,omitempty"`
+
+	// Player, Bus, BusName, Owner, Properties and Error are set for snapshots, the state of a player
+	// read when it appears. Error is set when it could not be read.
+	Player     string                   `json:"player,omitempty"`
+	Bus        string                   `json:"bus,omitempty"`
+	BusName    string                   `json:"bus_name,omitempty"`
+	Owner      string                   `json:"owner,omitempty"`
+	Properties map[string]recordedValue `json:"properties,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+
+	// Position, in microseconds, is set with Owner for the positions polled every second.
+	Position int64 `json:"position,omitempty"`
+}
+
+type recordedSignal struct {
+	Sender string          `json:"sender"`
+	Path   string          `json:"path"`
+	Name   string          `json:"name"`
+	Body   []recordedValue `json:"body"`
+}
+
+// recordedValue holds a D-Bus value in the GVariant text format, along with its signature.
+type recordedValue struct {
+	Signature string `json:"signature"`
+	Value     string `json:"value"`
+}
+
+func newRecordedValue(value interface{}) recordedValue {
+	variant, isVariant := value.(dbus.Variant)
+	if isVariant == false {
+		variant = dbus.MakeVariant(value)
+	}
+	return recordedValue{
+		Signature: variant.Signature().String(),
+		Value:     variant.String(),
+	}
+}
+
+func (v recordedValue) variant() (dbus.Variant, error) {
+	signature, err := dbus.ParseSignature(v.Signature)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	return dbus.ParseVariant(v.Value, signature)
+}
+
+// recorder writes a recording, one JSON entry per line.
+// Its methods are called with the lock of the monitor held, so entries are in processing order.
+// A nil recorder records nothing.
+type recorder struct {
+	encoder *json.Encoder
+	err     error
+}
+
+func (r *recorder) write(entry recordEntry) {
+	if r == nil || r.err != nil {
+		return
+	}
+	entry.Time = time.Now()
+	r.err = r.encoder.Encode(entry)
+}
+
+func (r *recorder) signal(signal *dbus.Signal) {
+	if r == nil {
+		return
+	}
+	recorded := &recordedSignal{
+		Sender: signal.Sender,
+		Path:   string(signal.Path),
+		Name:   signal.Name,
+		Body:   make([]recordedValue, 0, len(signal.Body)),
+	}
+	for _, value := range signal.Body {
+		recorded.Body = append(recorded.Body, newRecordedValue(value))
+	}
+	r.write(recordEntry{Type: recordSignal, Signal: recorded})
+}
+
+func (r *recorder) snapshot(playerName string, player *PlayerState, values map[string]dbus.Variant, err error) {
+	if r == nil {
+		return
+	}
+	entry := recordEntry{Type: recordSnapshot, Player: playerName}
+	if err != nil {
+		entry.Error = err.Error()
+		if errors.Is(err, ErrPlayerNotFound) {
+			entry.Error = ErrPlayerNotFound.Error()
+		}
+	} else {
+		entry.Bus = player.Bus
+		entry.BusName = player.BusName
+		entry.Owner = player.Owner
+		entry.Properties = make(map[string]recordedValue, len(values))
+		for key, value := range values {
+			entry.Properties[key] = newRecordedValue(value)
+		}
+	}
+	r.write(entry)
+}
+
+func (r *recorder) position(owner string, position time.Duration) {
+	r.write(recordEntry{Type: recordPosition, Owner: owner, Position: position.Microseconds()})
+}
+
+// Record is like Subscribe, and also writes to w everything the subscription reads from the bus:
+// the signals, the state of the players when they appear, and the positions polled every second.
+// The recording is made of JSON lines and can be given to Replay.
+// The channel is closed if writing to w fails.
+func (c *Client) Record(ctx context.Context, filter Filter, w io.Writer) <-chan Event {
+	monitor := newMprisMonitor(ctx, c, filter)
+	monitor.recorder = &recorder{encoder: json.NewEncoder(w)}
+	go monitor.run()
+	return monitor.events
+}
+
+// replayer feeds a recording to a monitor.
+type replayer struct {
+	entries []recordEntry
+	// current is the index of the entry being replayed.
+	current int
+	// consumed marks the snapshots already read by a handler.
+	consumed map[int]bool
+}
+
+// Replay sends the events that Subscribe sent while the recording read from r was made by Record.
+// No bus is involved: signals go through the same handlers as on a live bus. Time is virtual:
+// the positions polled every second are replayed from the recording rather than by a ticker,
+// and entries follow each other without waiting, so the recording is replayed at once.
+func Replay(ctx context.Context, r io.Reader, filter Filter) (<-chan Event, error) {
+	entries, err := readRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	monitor := newMprisMonitor(ctx, nil, filter)
+	monitor.replayer = &replayer{entries: entries, consumed: make(map[int]bool)}
+	go monitor.run()
+	return monitor.events, nil
+}
+
+func readRecording(r io.Reader) ([]recordEntry, error) {
+	entries := make([]recordEntry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry recordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		switch entry.Type {
+		case recordSignal, recordSnapshot, recordPosition:
+		default:
+			return nil, fmt.Errorf("recording line %d: unknown entry type %q", line, entry.Type)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// snapshot returns the state of playerName recorded after the current entry,
+// as read by a handler of the current signal.
+func (r *replayer) snapshot(playerName string) (*PlayerState, error) {
+	for index := r.current + 1; index < len(r.entries); index++ {
+		entry := r.entries[index]
+		if entry.Type != recordSnapshot || entry.Player != playerName || r.consumed[index] {
+			continue
+		}
+		r.consumed[index] = true
+		return entry.playerState()
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, playerName)
+}
+
+func (e recordEntry) playerState() (*PlayerState, error) {
+	if e.Error != "" {
+		if e.Error == ErrPlayerNotFound.Error() {
+			return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, e.Player)
+		}
+		return nil, errors.New(e.Error)
+	}
+	values := make(map[string]dbus.Variant, len(e.Properties))
+	for key, value := range e.Properties {
+		variant, err := value.variant()
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", key, err)
+		}
+		values[key] = variant
+	}
+	player := newPlayerState(e.Player, e.BusName, e.Owner)
+	player.Bus = e.Bus
+	player.Update(values)
+	return player, nil
+}
+
+func (e recordEntry) signal() (*dbus.Signal, error) {
+	body := make([]interface{}, 0, len(e.Signal.Body))
+	for _, value := range e.Signal.Body {
+		variant, err := value.variant()
+		if err != nil {
+			return nil, fmt.Errorf("signal %s: %w", e.Signal.Name, err)
+		}
+		body = append(body, variant.Value())
+	}
+	return &dbus.Signal{
+		Sender: e.Signal.Sender,
+		Path:   dbus.ObjectPath(e.Signal.Path),
+		Name:   e.Signal.Name,
+		Body:   body,
+	}, nil
+}
+
+// replay runs the handlers of every recorded entry, in order.
+func (m *mprisMonitor) replay() {
+	r := m.replayer
+	for index, entry := range r.entries {
+		if m.ctx.Err() != nil {
+			return
+		}
+		r.current = index
+
+		m.lock.Lock()
+		switch entry.Type {
+		case recordSnapshot:
+			// Snapshots not read by a handler were taken when subscribing.
+			if r.consumed[index] == false {
+				if player, err := entry.playerState(); err == nil {
+					m.appear(player)
+				}
+			}
+		case recordSignal:
+			if signal, err := entry.signal(); err == nil {
+				if handler, supported := signalMapping[signal.Name]; supported {
+					handler(m, signal)
+				}
+			}
+		case recordPosition:
+			if player, found := m.players[entry.Owner]; found {
+				player.Position = time.Duration(entry.Position) * time.Microsecond
+				m.emit(EventPosition, player)
+			}
+		}
+		m.lock.Unlock()
+	}
+}
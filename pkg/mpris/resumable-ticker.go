@@ -0,0 +1,112 @@
+package mpris
+
+import (
+	"sync"
+	"time"
+)
+
+type state int
+
+const (
+	stateIdle state = iota
+	stateRunning
+	statePaused
+)
+
+// resumableTicker calls a callback every duration, and can be paused and resumed
+// without losing the time elapsed toward the next tick. It never blocks its caller,
+// and is safe for concurrent use.
+type resumableTicker struct {
+	lock     sync.Mutex
+	duration time.Duration
+	callback func()
+	state    state
+	timer    *time.Timer
+	// next is when the timer fires while running, and remaining the time left until then while paused.
+	next      time.Time
+	remaining time.Duration
+	// generation identifies the timer scheduled last, the ones stopped meanwhile not calling back.
+	generation int
+}
+
+func newTicker(duration time.Duration, callback func()) *resumableTicker {
+	return &resumableTicker{
+		duration: duration,
+		callback: callback,
+		state:    stateIdle,
+	}
+}
+
+// schedule makes the next tick happen after delay. The lock must be held.
+func (t *resumableTicker) schedule(delay time.Duration) {
+	if delay < 0 {
+		delay = 0
+	}
+	t.cancel()
+	generation := t.generation
+	t.state = stateRunning
+	t.next = time.Now().Add(delay)
+	t.timer = time.AfterFunc(delay, func() { t.tick(generation) })
+}
+
+// cancel stops the timer scheduled. The lock must be held.
+func (t *resumableTicker) cancel() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.generation++
+}
+
+func (t *resumableTicker) tick(generation int) {
+	t.lock.Lock()
+	if generation != t.generation || t.state != stateRunning {
+		t.lock.Unlock()
+		return
+	}
+	t.schedule(t.duration)
+	t.lock.Unlock()
+	// Called without the lock, the callback being free to stop the ticker.
+	t.callback()
+}
+
+func (t *resumableTicker) start() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.state == stateIdle {
+		t.schedule(t.duration)
+	}
+}
+
+func (t *resumableTicker) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.cancel()
+	t.state = stateIdle
+}
+
+func (t *resumableTicker) pause() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.state != stateRunning {
+		return
+	}
+	t.remaining = max(time.Until(t.next), 0)
+	t.cancel()
+	t.state = statePaused
+}
+
+func (t *resumableTicker) resume() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.state == statePaused {
+		t.schedule(t.remaining)
+	}
+}
+
+// resumeOrStartAfter makes the ticker run, its next tick happening after delay.
+func (t *resumableTicker) resumeOrStartAfter(delay time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.schedule(delay)
+}